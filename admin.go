@@ -0,0 +1,307 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/nginx/nginx-prometheus-exporter/collector"
+	"github.com/prometheus/exporter-toolkit/web"
+	"gopkg.in/yaml.v2"
+)
+
+// maxAdminRequestSize is the maximum size of the body of a request to the admin API.
+const maxAdminRequestSize = 1 << 20
+
+// loadAdminToken returns the bearer token required by the admin API, which is empty when the clients are only
+// authenticated by their certificates. The admin API must be protected by a token, client certificates, or both.
+func loadAdminToken(tokenFile string, webConfigFile string) (string, error) {
+	var token string
+	if tokenFile != "" {
+		content, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read admin token file: %w", err)
+		}
+		token = strings.TrimSpace(string(content))
+		if token == "" {
+			return "", fmt.Errorf("admin token file %q is empty", tokenFile)
+		}
+	}
+
+	var mtls bool
+	if webConfigFile != "" {
+		if err := web.Validate(webConfigFile); err != nil {
+			return "", fmt.Errorf("invalid admin web config file %q: %w", webConfigFile, err)
+		}
+		content, err := os.ReadFile(webConfigFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read admin web config file: %w", err)
+		}
+		cfg := &web.Config{}
+		if err := yaml.Unmarshal(content, cfg); err != nil {
+			return "", fmt.Errorf("failed to parse admin web config file %q: %w", webConfigFile, err)
+		}
+		mtls = cfg.TLSConfig.ClientAuth == "RequireAndVerifyClientCert"
+	}
+
+	if token == "" && !mtls {
+		return "", errors.New("the admin API requires a token file or a web config file with client_auth_type RequireAndVerifyClientCert")
+	}
+	return token, nil
+}
+
+// kindLabels are the variable labels of a kind of NGINX Plus objects in the admin API: the label names and the
+// label values applied by the collector of every target, by target URI. The values of every object are in the
+// order of the label names.
+type kindLabels struct {
+	LabelNames []string                       `json:"label_names"`
+	Targets    map[string]map[string][]string `json:"targets"`
+}
+
+// adminLabels are the changes of the label values made through the admin API, by kind. They are kept apart
+// from the labels file and applied over it every time it is loaded, so that reloading the file doesn't undo them.
+type adminLabels map[string]*adminKindLabels
+
+type adminKindLabels struct {
+	// values are the label values of the objects set through the admin API, by label name.
+	values map[string]map[string]string
+	// deleted are the objects of the labels file deleted through the admin API.
+	deleted map[string]bool
+	// replaced is set when the objects of the labels file were all replaced through the admin API.
+	replaced bool
+}
+
+// kind returns the changes of kind, which can be changed.
+func (a adminLabels) kind(kind string) *adminKindLabels {
+	if a[kind] == nil {
+		a[kind] = &adminKindLabels{
+			values:  make(map[string]map[string]string),
+			deleted: make(map[string]bool),
+		}
+	}
+	return a[kind]
+}
+
+// clone returns a copy of a whose changes can be changed without changing a.
+func (a adminLabels) clone() adminLabels {
+	clone := make(adminLabels, len(a))
+	for kind, labels := range a {
+		clone[kind] = &adminKindLabels{
+			values:   maps.Clone(labels.values),
+			deleted:  maps.Clone(labels.deleted),
+			replaced: labels.replaced,
+		}
+	}
+	return clone
+}
+
+// apply returns the configuration of the labels file with the changes applied over it. The values of the labels
+// that the file no longer declares are ignored.
+func (a adminLabels) apply(file *labelsConfig) *labelsConfig {
+	if file == nil {
+		return nil
+	}
+	cfg := file.clone()
+	for kind, labels := range a {
+		if labels.replaced {
+			delete(cfg.Values, kind)
+		}
+		for object := range labels.deleted {
+			delete(cfg.Values[kind], object)
+		}
+		for object, values := range labels.values {
+			if cfg.Values[kind] == nil {
+				cfg.Values[kind] = make(map[string]map[string]string)
+			}
+			cfg.Values[kind][object] = values
+		}
+	}
+	return cfg
+}
+
+func (k *adminKindLabels) set(object string, values map[string]string) {
+	k.values[object] = values
+	delete(k.deleted, object)
+}
+
+func (k *adminKindLabels) delete(object string) {
+	delete(k.values, object)
+	k.deleted[object] = true
+}
+
+// replace drops the values of all the objects, including those of the labels file.
+func (k *adminKindLabels) replace() {
+	clear(k.values)
+	clear(k.deleted)
+	k.replaced = true
+}
+
+// adminHandler serves the admin API, which changes the variable labels of NGINX Plus objects on the
+// collectors of all targets.
+type adminHandler struct {
+	logger  *slog.Logger
+	labels  *variableLabels
+	targets *targetManager
+	mux     *http.ServeMux
+	token   string
+}
+
+func newAdminHandler(logger *slog.Logger, token string, labels *variableLabels, targets *targetManager) *adminHandler {
+	h := &adminHandler{
+		logger:  logger,
+		labels:  labels,
+		targets: targets,
+		mux:     http.NewServeMux(),
+		token:   token,
+	}
+	h.mux.HandleFunc("GET /api/v1/labels", h.getAllLabels)
+	h.mux.HandleFunc("GET /api/v1/labels/{kind}", h.getLabels)
+	h.mux.HandleFunc("PUT /api/v1/labels/{kind}", h.putLabels)
+	h.mux.HandleFunc("PATCH /api/v1/labels/{kind}", h.patchLabels)
+	h.mux.HandleFunc("DELETE /api/v1/labels/{kind}", h.deleteLabels)
+	return h
+}
+
+func (h *adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.token != "" {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "invalid bearer token", http.StatusUnauthorized)
+			return
+		}
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// newKindLabels returns the label names of kind in cfg and the label values of kind in the label stores of the
+// targets.
+func newKindLabels(cfg *labelsConfig, stores map[string]*collector.LabelStore, kind string) kindLabels {
+	labels := kindLabels{
+		LabelNames: []string{},
+		Targets:    make(map[string]map[string][]string, len(stores)),
+	}
+	if cfg != nil {
+		labels.LabelNames = append(labels.LabelNames, cfg.LabelNames[kind]...)
+	}
+	for target, store := range stores {
+		labels.Targets[target] = store.Values(kind)
+	}
+	return labels
+}
+
+// getAllLabels responds with the labels of every kind.
+func (h *adminHandler) getAllLabels(w http.ResponseWriter, _ *http.Request) {
+	cfg := h.labels.current()
+	stores := h.targets.labelStores()
+	labels := make(map[string]kindLabels, len(fixedLabels))
+	for kind := range fixedLabels {
+		labels[kind] = newKindLabels(cfg, stores, kind)
+	}
+	h.writeJSON(w, labels)
+}
+
+// getLabels responds with the labels of a kind.
+func (h *adminHandler) getLabels(w http.ResponseWriter, r *http.Request) {
+	kind, ok := h.kind(w, r)
+	if !ok {
+		return
+	}
+	h.writeJSON(w, newKindLabels(h.labels.current(), h.targets.labelStores(), kind))
+}
+
+// putLabels replaces the label values of all objects of a kind.
+func (h *adminHandler) putLabels(w http.ResponseWriter, r *http.Request) {
+	h.setLabels(w, r, true)
+}
+
+// patchLabels sets the label values of the given objects of a kind, leaving the other objects unchanged.
+func (h *adminHandler) patchLabels(w http.ResponseWriter, r *http.Request) {
+	h.setLabels(w, r, false)
+}
+
+func (h *adminHandler) setLabels(w http.ResponseWriter, r *http.Request, replace bool) {
+	kind, ok := h.kind(w, r)
+	if !ok {
+		return
+	}
+	var values map[string][]string
+	if !h.readJSON(w, r, &values) {
+		return
+	}
+	h.update(w, r, kind, func(file *labelsConfig, admin adminLabels) error {
+		changes := admin.kind(kind)
+		if replace {
+			changes.replace()
+		}
+		for object, v := range values {
+			labels, err := file.namedValues(kind, object, v)
+			if err != nil {
+				return err
+			}
+			changes.set(object, labels)
+		}
+		return nil
+	})
+}
+
+// deleteLabels deletes the label values of the given objects of a kind.
+func (h *adminHandler) deleteLabels(w http.ResponseWriter, r *http.Request) {
+	kind, ok := h.kind(w, r)
+	if !ok {
+		return
+	}
+	var objects []string
+	if !h.readJSON(w, r, &objects) {
+		return
+	}
+	h.update(w, r, kind, func(_ *labelsConfig, admin adminLabels) error {
+		changes := admin.kind(kind)
+		for _, object := range objects {
+			changes.delete(object)
+		}
+		return nil
+	})
+}
+
+// update changes the labels configuration and the collectors, responding with the error of change if it fails.
+func (h *adminHandler) update(w http.ResponseWriter, r *http.Request, kind string, change func(file *labelsConfig, admin adminLabels) error) {
+	if err := h.labels.update(h.targets, change); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.logger.Info("variable labels changed through the admin API", "kind", kind, "method", r.Method)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// kind returns the kind of the request path, responding with an error if the kind is unknown.
+func (h *adminHandler) kind(w http.ResponseWriter, r *http.Request) (string, bool) {
+	kind := r.PathValue("kind")
+	if _, ok := fixedLabels[kind]; !ok {
+		http.Error(w, fmt.Sprintf("unknown kind %q", kind), http.StatusNotFound)
+		return "", false
+	}
+	return kind, true
+}
+
+// readJSON decodes the request body into v, responding with an error if the body is invalid.
+func (h *adminHandler) readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAdminRequestSize)).Decode(v); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func (h *adminHandler) writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.logger.Error("failed to write admin API response", "error", err.Error())
+	}
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nginx/nginx-prometheus-exporter/collector"
+)
+
+func TestLoadAdminToken(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	tokenFile := write("token", "secret\n")
+	emptyTokenFile := write("empty-token", "\n")
+	webConfigFile := write("web.yml", "basic_auth_users: {}\n")
+
+	tests := []struct {
+		name          string
+		tokenFile     string
+		webConfigFile string
+		want          string
+		wantErr       bool
+	}{
+		{
+			name:      "token file",
+			tokenFile: tokenFile,
+			want:      "secret",
+		},
+		{
+			name:          "token file and web config without client certificates",
+			tokenFile:     tokenFile,
+			webConfigFile: webConfigFile,
+			want:          "secret",
+		},
+		{
+			name:    "no token file nor web config",
+			wantErr: true,
+		},
+		{
+			name:      "empty token file",
+			tokenFile: emptyTokenFile,
+			wantErr:   true,
+		},
+		{
+			name:          "web config without client certificates",
+			webConfigFile: webConfigFile,
+			wantErr:       true,
+		},
+		{
+			name:      "missing token file",
+			tokenFile: filepath.Join(dir, "missing"),
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := loadAdminToken(tt.tokenFile, tt.webConfigFile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadAdminToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("loadAdminToken() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdminHandler(t *testing.T) {
+	t.Parallel()
+
+	nginx := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/9/http/upstreams":
+			_, _ = io.WriteString(w, `{"backend": {"peers": [{"server": "10.0.0.1:80"}]}}`)
+		case "/9/workers":
+			_, _ = io.WriteString(w, "[]")
+		default:
+			_, _ = io.WriteString(w, "{}")
+		}
+	}))
+	t.Cleanup(nginx.Close)
+
+	labels := &variableLabels{}
+	labels.set(&labelsConfig{LabelNames: map[string][]string{labelKindUpstream: {"team", "tier"}}})
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	manager := newTargetManager(logger, 10)
+	cfg, err := newConfigFromURIs([]string{nginx.URL}, targetConfig{Mode: modePlus, Timeout: time.Second, Labels: labels})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.apply(cfg); err != nil {
+		t.Fatalf("apply() returned error: %v", err)
+	}
+
+	admin := httptest.NewServer(newAdminHandler(logger, "secret", labels, manager))
+	t.Cleanup(admin.Close)
+
+	// the requests depend on each other, so they run in order
+	steps := []struct {
+		name     string
+		method   string
+		path     string
+		token    string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "missing token",
+			method:   http.MethodGet,
+			path:     "/api/v1/labels/upstream",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "wrong token",
+			method:   http.MethodGet,
+			path:     "/api/v1/labels/upstream",
+			token:    "wrong",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "unknown kind",
+			method:   http.MethodPatch,
+			path:     "/api/v1/labels/upstreams",
+			token:    "secret",
+			body:     `{"backend": ["payments", "gold"]}`,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "patch",
+			method:   http.MethodPatch,
+			path:     "/api/v1/labels/upstream",
+			token:    "secret",
+			body:     `{"backend": ["payments", "gold"], "legacy": ["core", ""]}`,
+			wantCode: http.StatusNoContent,
+		},
+		{
+			name:     "wrong number of values",
+			method:   http.MethodPatch,
+			path:     "/api/v1/labels/upstream",
+			token:    "secret",
+			body:     `{"backend": ["checkout"]}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "kind without label names",
+			method:   http.MethodPatch,
+			path:     "/api/v1/labels/cache_zone",
+			token:    "secret",
+			body:     `{"images": []}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "invalid body",
+			method:   http.MethodPut,
+			path:     "/api/v1/labels/upstream",
+			token:    "secret",
+			body:     `["backend"]`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "get",
+			method:   http.MethodGet,
+			path:     "/api/v1/labels/upstream",
+			token:    "secret",
+			wantCode: http.StatusOK,
+			wantBody: `{"label_names":["team","tier"],"targets":{"TARGET":{"backend":["payments","gold"],"legacy":["core",""]}}}`,
+		},
+		{
+			name:     "put",
+			method:   http.MethodPut,
+			path:     "/api/v1/labels/upstream",
+			token:    "secret",
+			body:     `{"backend": ["checkout", "silver"]}`,
+			wantCode: http.StatusNoContent,
+		},
+		{
+			name:     "get after put",
+			method:   http.MethodGet,
+			path:     "/api/v1/labels/upstream",
+			token:    "secret",
+			wantCode: http.StatusOK,
+			wantBody: `{"label_names":["team","tier"],"targets":{"TARGET":{"backend":["checkout","silver"]}}}`,
+		},
+		{
+			name:     "delete",
+			method:   http.MethodDelete,
+			path:     "/api/v1/labels/upstream",
+			token:    "secret",
+			body:     `["backend"]`,
+			wantCode: http.StatusNoContent,
+		},
+		{
+			name:     "get after delete",
+			method:   http.MethodGet,
+			path:     "/api/v1/labels/upstream",
+			token:    "secret",
+			wantCode: http.StatusOK,
+			wantBody: `{"label_names":["team","tier"],"targets":{"TARGET":{}}}`,
+		},
+	}
+	for _, step := range steps {
+		req, err := http.NewRequest(step.method, admin.URL+step.path, strings.NewReader(step.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if step.token != "" {
+			req.Header.Set("Authorization", "Bearer "+step.token)
+		}
+		resp, err := admin.Client().Do(req)
+		if err != nil {
+			t.Fatalf("%v: request failed: %v", step.name, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != step.wantCode {
+			t.Errorf("%v: got status %v, want %v: %s", step.name, resp.StatusCode, step.wantCode, body)
+		}
+		// the label values are those of the collector of the target
+		wantBody := strings.ReplaceAll(step.wantBody, "TARGET", nginx.URL)
+		if step.wantBody != "" && strings.TrimSpace(string(body)) != wantBody {
+			t.Errorf("%v: got body %s, want %s", step.name, body, wantBody)
+		}
+
+		// the collectors take the values set through the API
+		if step.name == "patch" {
+			got := upstreamLabels(t, manager)
+			if len(got) != 1 || got[0]["team"] != "payments" || got[0]["tier"] != "gold" {
+				t.Errorf("labels = %v, want team=payments and tier=gold", got)
+			}
+		}
+	}
+
+	// the values set on the collectors by other means, such as the label keyval zone, are returned as well
+	for _, store := range manager.labelStores() {
+		store.Update(func(tx *collector.LabelTx) {
+			tx.Set(labelKindUpstream, map[string][]string{"canary": {"core", ""}})
+		})
+	}
+	got := newKindLabels(labels.current(), manager.labelStores(), labelKindUpstream)
+	if want := map[string]map[string][]string{nginx.URL: {"canary": {"core", ""}}}; !reflect.DeepEqual(got.Targets, want) {
+		t.Errorf("label values = %v, want %v", got.Targets, want)
+	}
+}
+
+func TestAdminHandlerLabelsFileReload(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "labels.yml")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`label_names:
+  upstream: [team]
+values:
+  upstream:
+    backend: {team: payments}
+    legacy: {team: core}
+    removed: {team: core}
+`)
+	initial, err := loadLabels(path)
+	if err != nil {
+		t.Fatalf("loadLabels() returned error: %v", err)
+	}
+	labels := &variableLabels{}
+	labels.set(initial)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	manager := newTargetManager(logger, 10)
+	h := newAdminHandler(logger, "secret", labels, manager)
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPatch, "/api/v1/labels/upstream", strings.NewReader(`{"backend": ["admin"], "checkout": ["web"]}`)),
+		httptest.NewRequest(http.MethodDelete, "/api/v1/labels/upstream", strings.NewReader(`["legacy"]`)),
+	} {
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("%v: got status %v, want %v: %s", req.Method, rec.Code, http.StatusNoContent, rec.Body)
+		}
+	}
+
+	// the changes made through the admin API are applied over the reloaded file
+	write(`label_names:
+  upstream: [team]
+values:
+  upstream:
+    backend: {team: checkout}
+    legacy: {team: core}
+    added: {team: ops}
+`)
+	if err := reloadLabels(path, labels, manager); err != nil {
+		t.Fatalf("reloadLabels() returned error: %v", err)
+	}
+	want := map[string][]string{"backend": {"admin"}, "checkout": {"web"}, "added": {"ops"}}
+	if got := labels.current().labelValues(labelKindUpstream); !reflect.DeepEqual(got, want) {
+		t.Errorf("labelValues() = %v, want %v", got, want)
+	}
+}
@@ -46,11 +46,8 @@ func NewNginxClient(httpClient *http.Client, apiEndpoint string) *NginxClient {
 	return client
 }
 
-// GetStubStats fetches the stub_status metrics.
-func (client *NginxClient) GetStubStats() (*StubStats, error) {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
+// GetStubStats fetches the stub_status metrics. The request is aborted when ctx is done.
+func (client *NginxClient) GetStubStats(ctx context.Context) (*StubStats, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, client.apiEndpoint, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create a get request: %w", err)
@@ -71,7 +68,7 @@ func (client *NginxClient) GetStubStats() (*StubStats, error) {
 	}
 
 	r := bytes.NewReader(body)
-	stats, err := parseStubStats(r)
+	stats, err := ParseStubStats(r)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse response body %q: %w", string(body), err)
 	}
@@ -79,7 +76,8 @@ func (client *NginxClient) GetStubStats() (*StubStats, error) {
 	return stats, nil
 }
 
-func parseStubStats(r io.Reader) (*StubStats, error) {
+// ParseStubStats parses the content of the stub_status page.
+func ParseStubStats(r io.Reader) (*StubStats, error) {
 	var s StubStats
 	if _, err := fmt.Fscanf(r, templateMetrics,
 		&s.Connections.Active,
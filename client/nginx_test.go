@@ -2,6 +2,11 @@ package client
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -38,14 +43,39 @@ func TestParseStubStatsValidInput(t *testing.T) {
 
 	for _, test := range tests {
 		r := bytes.NewReader(test.input)
-		result, err := parseStubStats(r)
+		result, err := ParseStubStats(r)
 
 		if err != nil && !test.expectedError {
-			t.Errorf("parseStubStats() returned error for valid input %q: %v", string(test.input), err)
+			t.Errorf("ParseStubStats() returned error for valid input %q: %v", string(test.input), err)
 		}
 
 		if !test.expectedError && test.expectedResult != *result {
-			t.Errorf("parseStubStats() result %v != expected %v for input %q", result, test.expectedResult, test.input)
+			t.Errorf("ParseStubStats() result %v != expected %v for input %q", result, test.expectedResult, test.input)
 		}
 	}
 }
+
+func TestGetStubStats(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, validStabStats)
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewNginxClient(server.Client(), server.URL)
+
+	stats, err := client.GetStubStats(context.Background())
+	if err != nil {
+		t.Fatalf("GetStubStats() returned error: %v", err)
+	}
+	if stats.Connections.Active != 1457 {
+		t.Errorf("GetStubStats() active connections = %v, want 1457", stats.Connections.Active)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.GetStubStats(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetStubStats() error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,250 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	plusclient "github.com/nginx/nginx-plus-go-client/v2/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Values of the response codes of a family that select all the codes or none of them.
+const (
+	ResponseCodesAll     = "all"
+	ResponseCodesClasses = "classes"
+)
+
+// ResponseCodesFamilies returns the families of NGINX Plus objects that report their responses per status code.
+func ResponseCodesFamilies() []string {
+	return []string{FamilyServerZone, FamilyLocationZone, FamilyUpstream}
+}
+
+// ResponseCodes selects the status codes exposed in the responses_codes metrics of a family.
+// The responses per class of status codes are always exposed.
+type ResponseCodes struct {
+	allowlist map[string]bool
+	none      bool
+}
+
+// ParseResponseCodes parses the status codes to expose: "all" for every code reported by NGINX Plus,
+// "classes" for none of them, or a comma-separated list of codes.
+func ParseResponseCodes(s string) (ResponseCodes, error) {
+	switch s {
+	case ResponseCodesAll, "":
+		return ResponseCodes{}, nil
+	case ResponseCodesClasses:
+		return ResponseCodes{none: true}, nil
+	}
+
+	r := ResponseCodes{allowlist: make(map[string]bool)}
+	for _, code := range strings.Split(s, ",") {
+		code = strings.TrimSpace(code)
+		n, err := strconv.Atoi(code)
+		if err != nil || n < 100 || n > 599 {
+			return ResponseCodes{}, fmt.Errorf("invalid status code %q", code)
+		}
+		r.allowlist[code] = true
+	}
+	return r, nil
+}
+
+func (r ResponseCodes) keep(code string) bool {
+	if r.none {
+		return false
+	}
+	return r.allowlist == nil || r.allowlist[code]
+}
+
+// WithResponseCodes selects the status codes exposed for the given families. All the codes are exposed
+// for the other families.
+func WithResponseCodes(codes map[string]ResponseCodes) NginxPlusCollectorOption {
+	return func(c *NginxPlusCollector) {
+		c.responseCodes = codes
+	}
+}
+
+// WithAPIEndpoint makes the collector decode the responses of the server zones, location zones and upstreams
+// itself, from the NGINX Plus API at endpoint and with the API version of the client. Unlike the typed stats of
+// the client, they include every status code, such as 418 or 508.
+func WithAPIEndpoint(httpClient *http.Client, endpoint string) NginxPlusCollectorOption {
+	return func(c *NginxPlusCollector) {
+		c.api = &apiEndpoint{
+			httpClient: httpClient,
+			endpoint:   endpoint,
+		}
+	}
+}
+
+// apiEndpoint is the NGINX Plus API endpoint of the client, for the stats the client can't decode completely.
+type apiEndpoint struct {
+	httpClient *http.Client
+	endpoint   string
+}
+
+// getRaw decodes the response to the API request for path into v, using the API version of the client.
+func (c *NginxPlusCollector) getRaw(ctx context.Context, path string, v any) error {
+	endpoint, err := url.JoinPath(c.api.endpoint, strconv.Itoa(c.nginxClient.Version()), path)
+	if err != nil {
+		return fmt.Errorf("failed to create the url of %v: %w", path, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create a get request: %w", err)
+	}
+	resp, err := c.api.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to get %v: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get %v: expected %v response, got %v", path, http.StatusOK, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("error unmarshaling response of %v: %w", path, err)
+	}
+	return nil
+}
+
+// plusStats are the stats of NGINX Plus along with the responses per status code, which the client
+// only decodes for a fixed set of codes.
+type plusStats struct {
+	*plusclient.Stats
+	// labelPairs are the pairs of the label keyval zone, nil if they weren't fetched.
+	labelPairs plusclient.KeyValPairs
+	// keyVals and streamKeyVals are the pairs of the HTTP and stream keyval zones.
+	keyVals       plusclient.KeyValPairsByZone
+	streamKeyVals plusclient.KeyValPairsByZone
+	// license is the license of NGINX Plus, nil if it isn't reported.
+	license *plusclient.NginxLicense
+	// workerRestarts are the restarts per worker id counted up to these stats.
+	workerRestarts map[string]uint64
+	codes          responseCodeCounts
+	// clockSkew is the difference between the clock of NGINX and the clock of the exporter when the stats
+	// were fetched.
+	clockSkew time.Duration
+}
+
+// responseCodeCounts holds the number of responses per status code of every server zone, location zone
+// and upstream peer. The peers are keyed by their ID in the upstream.
+type responseCodeCounts struct {
+	serverZones   map[string]map[string]uint64
+	locationZones map[string]map[string]uint64
+	upstreamPeers map[string]map[int]map[string]uint64
+}
+
+// peer returns the responses per status code of the peer of upstream with the given ID.
+func (r responseCodeCounts) peer(upstream string, id int) map[string]uint64 {
+	return r.upstreamPeers[upstream][id]
+}
+
+// rawResponses are the responses of an object of the NGINX Plus API. Codes shadows the codes of the client,
+// which only decodes a fixed set of status codes.
+type rawResponses struct {
+	plusclient.Responses
+	Codes map[string]uint64
+}
+
+type rawServerZone struct {
+	plusclient.ServerZone
+	Responses rawResponses
+}
+
+type rawLocationZone struct {
+	plusclient.LocationZone
+	Responses rawResponses
+}
+
+type rawPeer struct {
+	plusclient.Peer
+	Responses rawResponses
+}
+
+type rawUpstream struct {
+	plusclient.Upstream
+	Peers []rawPeer
+}
+
+// serverZones returns the typed server zones and the responses per status code of every zone.
+func serverZones(raw map[string]rawServerZone) (plusclient.ServerZones, map[string]map[string]uint64) {
+	zones := make(plusclient.ServerZones, len(raw))
+	codes := make(map[string]map[string]uint64, len(raw))
+	for name, r := range raw {
+		zone := r.ServerZone
+		zone.Responses = r.Responses.Responses
+		zones[name] = zone
+		codes[name] = r.Responses.Codes
+	}
+	return zones, codes
+}
+
+// locationZones returns the typed location zones and the responses per status code of every zone.
+func locationZones(raw map[string]rawLocationZone) (plusclient.LocationZones, map[string]map[string]uint64) {
+	zones := make(plusclient.LocationZones, len(raw))
+	codes := make(map[string]map[string]uint64, len(raw))
+	for name, r := range raw {
+		zone := r.LocationZone
+		zone.Responses = r.Responses.Responses
+		zones[name] = zone
+		codes[name] = r.Responses.Codes
+	}
+	return zones, codes
+}
+
+// upstreams returns the typed upstreams and the responses per status code of every peer.
+func upstreams(raw map[string]rawUpstream) (plusclient.Upstreams, map[string]map[int]map[string]uint64) {
+	upstreams := make(plusclient.Upstreams, len(raw))
+	codes := make(map[string]map[int]map[string]uint64, len(raw))
+	for name, r := range raw {
+		upstream := r.Upstream
+		upstream.Peers = make([]plusclient.Peer, 0, len(r.Peers))
+		codes[name] = make(map[int]map[string]uint64, len(r.Peers))
+		for _, p := range r.Peers {
+			peer := p.Peer
+			peer.Responses = p.Responses.Responses
+			upstream.Peers = append(upstream.Peers, peer)
+			codes[name][peer.ID] = p.Responses.Codes
+		}
+		upstreams[name] = upstream
+	}
+	return upstreams, codes
+}
+
+// typedCodes returns the codes of the typed stats of the client, named by their JSON fields.
+func typedCodes(codes plusclient.HTTPCodes) map[string]uint64 {
+	counts := make(map[string]uint64)
+	v := reflect.ValueOf(codes)
+	for i := range v.NumField() {
+		code, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		counts[code] = v.Field(i).Uint()
+	}
+	return counts
+}
+
+func newResponseCodesMetric(namespace string, subsystem string, labelNames []string, constLabels prometheus.Labels) *prometheus.Desc {
+	labels := make([]string, 0, len(labelNames)+1)
+	labels = append(labels, labelNames...)
+	labels = append(labels, "code")
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, "responses_codes"), "Total responses sent to clients", labels, constLabels)
+}
+
+// collectResponseCodes sends the responses per status code of an object of the family to ch.
+func (c *NginxPlusCollector) collectResponseCodes(ch chan<- prometheus.Metric, desc *prometheus.Desc, family string, codes map[string]uint64, labelValues []string) {
+	selected := c.responseCodes[family]
+	for code, count := range codes {
+		if !selected.keep(code) {
+			continue
+		}
+		values := make([]string, 0, len(labelValues)+1)
+		values = append(values, labelValues...)
+		values = append(values, code)
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(count), values...)
+	}
+}
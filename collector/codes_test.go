@@ -0,0 +1,161 @@
+package collector
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	plusclient "github.com/nginx/nginx-plus-go-client/v2/client"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestParseResponseCodes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		value   string
+		keep    []string
+		drop    []string
+		wantErr bool
+	}{
+		{
+			name:  "all",
+			value: "all",
+			keep:  []string{"200", "418", "508"},
+		},
+		{
+			name:  "classes",
+			value: "classes",
+			drop:  []string{"200", "418"},
+		},
+		{
+			name:  "allowlist",
+			value: "200, 418,508",
+			keep:  []string{"200", "418", "508"},
+			drop:  []string{"404"},
+		},
+		{
+			name:    "invalid code",
+			value:   "200,2xx",
+			wantErr: true,
+		},
+		{
+			name:    "code out of range",
+			value:   "600",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			r, err := ParseResponseCodes(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseResponseCodes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, code := range tt.keep {
+				if !r.keep(code) {
+					t.Errorf("keep(%q) = false, want true", code)
+				}
+			}
+			for _, code := range tt.drop {
+				if r.keep(code) {
+					t.Errorf("keep(%q) = true, want false", code)
+				}
+			}
+		})
+	}
+}
+
+func TestTypedCodes(t *testing.T) {
+	t.Parallel()
+
+	got := typedCodes(plusclient.HTTPCodes{HTTPOk: 10, HTTPNotFound: 2, HTTPInsufficientStorage: 1})
+	if want := reflect.TypeOf(plusclient.HTTPCodes{}).NumField(); len(got) != want {
+		t.Errorf("typedCodes() returned %d codes, want %d", len(got), want)
+	}
+	for code, want := range map[string]uint64{"200": 10, "404": 2, "507": 1, "500": 0} {
+		if n, ok := got[code]; !ok || n != want {
+			t.Errorf("typedCodes()[%q] = %v, %v, want %v", code, n, ok, want)
+		}
+	}
+}
+
+func TestNginxPlusCollectorResponseCodes(t *testing.T) {
+	t.Parallel()
+
+	nginx := newTestPlusServer(t, plusRoutes(map[string]string{
+		"/9/http/server_zones": `{"app": {"responses": {"2xx": 10, "4xx": 3, "codes": {"200": 10, "404": 1, "418": 2}}}}`,
+		"/9/http/upstreams":    `{"backend": {"peers": [{"id": 3, "server": "10.0.0.1:80", "responses": {"codes": {"200": 5, "508": 1}}}, {"id": 1, "server": "10.0.0.2:80", "responses": {"codes": {"200": 0}}}]}}`,
+	}))
+
+	tests := []struct {
+		codes map[string]ResponseCodes
+		name  string
+		want  string
+	}{
+		{
+			name: "all codes",
+			want: `# HELP nginxplus_server_zone_responses Total responses sent to clients
+# TYPE nginxplus_server_zone_responses counter
+nginxplus_server_zone_responses{code="1xx",server_zone="app"} 0
+nginxplus_server_zone_responses{code="2xx",server_zone="app"} 10
+nginxplus_server_zone_responses{code="3xx",server_zone="app"} 0
+nginxplus_server_zone_responses{code="4xx",server_zone="app"} 3
+nginxplus_server_zone_responses{code="5xx",server_zone="app"} 0
+# HELP nginxplus_server_zone_responses_codes Total responses sent to clients
+# TYPE nginxplus_server_zone_responses_codes counter
+nginxplus_server_zone_responses_codes{code="200",server_zone="app"} 10
+nginxplus_server_zone_responses_codes{code="404",server_zone="app"} 1
+nginxplus_server_zone_responses_codes{code="418",server_zone="app"} 2
+# HELP nginxplus_upstream_server_responses_codes Total responses sent to clients
+# TYPE nginxplus_upstream_server_responses_codes counter
+nginxplus_upstream_server_responses_codes{code="200",server="10.0.0.1:80",upstream="backend"} 5
+nginxplus_upstream_server_responses_codes{code="200",server="10.0.0.2:80",upstream="backend"} 0
+nginxplus_upstream_server_responses_codes{code="508",server="10.0.0.1:80",upstream="backend"} 1
+`,
+		},
+		{
+			name: "allowlist and classes",
+			codes: map[string]ResponseCodes{
+				FamilyServerZone: mustParseResponseCodes(t, "418"),
+				FamilyUpstream:   mustParseResponseCodes(t, ResponseCodesClasses),
+			},
+			want: `# HELP nginxplus_server_zone_responses Total responses sent to clients
+# TYPE nginxplus_server_zone_responses counter
+nginxplus_server_zone_responses{code="1xx",server_zone="app"} 0
+nginxplus_server_zone_responses{code="2xx",server_zone="app"} 10
+nginxplus_server_zone_responses{code="3xx",server_zone="app"} 0
+nginxplus_server_zone_responses{code="4xx",server_zone="app"} 3
+nginxplus_server_zone_responses{code="5xx",server_zone="app"} 0
+# HELP nginxplus_server_zone_responses_codes Total responses sent to clients
+# TYPE nginxplus_server_zone_responses_codes counter
+nginxplus_server_zone_responses_codes{code="418",server_zone="app"} 2
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := newTestPlusCollectorOfClient(newTestPlusClient(t, nginx), nil,
+				WithSections(SectionServerZones, SectionUpstreams),
+				WithAPIEndpoint(nginx.Client(), nginx.URL+"/"),
+				WithResponseCodes(tt.codes))
+
+			err := testutil.CollectAndCompare(c, strings.NewReader(tt.want),
+				"nginxplus_server_zone_responses", "nginxplus_server_zone_responses_codes", "nginxplus_upstream_server_responses_codes")
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func mustParseResponseCodes(t *testing.T, s string) ResponseCodes {
+	t.Helper()
+	r, err := ParseResponseCodes(s)
+	if err != nil {
+		t.Fatalf("ParseResponseCodes() returned error: %v", err)
+	}
+	return r
+}
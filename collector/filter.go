@@ -0,0 +1,113 @@
+package collector
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Families of NGINX Plus objects whose names can be filtered.
+const (
+	FamilyServerZone            = "server-zone"
+	FamilyStreamServerZone      = "stream-server-zone"
+	FamilyUpstream              = "upstream"
+	FamilyStreamUpstream        = "stream-upstream"
+	FamilyLocationZone          = "location-zone"
+	FamilyResolver              = "resolver"
+	FamilyLimitRequest          = "limit-request"
+	FamilyLimitConnection       = "limit-connection"
+	FamilyStreamLimitConnection = "stream-limit-connection"
+	FamilyCache                 = "cache"
+)
+
+// FilterFamilies returns the families of NGINX Plus objects whose names can be filtered.
+func FilterFamilies() []string {
+	return []string{
+		FamilyServerZone,
+		FamilyStreamServerZone,
+		FamilyUpstream,
+		FamilyStreamUpstream,
+		FamilyLocationZone,
+		FamilyResolver,
+		FamilyLimitRequest,
+		FamilyLimitConnection,
+		FamilyStreamLimitConnection,
+		FamilyCache,
+	}
+}
+
+// NameFilter selects objects by name. A name is kept if it matches the include expression, or there is none,
+// and doesn't match the exclude expression. The expressions must match the whole name.
+type NameFilter struct {
+	include *regexp.Regexp
+	exclude *regexp.Regexp
+}
+
+// NewNameFilter creates a NameFilter from include and exclude regular expressions. Empty expressions are ignored.
+func NewNameFilter(include, exclude string) (NameFilter, error) {
+	var f NameFilter
+	var err error
+	if include != "" {
+		if f.include, err = regexp.Compile("^(?:" + include + ")$"); err != nil {
+			return NameFilter{}, fmt.Errorf("invalid include expression: %w", err)
+		}
+	}
+	if exclude != "" {
+		if f.exclude, err = regexp.Compile("^(?:" + exclude + ")$"); err != nil {
+			return NameFilter{}, fmt.Errorf("invalid exclude expression: %w", err)
+		}
+	}
+	return f, nil
+}
+
+func (f NameFilter) keep(name string) bool {
+	if f.include != nil && !f.include.MatchString(name) {
+		return false
+	}
+	return f.exclude == nil || !f.exclude.MatchString(name)
+}
+
+// WithNameFilters filters the objects of the given families by name. The objects that are filtered out
+// are counted in the nginx_exporter_filtered_objects metric.
+func WithNameFilters(filters map[string]NameFilter) NginxPlusCollectorOption {
+	return func(c *NginxPlusCollector) {
+		c.filters = filters
+	}
+}
+
+func newFilteredObjectsMetric(constLabels map[string]string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(exporterNamespace, "", "filtered_objects"), "Number of objects left out of the metrics by the name filters",
+		[]string{"family"}, constLabels)
+}
+
+// objectFilter applies the name filters of a collector during a single scrape and counts the
+// filtered out objects.
+type objectFilter struct {
+	filters  map[string]NameFilter
+	filtered map[string]int
+}
+
+func newObjectFilter(filters map[string]NameFilter) *objectFilter {
+	return &objectFilter{
+		filters:  filters,
+		filtered: make(map[string]int, len(filters)),
+	}
+}
+
+// keep reports whether the object of the family with the given name is collected.
+func (f *objectFilter) keep(family, name string) bool {
+	filter, ok := f.filters[family]
+	if !ok || filter.keep(name) {
+		return true
+	}
+	f.filtered[family]++
+	return false
+}
+
+// collect sends the number of filtered out objects of every filtered family to ch.
+func (f *objectFilter) collect(ch chan<- prometheus.Metric, desc *prometheus.Desc) {
+	for family := range f.filters {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(f.filtered[family]), family)
+	}
+}
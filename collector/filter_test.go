@@ -0,0 +1,92 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNameFilter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		include string
+		exclude string
+		keep    []string
+		drop    []string
+		wantErr bool
+	}{
+		{
+			name: "no expressions",
+			keep: []string{"backend", ""},
+		},
+		{
+			name:    "include",
+			include: "app-.*",
+			keep:    []string{"app-1", "app-backend"},
+			drop:    []string{"backend", "my-app-1"},
+		},
+		{
+			name:    "exclude",
+			exclude: "tmp-.*|canary",
+			keep:    []string{"backend", "canary-1"},
+			drop:    []string{"tmp-1", "canary"},
+		},
+		{
+			name:    "exclude takes precedence",
+			include: "app-.*",
+			exclude: "app-internal",
+			keep:    []string{"app-1"},
+			drop:    []string{"app-internal", "backend"},
+		},
+		{
+			name:    "invalid expression",
+			include: "app-(",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			f, err := NewNameFilter(tt.include, tt.exclude)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewNameFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, name := range tt.keep {
+				if !f.keep(name) {
+					t.Errorf("keep(%q) = false, want true", name)
+				}
+			}
+			for _, name := range tt.drop {
+				if f.keep(name) {
+					t.Errorf("keep(%q) = true, want false", name)
+				}
+			}
+		})
+	}
+}
+
+func TestNginxPlusCollectorNameFilters(t *testing.T) {
+	t.Parallel()
+
+	filter, err := NewNameFilter("app-.*", "app-2")
+	if err != nil {
+		t.Fatalf("NewNameFilter() returned error: %v", err)
+	}
+	c := newTestPlusCollector(t, map[string]string{"/9/http/server_zones": `{"app-1": {}, "app-2": {}, "internal": {}}`},
+		WithSections(SectionServerZones), WithNameFilters(map[string]NameFilter{FamilyServerZone: filter}))
+
+	if got := testutil.CollectAndCount(c, "nginxplus_server_zone_requests"); got != 1 {
+		t.Errorf("got %v nginxplus_server_zone_requests metrics, want 1", got)
+	}
+
+	want := `# HELP nginx_exporter_filtered_objects Number of objects left out of the metrics by the name filters
+# TYPE nginx_exporter_filtered_objects gauge
+nginx_exporter_filtered_objects{family="server-zone"} 2
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(want), "nginx_exporter_filtered_objects"); err != nil {
+		t.Error(err)
+	}
+}
@@ -9,6 +9,9 @@ const (
 	nginxDown = 0
 )
 
+// exporterNamespace is the namespace of the metrics about the exporter itself rather than about NGINX.
+const exporterNamespace = "nginx_exporter"
+
 func newGlobalMetric(namespace string, metricName string, docString string, constLabels map[string]string) *prometheus.Desc {
 	return prometheus.NewDesc(namespace+"_"+metricName, docString, nil, constLabels)
 }
@@ -22,6 +25,10 @@ func newUpMetric(namespace string, constLabels map[string]string) prometheus.Gau
 	})
 }
 
+func newSnapshotAgeMetric(namespace string, constLabels map[string]string) *prometheus.Desc {
+	return newGlobalMetric(namespace, "snapshot_age_seconds", "Age of the stats served from background polling", constLabels)
+}
+
 // MergeLabels merges two maps of labels.
 func MergeLabels(a map[string]string, b map[string]string) map[string]string {
 	c := make(map[string]string)
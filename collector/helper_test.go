@@ -1,10 +1,74 @@
 package collector
 
 import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+
+	plusclient "github.com/nginx/nginx-plus-go-client/v2/client"
 )
 
+// plusRoutes responds to the paths of the NGINX Plus API in routes, such as /9/http/upstreams, with their responses.
+// The other paths get an empty list for the workers and the available endpoints, and an empty object otherwise.
+func plusRoutes(routes map[string]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if response, ok := routes[r.URL.Path]; ok {
+			_, _ = io.WriteString(w, response)
+			return
+		}
+		// the path starts with the version of the API
+		_, endpoint, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		switch endpoint {
+		case "", "stream", "workers":
+			_, _ = io.WriteString(w, "[]")
+		default:
+			_, _ = io.WriteString(w, "{}")
+		}
+	}
+}
+
+// newTestPlusServer starts an NGINX Plus API served by handler, which is closed at the end of the test.
+func newTestPlusServer(t *testing.T, handler http.Handler) *httptest.Server {
+	t.Helper()
+	nginx := httptest.NewServer(handler)
+	t.Cleanup(nginx.Close)
+	return nginx
+}
+
+// newTestPlusClient returns a client of the NGINX Plus API of nginx.
+func newTestPlusClient(t *testing.T, nginx *httptest.Server, opts ...plusclient.Option) *plusclient.NginxClient {
+	t.Helper()
+	plusClient, err := plusclient.NewNginxClient(nginx.URL, append([]plusclient.Option{plusclient.WithHTTPClient(nginx.Client())}, opts...)...)
+	if err != nil {
+		t.Fatalf("NewNginxClient() returned error: %v", err)
+	}
+	return plusClient
+}
+
+// newTestPlusCollectorOfClient returns a collector of plusClient in the nginxplus namespace, with the variable label
+// names of every kind in labelNames.
+func newTestPlusCollectorOfClient(plusClient *plusclient.NginxClient, labelNames map[string][]string, opts ...NginxPlusCollectorOption) *NginxPlusCollector {
+	return NewNginxPlusCollector(plusClient, "nginxplus", NewVariableLabelNamesByKind(labelNames), nil,
+		slog.New(slog.NewTextHandler(io.Discard, nil)), opts...)
+}
+
+// newTestPlusCollector returns a collector of an NGINX Plus API responding with routes, see plusRoutes.
+func newTestPlusCollector(t *testing.T, routes map[string]string, opts ...NginxPlusCollectorOption) *NginxPlusCollector {
+	t.Helper()
+	return newTestPlusCollectorWithLabels(t, routes, nil, opts...)
+}
+
+// newTestPlusCollectorWithLabels returns a collector of an NGINX Plus API responding with routes, with the variable
+// label names of every kind in labelNames.
+func newTestPlusCollectorWithLabels(t *testing.T, routes map[string]string, labelNames map[string][]string, opts ...NginxPlusCollectorOption) *NginxPlusCollector {
+	t.Helper()
+	return newTestPlusCollectorOfClient(newTestPlusClient(t, newTestPlusServer(t, plusRoutes(routes))), labelNames, opts...)
+}
+
 func TestMergeLabels(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,176 @@
+package collector
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	plusclient "github.com/nginx/nginx-plus-go-client/v2/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// WithKeyValKeys exports the key-value pairs of the keyval zones whose full names, the zone and the key separated by
+// a slash, are kept by keys as info series. At most limit pairs are exported per scrape, in the order of their full
+// names with the HTTP zones first, and the others are counted in the nginx_exporter_keyval_keys_dropped metric.
+func WithKeyValKeys(keys NameFilter, limit int) NginxPlusCollectorOption {
+	return func(c *NginxPlusCollector) {
+		c.keyValExport = &keyValExport{keys: keys, limit: limit}
+	}
+}
+
+// keyValExport selects the key-value pairs exported as info series.
+type keyValExport struct {
+	keys  NameFilter
+	limit int
+}
+
+func newKeyValMetric(namespace string, subsystem string, metricName string, docString string, labelNames []string, constLabels prometheus.Labels) *prometheus.Desc {
+	labels := []string{"zone"}
+	labels = append(labels, labelNames...)
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, metricName), docString, labels, constLabels)
+}
+
+// newKeyValKeysDroppedMetric returns the gauge of the pairs left out by the export limit. It counts the pairs of the
+// last scrape rather than a total, as the same pairs are left out again by every scrape.
+func newKeyValKeysDroppedMetric(constLabels map[string]string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(exporterNamespace, "", "keyval_keys_dropped"),
+		"Number of key-value pairs matching the exported keys that were left out by the export limit in the last scrape", nil, constLabels)
+}
+
+// getKeyVals fetches the pairs of all the HTTP and stream keyval zones. The stream zones are only requested when the
+// API has them, as the stream endpoints are missing without a stream block in the configuration of NGINX.
+func getKeyVals(ctx context.Context, c *NginxPlusCollector, stats *plusStats) error {
+	keyVals, err := c.nginxClient.GetAllKeyValPairs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get keyvals: %w", err)
+	}
+	stats.keyVals = keyVals
+
+	available, known := c.streamKeyVals.get()
+	if !known {
+		available, err = c.hasStreamKeyVals(ctx)
+		if err != nil {
+			return err
+		}
+		c.streamKeyVals.set(available)
+	}
+	if !available {
+		return nil
+	}
+	streamKeyVals, err := c.nginxClient.GetAllStreamKeyValPairs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get stream keyvals: %w", err)
+	}
+	stats.streamKeyVals = streamKeyVals
+	return nil
+}
+
+// hasStreamKeyVals tells whether the API has stream keyval zones from its available endpoints.
+func (c *NginxPlusCollector) hasStreamKeyVals(ctx context.Context) (bool, error) {
+	endpoints, err := c.nginxClient.GetAvailableEndpoints(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get the available endpoints: %w", err)
+	}
+	if !slices.Contains(endpoints, "stream") {
+		return false, nil
+	}
+	streamEndpoints, err := c.nginxClient.GetAvailableStreamEndpoints(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get the available stream endpoints: %w", err)
+	}
+	return slices.Contains(streamEndpoints, "keyvals"), nil
+}
+
+// endpointCache remembers whether an endpoint of the API is available. The endpoints only change when NGINX Plus
+// reloads its configuration, so they are looked up again once the configuration generation changes.
+type endpointCache struct {
+	generation uint64
+	mutex      sync.Mutex
+	available  bool
+	known      bool
+}
+
+// get returns whether the endpoint is available, and whether that is known.
+func (e *endpointCache) get() (available, known bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.available, e.known
+}
+
+func (e *endpointCache) set(available bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.available = available
+	e.known = true
+}
+
+// reloaded forgets whether the endpoint is available once NGINX Plus reports a new configuration generation.
+func (e *endpointCache) reloaded(generation uint64) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if generation == e.generation {
+		return
+	}
+	// the first generation seen is the one the endpoint was looked up with
+	if e.generation != 0 {
+		e.known = false
+	}
+	e.generation = generation
+}
+
+// collectKeyVals sends the number of pairs of every keyval zone to ch, and the exported pairs when enabled.
+func (c *NginxPlusCollector) collectKeyVals(ch chan<- prometheus.Metric, stats *plusStats) {
+	for zone, pairs := range stats.keyVals {
+		ch <- prometheus.MustNewConstMetric(c.keyValMetrics["entries"], prometheus.GaugeValue, float64(len(pairs)), zone)
+	}
+	for zone, pairs := range stats.streamKeyVals {
+		ch <- prometheus.MustNewConstMetric(c.keyValMetrics["stream_entries"], prometheus.GaugeValue, float64(len(pairs)), zone)
+	}
+	if c.keyValExport == nil {
+		return
+	}
+
+	type exportedPair struct {
+		desc  *prometheus.Desc
+		zone  string
+		key   string
+		value string
+	}
+	var exported []exportedPair
+	for _, family := range []struct {
+		desc   *prometheus.Desc
+		byZone plusclient.KeyValPairsByZone
+	}{
+		{desc: c.keyValMetrics["key_info"], byZone: stats.keyVals},
+		{desc: c.keyValMetrics["stream_key_info"], byZone: stats.streamKeyVals},
+	} {
+		var pairs []exportedPair
+		for zone, zonePairs := range family.byZone {
+			for key, value := range zonePairs {
+				if c.keyValExport.keys.keep(zone + "/" + key) {
+					pairs = append(pairs, exportedPair{desc: family.desc, zone: zone, key: key, value: value})
+				}
+			}
+		}
+		slices.SortFunc(pairs, func(a, b exportedPair) int {
+			return cmp.Or(cmp.Compare(a.zone, b.zone), cmp.Compare(a.key, b.key))
+		})
+		exported = append(exported, pairs...)
+	}
+
+	dropped := 0
+	if len(exported) > c.keyValExport.limit {
+		dropped = len(exported) - c.keyValExport.limit
+		exported = exported[:c.keyValExport.limit]
+		c.logger.Debug("dropped keyval pairs over the export limit", "limit", c.keyValExport.limit, "dropped", dropped)
+	}
+	for _, p := range exported {
+		ch <- prometheus.MustNewConstMetric(p.desc, prometheus.GaugeValue, 1, p.zone, p.key, p.value)
+	}
+	ch <- prometheus.MustNewConstMetric(c.keyValMetrics["keys_dropped"], prometheus.GaugeValue, float64(dropped))
+}
@@ -0,0 +1,108 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNginxPlusCollectorKeyVals(t *testing.T) {
+	t.Parallel()
+
+	routes := func(stream bool) map[string]string {
+		routes := map[string]string{
+			"/9/":             `["nginx", "http"]`,
+			"/9/http/keyvals": `{"flags": {"beta": "on", "dark_mode": "off"}, "allowlist": {"10.0.0.1": "1", "10.0.0.2": "1", "10.0.0.3": "1"}}`,
+		}
+		if stream {
+			routes["/9/"] = `["nginx", "http", "stream"]`
+			routes["/9/stream"] = `["server_zones", "keyvals"]`
+			routes["/9/stream/keyvals"] = `{"routes": {"tenant-a": "pool1"}}`
+		}
+		return routes
+	}
+
+	tests := []struct {
+		opts    []NginxPlusCollectorOption
+		name    string
+		want    string
+		metrics []string
+		stream  bool
+	}{
+		{
+			name:    "entries",
+			stream:  true,
+			metrics: []string{"nginxplus_keyval_entries", "nginxplus_stream_keyval_entries", "nginxplus_keyval_key_info", "nginx_exporter_keyval_keys_dropped"},
+			want: `# HELP nginxplus_keyval_entries Number of key-value pairs in the zone
+# TYPE nginxplus_keyval_entries gauge
+nginxplus_keyval_entries{zone="allowlist"} 3
+nginxplus_keyval_entries{zone="flags"} 2
+# HELP nginxplus_stream_keyval_entries Number of key-value pairs in the zone
+# TYPE nginxplus_stream_keyval_entries gauge
+nginxplus_stream_keyval_entries{zone="routes"} 1
+`,
+		},
+		{
+			name:    "no stream block",
+			metrics: []string{"nginxplus_keyval_entries", "nginxplus_stream_keyval_entries"},
+			want: `# HELP nginxplus_keyval_entries Number of key-value pairs in the zone
+# TYPE nginxplus_keyval_entries gauge
+nginxplus_keyval_entries{zone="allowlist"} 3
+nginxplus_keyval_entries{zone="flags"} 2
+`,
+		},
+		{
+			name:    "exported keys",
+			stream:  true,
+			opts:    []NginxPlusCollectorOption{WithKeyValKeys(mustNameFilter(t, "flags/.*|routes/.*"), 10)},
+			metrics: []string{"nginxplus_keyval_key_info", "nginxplus_stream_keyval_key_info", "nginx_exporter_keyval_keys_dropped"},
+			want: `# HELP nginx_exporter_keyval_keys_dropped Number of key-value pairs matching the exported keys that were left out by the export limit in the last scrape
+# TYPE nginx_exporter_keyval_keys_dropped gauge
+nginx_exporter_keyval_keys_dropped 0
+# HELP nginxplus_keyval_key_info Value of an exported key of the zone
+# TYPE nginxplus_keyval_key_info gauge
+nginxplus_keyval_key_info{key="beta",value="on",zone="flags"} 1
+nginxplus_keyval_key_info{key="dark_mode",value="off",zone="flags"} 1
+# HELP nginxplus_stream_keyval_key_info Value of an exported key of the zone
+# TYPE nginxplus_stream_keyval_key_info gauge
+nginxplus_stream_keyval_key_info{key="tenant-a",value="pool1",zone="routes"} 1
+`,
+		},
+		{
+			name:    "export limit",
+			stream:  true,
+			opts:    []NginxPlusCollectorOption{WithKeyValKeys(mustNameFilter(t, ".*"), 2)},
+			metrics: []string{"nginxplus_keyval_key_info", "nginxplus_stream_keyval_key_info", "nginx_exporter_keyval_keys_dropped"},
+			want: `# HELP nginx_exporter_keyval_keys_dropped Number of key-value pairs matching the exported keys that were left out by the export limit in the last scrape
+# TYPE nginx_exporter_keyval_keys_dropped gauge
+nginx_exporter_keyval_keys_dropped 4
+# HELP nginxplus_keyval_key_info Value of an exported key of the zone
+# TYPE nginxplus_keyval_key_info gauge
+nginxplus_keyval_key_info{key="10.0.0.1",value="1",zone="allowlist"} 1
+nginxplus_keyval_key_info{key="10.0.0.2",value="1",zone="allowlist"} 1
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			opts := append([]NginxPlusCollectorOption{WithSections(SectionKeyVals)}, tt.opts...)
+			c := newTestPlusCollector(t, routes(tt.stream), opts...)
+
+			if err := testutil.CollectAndCompare(c, strings.NewReader(tt.want), tt.metrics...); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func mustNameFilter(t *testing.T, include string) NameFilter {
+	t.Helper()
+	f, err := NewNameFilter(include, "")
+	if err != nil {
+		t.Fatalf("NewNameFilter() returned error: %v", err)
+	}
+	return f
+}
@@ -0,0 +1,161 @@
+package collector
+
+import (
+	"slices"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// labelKinds are all the kinds of NGINX Plus objects that can have variable labels.
+var labelKinds = []string{
+	LabelKindUpstream,
+	LabelKindServerZone,
+	LabelKindUpstreamPeer,
+	LabelKindStreamUpstream,
+	LabelKindStreamServerZone,
+	LabelKindStreamUpstreamPeer,
+	LabelKindCacheZone,
+	LabelKindLocationZone,
+	LabelKindResolver,
+	LabelKindLimitRequest,
+	LabelKindLimitConnection,
+	LabelKindStreamLimitConnection,
+	LabelKindStreamZoneSyncZone,
+}
+
+// WithLabelExpiry evicts the label values of the objects that didn't match an object of NGINX Plus for the given
+// number of scrapes or for the given duration, whichever comes first. A zero value disables the limit. Only the
+// successful scrapes count, and the objects left out by the name filters or the disabled sections never match.
+// The values set with LabelTx.SetPinned are never evicted.
+func WithLabelExpiry(scrapes int, ttl time.Duration) NginxPlusCollectorOption {
+	return func(c *NginxPlusCollector) {
+		if scrapes <= 0 && ttl <= 0 {
+			c.labelExpiry = nil
+			return
+		}
+		c.labelExpiry = &labelExpiry{
+			scrapes:     uint64(max(scrapes, 0)),
+			ttl:         ttl,
+			lastMatched: make(map[labelKey]labelMatch),
+		}
+	}
+}
+
+func newLabelStoreEntriesMetric(constLabels map[string]string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(exporterNamespace, "", "label_store_entries"), "Number of objects with variable label values in the label store",
+		[]string{"kind"}, constLabels)
+}
+
+type labelKey struct {
+	kind string
+	name string
+}
+
+// labelMatch is the last scrape in which the label values of an object matched an object of NGINX Plus.
+type labelMatch struct {
+	time   time.Time
+	scrape uint64
+}
+
+// labelExpiry keeps track of the objects of the label store that match the objects of NGINX Plus.
+type labelExpiry struct {
+	lastMatched map[labelKey]labelMatch
+	ttl         time.Duration
+	scrapes     uint64
+	scrape      uint64 // number of successful scrapes
+}
+
+// expired returns the names of the objects of the scrape labels, by kind, that expired after the scrape. The
+// objects that are new to the store are considered matched by the scrape, and the pinned objects never expire.
+func (e *labelExpiry) expired(labels *scrapeLabels, now time.Time) map[string][]string {
+	e.scrape++
+	expired := make(map[string][]string)
+	stored := make(map[labelKey]bool, len(e.lastMatched))
+	for kind, objects := range labels.snapshot.values {
+		for name := range objects {
+			key := labelKey{kind: kind, name: name}
+			if labels.snapshot.pinned[key] {
+				continue
+			}
+			stored[key] = true
+			last, ok := e.lastMatched[key]
+			if !ok || labels.matched[key] {
+				e.lastMatched[key] = labelMatch{time: now, scrape: e.scrape}
+				continue
+			}
+			if (e.scrapes > 0 && e.scrape-last.scrape >= e.scrapes) || (e.ttl > 0 && now.Sub(last.time) >= e.ttl) {
+				expired[kind] = append(expired[kind], name)
+			}
+		}
+	}
+	// forget the objects deleted from the store
+	for key := range e.lastMatched {
+		if !stored[key] {
+			delete(e.lastMatched, key)
+		}
+	}
+	return expired
+}
+
+// scrapeLabels are the variable labels used by a scrape: a single version of the label store, and the objects
+// of the store that matched an object of NGINX Plus.
+type scrapeLabels struct {
+	snapshot *labelSnapshot
+	matched  map[labelKey]bool
+}
+
+func newScrapeLabels(snapshot *labelSnapshot) *scrapeLabels {
+	return &scrapeLabels{
+		snapshot: snapshot,
+		matched:  make(map[labelKey]bool),
+	}
+}
+
+// values returns the label values of the object of kind with the given name, and marks the object as matched.
+func (l *scrapeLabels) values(kind, name string) []string {
+	values, ok := l.snapshot.values[kind][name]
+	if ok {
+		l.matched[labelKey{kind: kind, name: name}] = true
+	}
+	return values
+}
+
+// expireLabels evicts the expired label values from the store after a successful scrape. The values changed
+// since the version used by the scrape are kept.
+func (c *NginxPlusCollector) expireLabels(labels *scrapeLabels) {
+	if c.labelExpiry == nil {
+		return
+	}
+	expired := c.labelExpiry.expired(labels, time.Now())
+	if len(expired) == 0 {
+		return
+	}
+
+	evicted := 0
+	c.labels.Update(func(tx *LabelTx) {
+		for kind, names := range expired {
+			var unchanged []string
+			for _, name := range names {
+				current, ok := tx.values[kind][name]
+				if ok && slices.Equal(current, labels.snapshot.values[kind][name]) {
+					unchanged = append(unchanged, name)
+				}
+			}
+			tx.Delete(kind, unchanged)
+			evicted += len(unchanged)
+		}
+	})
+	c.logger.Debug("evicted expired label values", "objects", evicted)
+}
+
+// collectLabelStoreEntries sends the number of objects of the label store of every kind with variable labels to ch.
+func (c *NginxPlusCollector) collectLabelStoreEntries(ch chan<- prometheus.Metric) {
+	values := c.labels.snapshot().values
+	for _, kind := range labelKinds {
+		if c.labelNames(kind) == nil {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.labelStoreEntriesMetric, prometheus.GaugeValue, float64(len(values[kind])), kind)
+	}
+}
@@ -0,0 +1,139 @@
+package collector
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLabelExpiry(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	snapshot := &labelSnapshot{values: map[string]map[string][]string{
+		LabelKindUpstream: {"backend": {"payments"}, "legacy": {"core"}},
+	}}
+	// scrapes lists the objects matched by every scrape and when it happens
+	type scrape struct {
+		at      time.Time
+		want    map[string][]string
+		matched []string
+	}
+	tests := []struct {
+		name    string
+		scrapes []scrape
+		limit   int
+		ttl     time.Duration
+	}{
+		{
+			name:  "scrapes",
+			limit: 2,
+			scrapes: []scrape{
+				{at: start, matched: []string{"backend"}, want: map[string][]string{}},
+				{at: start, matched: []string{"backend"}, want: map[string][]string{}},
+				{at: start, matched: []string{"backend"}, want: map[string][]string{LabelKindUpstream: {"legacy"}}},
+			},
+		},
+		{
+			name: "ttl",
+			ttl:  time.Minute,
+			scrapes: []scrape{
+				{at: start, matched: []string{"backend"}, want: map[string][]string{}},
+				{at: start.Add(30 * time.Second), matched: []string{"backend"}, want: map[string][]string{}},
+				{at: start.Add(time.Minute), matched: []string{"backend"}, want: map[string][]string{LabelKindUpstream: {"legacy"}}},
+			},
+		},
+		{
+			name:  "matched again",
+			limit: 2,
+			scrapes: []scrape{
+				{at: start, want: map[string][]string{}},
+				{at: start, matched: []string{"backend", "legacy"}, want: map[string][]string{}},
+				{at: start, want: map[string][]string{}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var c NginxPlusCollector
+			WithLabelExpiry(tt.limit, tt.ttl)(&c)
+			for i, s := range tt.scrapes {
+				labels := newScrapeLabels(snapshot)
+				for _, name := range s.matched {
+					labels.values(LabelKindUpstream, name)
+				}
+				if got := c.labelExpiry.expired(labels, s.at); !reflect.DeepEqual(got, s.want) {
+					t.Errorf("scrape %v: expired() = %v, want %v", i, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNginxPlusCollectorLabelExpiry(t *testing.T) {
+	t.Parallel()
+
+	c := newTestPlusCollectorWithLabels(t, map[string]string{"/9/http/server_zones": `{"app": {}}`},
+		map[string][]string{LabelKindServerZone: {"team"}}, WithSections(SectionServerZones), WithLabelExpiry(2, 0))
+	c.UpdateServerZoneLabels(map[string][]string{"app": {"web"}, "removed": {"web"}})
+
+	for range 2 {
+		if n := testutil.CollectAndCount(c, "nginxplus_server_zone_requests"); n != 1 {
+			t.Fatalf("CollectAndCount() = %v, want 1", n)
+		}
+	}
+	// the third scrape evicts the zone that NGINX doesn't report
+	want := `# HELP nginx_exporter_label_store_entries Number of objects with variable label values in the label store
+# TYPE nginx_exporter_label_store_entries gauge
+nginx_exporter_label_store_entries{kind="server_zone"} 1
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(want), "nginx_exporter_label_store_entries"); err != nil {
+		t.Error(err)
+	}
+	if got, want := c.LabelStore().Values(LabelKindServerZone), map[string][]string{"app": {"web"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Values() = %v, want %v", got, want)
+	}
+}
+
+func TestNginxPlusCollectorLabelExpiryPinned(t *testing.T) {
+	t.Parallel()
+
+	// the zones of NGINX go away for some scrapes, then come back
+	var gone atomic.Bool
+	zones := plusRoutes(map[string]string{"/9/http/server_zones": `{"app": {}, "pushed": {}}`})
+	nginx := newTestPlusServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if gone.Load() {
+			plusRoutes(nil)(w, r)
+			return
+		}
+		zones(w, r)
+	}))
+	c := newTestPlusCollectorOfClient(newTestPlusClient(t, nginx), map[string][]string{LabelKindServerZone: {"team"}},
+		WithSections(SectionServerZones), WithLabelExpiry(2, 0))
+	c.LabelStore().Update(func(tx *LabelTx) {
+		tx.SetPinned(LabelKindServerZone, map[string][]string{"app": {"web"}})
+		tx.Set(LabelKindServerZone, map[string][]string{"pushed": {"web"}})
+	})
+
+	gone.Store(true)
+	for range 3 {
+		testutil.CollectAndCount(c, "nginxplus_server_zone_requests")
+	}
+	gone.Store(false)
+
+	want := `# HELP nginxplus_server_zone_requests Total client requests
+# TYPE nginxplus_server_zone_requests counter
+nginxplus_server_zone_requests{server_zone="app",team="web"} 0
+nginxplus_server_zone_requests{server_zone="pushed",team=""} 0
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(want), "nginxplus_server_zone_requests"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,304 @@
+package collector
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// labelFileFormat is the version of the format of the label files. Files of other versions are ignored.
+const labelFileFormat = 1
+
+// labelFile is the content of a label file. The checksum covers all the other fields.
+type labelFile struct {
+	LabelNames map[string][]string            `json:"label_names"`
+	Values     map[string]map[string][]string `json:"values"`
+	Checksum   string                         `json:"checksum"`
+	Format     int                            `json:"format"`
+}
+
+func (f labelFile) checksum() (string, error) {
+	f.Checksum = ""
+	// maps are encoded with sorted keys, so the same content always has the same checksum
+	data, err := json.Marshal(f)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// WithLabelFile persists the label store of the collector to the file at path, so that the label values survive
+// a restart. The values are loaded from the file when the collector is created, and the file is replaced atomically
+// in the background after the transactions. A corrupt file, or a file written for other label names, is ignored.
+//
+// A label file is written by a single collector at a time. The collector only writes the file if no other collector
+// writes it; a collector replacing another one must call ClaimLabelFile once it is used instead of the other one, and
+// a collector that is no longer used must call ReleaseLabelFile.
+func WithLabelFile(path string) NginxPlusCollectorOption {
+	return func(c *NginxPlusCollector) {
+		labelNames := c.kindLabelNames()
+		values, err := readLabelFile(path, labelNames)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			c.logger.Warn("ignoring the label file", "path", path, "error", err.Error())
+		default:
+			c.labels.Update(func(tx *LabelTx) {
+				for kind, objects := range values {
+					tx.Replace(kind, objects)
+				}
+			})
+		}
+		c.labelFile = newLabelFileWriter(path, labelNames, c.logger)
+		c.labels.persist = c.labelFile.store
+	}
+}
+
+// ClaimLabelFile makes the collector the writer of its label file in place of the collector that wrote it so far,
+// and saves the current label values. It has no effect if the collector has no label file.
+func (c *NginxPlusCollector) ClaimLabelFile() {
+	if c.labelFile == nil {
+		return
+	}
+	c.labelFile.file.mutex.Lock()
+	c.labelFile.file.owner = c.labelFile
+	c.labelFile.file.mutex.Unlock()
+	c.labelFile.store(c.labels.snapshot())
+}
+
+// ReleaseLabelFile stops the collector from writing its label file, once it was replaced by the collector claiming
+// the file or discarded. The file is forgotten once none of its collectors are in use. It has no effect if the
+// collector has no label file.
+func (c *NginxPlusCollector) ReleaseLabelFile() {
+	if c.labelFile == nil {
+		return
+	}
+	c.labelFile.release()
+}
+
+// labelFiles are the label files in use, by path.
+var (
+	labelFiles      = make(map[string]*sharedLabelFile)
+	labelFilesMutex sync.Mutex
+)
+
+// sharedLabelFile is a label file that the collectors of a target share across reloads. Only its owner writes it,
+// so that a collector being replaced doesn't overwrite the values saved by the collector replacing it.
+type sharedLabelFile struct {
+	owner *labelFileWriter
+	mutex sync.Mutex // serializes the writes and the changes of the owner
+	// writers is the number of writers of the file that were not released, guarded by labelFilesMutex.
+	writers int
+}
+
+// labelFileWriter writes the versions of a label store to a label file in the background. When the versions come
+// faster than the file is written, only the latest one is written.
+type labelFileWriter struct {
+	logger     *slog.Logger
+	labelNames map[string][]string
+	file       *sharedLabelFile
+	pending    *labelSnapshot
+	idle       *sync.Cond
+	path       string
+	// stored is the version of the latest snapshot stored.
+	stored  uint64
+	mutex   sync.Mutex
+	writing bool
+	// released is set once the writer no longer writes the file, guarded by labelFilesMutex.
+	released bool
+}
+
+// newLabelFileWriter creates a writer of the label file at path, which owns the file if no other writer does.
+func newLabelFileWriter(path string, labelNames map[string][]string, logger *slog.Logger) *labelFileWriter {
+	labelFilesMutex.Lock()
+	file, ok := labelFiles[path]
+	if !ok {
+		file = &sharedLabelFile{}
+		labelFiles[path] = file
+	}
+	file.writers++
+	labelFilesMutex.Unlock()
+
+	w := &labelFileWriter{
+		logger:     logger,
+		labelNames: labelNames,
+		file:       file,
+		path:       path,
+	}
+	w.idle = sync.NewCond(&w.mutex)
+	file.mutex.Lock()
+	if file.owner == nil {
+		file.owner = w
+	}
+	file.mutex.Unlock()
+	return w
+}
+
+// release gives up the ownership of the file, if the writer has it, and forgets the file once all its writers
+// are released.
+func (w *labelFileWriter) release() {
+	labelFilesMutex.Lock()
+	defer labelFilesMutex.Unlock()
+
+	if w.released {
+		return
+	}
+	w.released = true
+	w.file.mutex.Lock()
+	if w.file.owner == w {
+		w.file.owner = nil
+	}
+	w.file.mutex.Unlock()
+	w.file.writers--
+	if w.file.writers == 0 {
+		delete(labelFiles, w.path)
+	}
+}
+
+// store schedules the writing of snapshot, replacing the version waiting to be written, if any. Snapshots older
+// than the latest one stored are ignored. It doesn't block.
+func (w *labelFileWriter) store(snapshot *labelSnapshot) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if snapshot.version < w.stored {
+		return
+	}
+	w.stored = snapshot.version
+	w.pending = snapshot
+	if !w.writing {
+		w.writing = true
+		go w.run()
+	}
+}
+
+// run writes the pending versions until there are none left.
+func (w *labelFileWriter) run() {
+	for {
+		w.mutex.Lock()
+		snapshot := w.pending
+		w.pending = nil
+		if snapshot == nil {
+			w.writing = false
+			w.idle.Broadcast()
+			w.mutex.Unlock()
+			return
+		}
+		w.mutex.Unlock()
+
+		w.write(snapshot)
+	}
+}
+
+// write writes snapshot to the label file if the writer owns it.
+func (w *labelFileWriter) write(snapshot *labelSnapshot) {
+	w.file.mutex.Lock()
+	defer w.file.mutex.Unlock()
+
+	if w.file.owner != w {
+		return
+	}
+	if err := writeLabelFile(w.path, w.labelNames, snapshot.values); err != nil {
+		w.logger.Warn("error writing the label file", "path", w.path, "error", err.Error())
+	}
+}
+
+// wait waits until the stored versions are written.
+func (w *labelFileWriter) wait() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for w.writing {
+		w.idle.Wait()
+	}
+}
+
+// kindLabelNames returns the variable label names of every kind that has some.
+func (c *NginxPlusCollector) kindLabelNames() map[string][]string {
+	labelNames := make(map[string][]string)
+	for _, kind := range labelKinds {
+		if names := c.labelNames(kind); names != nil {
+			labelNames[kind] = names
+		}
+	}
+	return labelNames
+}
+
+// readLabelFile reads the label values of the file at path, which must have been written for labelNames.
+func readLabelFile(path string, labelNames map[string][]string) (map[string]map[string][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f labelFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("corrupt label file: %w", err)
+	}
+	if f.Format != labelFileFormat {
+		return nil, fmt.Errorf("unsupported label file format %v", f.Format)
+	}
+	sum, err := f.checksum()
+	if err != nil {
+		return nil, err
+	}
+	if sum != f.Checksum {
+		return nil, errors.New("corrupt label file: checksum mismatch")
+	}
+	if !maps.EqualFunc(f.LabelNames, labelNames, slices.Equal[[]string]) {
+		return nil, errors.New("the label file was written for other label names")
+	}
+	return f.Values, nil
+}
+
+// writeLabelFile replaces the file at path with the label values of the kinds of labelNames. The file is written
+// to a temporary file first, so that it is never left half written.
+func writeLabelFile(path string, labelNames map[string][]string, values map[string]map[string][]string) error {
+	f := labelFile{
+		LabelNames: labelNames,
+		Values:     make(map[string]map[string][]string, len(labelNames)),
+		Format:     labelFileFormat,
+	}
+	for kind := range labelNames {
+		if len(values[kind]) > 0 {
+			f.Values[kind] = values[kind]
+		}
+	}
+	sum, err := f.checksum()
+	if err != nil {
+		return err
+	}
+	f.Checksum = sum
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package collector
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadLabelFile(t *testing.T) {
+	t.Parallel()
+
+	labelNames := map[string][]string{LabelKindUpstream: {"team"}}
+	values := map[string]map[string][]string{LabelKindUpstream: {"backend": {"payments"}}}
+	valid := filepath.Join(t.TempDir(), "labels.json")
+	if err := writeLabelFile(valid, labelNames, values); err != nil {
+		t.Fatalf("writeLabelFile() returned error: %v", err)
+	}
+	data, err := os.ReadFile(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		labelNames map[string][]string
+		name       string
+		content    string
+		wantErr    bool
+	}{
+		{
+			name:       "valid",
+			content:    string(data),
+			labelNames: labelNames,
+		},
+		{
+			name:       "truncated",
+			content:    string(data[:len(data)/2]),
+			labelNames: labelNames,
+			wantErr:    true,
+		},
+		{
+			name:       "checksum mismatch",
+			content:    strings.Replace(string(data), "payments", "checkout", 1),
+			labelNames: labelNames,
+			wantErr:    true,
+		},
+		{
+			name:       "unsupported format",
+			content:    strings.Replace(string(data), `"format":1`, `"format":2`, 1),
+			labelNames: labelNames,
+			wantErr:    true,
+		},
+		{
+			name:       "other label names",
+			content:    string(data),
+			labelNames: map[string][]string{LabelKindUpstream: {"team", "tier"}},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "labels.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readLabelFile(path, tt.labelNames)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readLabelFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, values) {
+				t.Errorf("readLabelFile() = %v, want %v", got, values)
+			}
+		})
+	}
+}
+
+func TestNginxPlusCollectorLabelFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "labels.json")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	labelNames := NewVariableLabelNames([]string{"team"}, nil, nil, nil, nil, nil, nil)
+
+	c := NewNginxPlusCollector(nil, "nginxplus", labelNames, nil, logger, WithLabelFile(path))
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("the label file exists before any change: %v", err)
+	}
+	c.UpdateUpstreamServerLabels(map[string][]string{"backend": {"payments"}, "legacy": {"core"}})
+	c.DeleteUpstreamServerLabels([]string{"legacy"})
+	c.labelFile.wait()
+
+	// a new collector starts with the saved values
+	restarted := NewNginxPlusCollector(nil, "nginxplus", labelNames, nil, logger, WithLabelFile(path))
+	want := map[string][]string{"backend": {"payments"}}
+	if got := restarted.LabelStore().Values(LabelKindUpstream); !reflect.DeepEqual(got, want) {
+		t.Errorf("Values() = %v, want %v", got, want)
+	}
+	// no temporary file is left behind
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("the directory has %v entries, want only the label file", len(entries))
+	}
+
+	// the values of a file written for other label names are ignored
+	other := NewNginxPlusCollector(nil, "nginxplus", NewVariableLabelNames([]string{"team", "tier"}, nil, nil, nil, nil, nil, nil), nil, logger,
+		WithLabelFile(path))
+	if got := other.LabelStore().Values(LabelKindUpstream); len(got) != 0 {
+		t.Errorf("Values() = %v, want no values", got)
+	}
+}
+
+func TestNginxPlusCollectorClaimLabelFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "labels.json")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	labelNames := NewVariableLabelNames([]string{"team"}, nil, nil, nil, nil, nil, nil)
+	saved := func() map[string][]string {
+		t.Helper()
+		values, err := readLabelFile(path, map[string][]string{LabelKindUpstream: {"team"}})
+		if err != nil {
+			t.Fatalf("readLabelFile() returned error: %v", err)
+		}
+		return values[LabelKindUpstream]
+	}
+
+	previous := NewNginxPlusCollector(nil, "nginxplus", labelNames, nil, logger, WithLabelFile(path))
+	previous.UpdateUpstreamServerLabels(map[string][]string{"backend": {"payments"}})
+	previous.labelFile.wait()
+
+	// the collector replacing the previous one doesn't write the file until it claims it
+	next := NewNginxPlusCollector(nil, "nginxplus", labelNames, nil, logger, WithLabelFile(path))
+	next.UpdateUpstreamServerLabels(map[string][]string{"backend": {"checkout"}})
+	next.labelFile.wait()
+	if got, want := saved(), map[string][]string{"backend": {"payments"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("saved values before the claim = %v, want %v", got, want)
+	}
+
+	next.ClaimLabelFile()
+	next.labelFile.wait()
+	previous.UpdateUpstreamServerLabels(map[string][]string{"backend": {"core"}})
+	previous.labelFile.wait()
+	if got, want := saved(), map[string][]string{"backend": {"checkout"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("saved values after the claim = %v, want %v", got, want)
+	}
+}
+
+func TestNginxPlusCollectorReleaseLabelFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "labels.json")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	labelNames := NewVariableLabelNames([]string{"team"}, nil, nil, nil, nil, nil, nil)
+	tracked := func() bool {
+		labelFilesMutex.Lock()
+		defer labelFilesMutex.Unlock()
+		_, ok := labelFiles[path]
+		return ok
+	}
+
+	previous := NewNginxPlusCollector(nil, "nginxplus", labelNames, nil, logger, WithLabelFile(path))
+	next := NewNginxPlusCollector(nil, "nginxplus", labelNames, nil, logger, WithLabelFile(path))
+	next.ClaimLabelFile()
+	previous.ReleaseLabelFile()
+	previous.ReleaseLabelFile()
+	if !tracked() {
+		t.Error("the label file was forgotten while its owner is in use")
+	}
+	next.ReleaseLabelFile()
+	if tracked() {
+		t.Error("the label file is still tracked after all its collectors were released")
+	}
+
+	// a collector created later writes the file without claiming it
+	later := NewNginxPlusCollector(nil, "nginxplus", labelNames, nil, logger, WithLabelFile(path))
+	t.Cleanup(later.ReleaseLabelFile)
+	later.UpdateUpstreamServerLabels(map[string][]string{"backend": {"payments"}})
+	later.labelFile.wait()
+	values, err := readLabelFile(path, map[string][]string{LabelKindUpstream: {"team"}})
+	if err != nil {
+		t.Fatalf("readLabelFile() returned error: %v", err)
+	}
+	if got, want := values[LabelKindUpstream], map[string][]string{"backend": {"payments"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("saved values = %v, want %v", got, want)
+	}
+}
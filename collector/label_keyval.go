@@ -0,0 +1,96 @@
+package collector
+
+import (
+	"context"
+	"net/url"
+	"slices"
+	"strings"
+
+	plusclient "github.com/nginx/nginx-plus-go-client/v2/client"
+)
+
+// WithLabelKeyValZone reads the variable label values of the objects from the HTTP keyval zone of NGINX Plus with
+// the given name on every fetch of the stats, and applies their changes to the label store. The keys are the kind and
+// the name of an object separated by a colon, such as upstream:backend, and the values are the labels in URL query
+// encoding, such as team=payments&tier=gold. Labels that are not given are empty, and unknown labels are ignored.
+func WithLabelKeyValZone(zone string) NginxPlusCollectorOption {
+	return func(c *NginxPlusCollector) {
+		c.labelZone = zone
+	}
+}
+
+// fetchKeyValLabels fetches the pairs of the label keyval zone. The stats are still collected when the zone can't
+// be read, and the label values are left unchanged.
+func (c *NginxPlusCollector) fetchKeyValLabels(ctx context.Context, stats *plusStats) {
+	pairs, err := c.nginxClient.GetKeyValPairs(ctx, c.labelZone)
+	if err != nil {
+		c.logger.Warn("error getting the label keyval zone", "zone", c.labelZone, "error", err.Error())
+		return
+	}
+	stats.labelPairs = pairs
+}
+
+// applyKeyValLabels applies the changes of the label values read from the keyval zone since the previous call
+// to the label store, in a single transaction. The values set by other means are only replaced when the values of
+// the keyval zone change. The values of the zone are pinned, so that the label expiry doesn't evict them, and the
+// values missing from the store, deleted by other means, are set again.
+func (c *NginxPlusCollector) applyKeyValLabels(pairs plusclient.KeyValPairs) {
+	values := make(map[string]map[string][]string)
+	for key, value := range pairs {
+		kind, name, ok := strings.Cut(key, ":")
+		labelNames := c.labelNames(kind)
+		if !ok || labelNames == nil {
+			c.logger.Debug("ignoring the key of the label keyval zone", "key", key)
+			continue
+		}
+		labels, err := url.ParseQuery(value)
+		if err != nil {
+			c.logger.Warn("invalid labels in the label keyval zone", "key", key, "error", err.Error())
+			continue
+		}
+		objectValues := make([]string, len(labelNames))
+		for i, label := range labelNames {
+			objectValues[i] = labels.Get(label)
+		}
+		if values[kind] == nil {
+			values[kind] = make(map[string][]string)
+		}
+		values[kind][name] = objectValues
+	}
+
+	deleted := make(map[string][]string)
+	for kind, objects := range c.keyValLabels {
+		for name := range objects {
+			if _, ok := values[kind][name]; !ok {
+				deleted[kind] = append(deleted[kind], name)
+			}
+		}
+	}
+	current := c.labels.snapshot()
+	updated := make(map[string]map[string][]string)
+	for kind, objects := range values {
+		for name, v := range objects {
+			_, stored := current.values[kind][name]
+			if previous, ok := c.keyValLabels[kind][name]; ok && stored && slices.Equal(previous, v) {
+				continue
+			}
+			if updated[kind] == nil {
+				updated[kind] = make(map[string][]string)
+			}
+			updated[kind][name] = v
+		}
+	}
+	c.keyValLabels = values
+	if len(deleted) == 0 && len(updated) == 0 {
+		return
+	}
+
+	c.labels.Update(func(tx *LabelTx) {
+		for kind, names := range deleted {
+			tx.Delete(kind, names)
+		}
+		for kind, objects := range updated {
+			tx.SetPinned(kind, objects)
+		}
+	})
+}
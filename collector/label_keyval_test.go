@@ -0,0 +1,87 @@
+package collector
+
+import (
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNginxPlusCollectorLabelKeyValZone(t *testing.T) {
+	t.Parallel()
+
+	initial := `{
+		"upstream:backend": "team=payments&tier=gold",
+		"upstream:legacy": "tier=bronze&unknown=ignored",
+		"upstream_peer:backend/10.0.0.1:80": "az=eu-west-1a",
+		"cache_zone:images": "team=media",
+		"invalid": "team=core"
+	}`
+	var pairs atomic.Pointer[string]
+	pairs.Store(&initial)
+	nginx := newTestPlusServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		plusRoutes(map[string]string{"/9/http/keyvals/labels": *pairs.Load()})(w, r)
+	}))
+	c := newTestPlusCollectorOfClient(newTestPlusClient(t, nginx), map[string][]string{LabelKindUpstream: {"team", "tier"}, LabelKindUpstreamPeer: {"az"}},
+		WithSections(SectionUpstreams), WithLabelKeyValZone("labels"))
+	// values set by other means are kept until the keyval zone changes them
+	c.UpdateUpstreamServerLabels(map[string][]string{"checkout": {"identity", "silver"}})
+
+	testutil.CollectAndCount(c)
+	tests := []struct {
+		want map[string][]string
+		kind string
+	}{
+		{
+			kind: LabelKindUpstream,
+			want: map[string][]string{"backend": {"payments", "gold"}, "legacy": {"", "bronze"}, "checkout": {"identity", "silver"}},
+		},
+		{
+			kind: LabelKindUpstreamPeer,
+			want: map[string][]string{"backend/10.0.0.1:80": {"eu-west-1a"}},
+		},
+		{
+			// cache zones have no label names
+			kind: LabelKindCacheZone,
+			want: map[string][]string{},
+		},
+	}
+	for _, tt := range tests {
+		if got := c.LabelStore().Values(tt.kind); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Values(%q) = %v, want %v", tt.kind, got, tt.want)
+		}
+	}
+
+	// the pairs removed from the zone are removed from the store
+	changed := `{"upstream:backend": "team=payments&tier=silver"}`
+	pairs.Store(&changed)
+	testutil.CollectAndCount(c)
+	want := map[string][]string{"backend": {"payments", "silver"}, "checkout": {"identity", "silver"}}
+	if got := c.LabelStore().Values(LabelKindUpstream); !reflect.DeepEqual(got, want) {
+		t.Errorf("Values() = %v, want %v", got, want)
+	}
+	if got := c.LabelStore().Values(LabelKindUpstreamPeer); len(got) != 0 {
+		t.Errorf("Values(%q) = %v, want no values", LabelKindUpstreamPeer, got)
+	}
+
+	// unchanged pairs don't change the store
+	version := c.LabelStore().Version()
+	testutil.CollectAndCount(c)
+	if got := c.LabelStore().Version(); got != version {
+		t.Errorf("Version() = %v after a scrape without changes, want %v", got, version)
+	}
+
+	// the values of the zone are pinned, and set again once deleted by other means
+	if !c.LabelStore().snapshot().pinned[labelKey{kind: LabelKindUpstream, name: "backend"}] {
+		t.Errorf("the values of %q are not pinned", "backend")
+	}
+	c.LabelStore().Update(func(tx *LabelTx) {
+		tx.Delete(LabelKindUpstream, []string{"backend"})
+	})
+	testutil.CollectAndCount(c)
+	if got := c.LabelStore().Values(LabelKindUpstream); !reflect.DeepEqual(got, want) {
+		t.Errorf("Values() = %v after deleting a value of the zone, want %v", got, want)
+	}
+}
@@ -0,0 +1,133 @@
+package collector
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Kinds of NGINX Plus objects that can have variable labels. Peers are named upstream/server.
+const (
+	LabelKindUpstream              = "upstream"
+	LabelKindServerZone            = "server_zone"
+	LabelKindUpstreamPeer          = "upstream_peer"
+	LabelKindStreamUpstream        = "stream_upstream"
+	LabelKindStreamServerZone      = "stream_server_zone"
+	LabelKindStreamUpstreamPeer    = "stream_upstream_peer"
+	LabelKindCacheZone             = "cache_zone"
+	LabelKindLocationZone          = "location_zone"
+	LabelKindResolver              = "resolver"
+	LabelKindLimitRequest          = "limit_request"
+	LabelKindLimitConnection       = "limit_connection"
+	LabelKindStreamLimitConnection = "stream_limit_connection"
+	LabelKindStreamZoneSyncZone    = "stream_zone_sync_zone"
+)
+
+// LabelRule derives variable label values from the names of NGINX Plus objects. When the regular expression
+// matches the full name of an object, the named capture groups become the values of the labels with the same
+// names.
+type LabelRule struct {
+	regexp *regexp.Regexp
+	expr   string
+}
+
+// NewLabelRule creates a LabelRule from a regular expression with at least one named capture group.
+func NewLabelRule(expr string) (LabelRule, error) {
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return LabelRule{}, fmt.Errorf("invalid label rule %q: %w", expr, err)
+	}
+	if len(LabelRule{regexp: re}.LabelNames()) == 0 {
+		return LabelRule{}, fmt.Errorf("label rule %q has no named capture group", expr)
+	}
+	return LabelRule{regexp: re, expr: expr}, nil
+}
+
+// LabelNames returns the names of the capture groups of the rule.
+func (r LabelRule) LabelNames() []string {
+	var names []string
+	for _, name := range r.regexp.SubexpNames() {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// String returns the regular expression of the rule.
+func (r LabelRule) String() string {
+	return r.expr
+}
+
+// labelValues returns the values of labelNames captured from name, which are empty for the labels without a
+// capture group, and whether the rule matches name.
+func (r LabelRule) labelValues(name string, labelNames []string) ([]string, bool) {
+	match := r.regexp.FindStringSubmatch(name)
+	if match == nil {
+		return nil, false
+	}
+	values := make([]string, len(labelNames))
+	for i, label := range labelNames {
+		if j := r.regexp.SubexpIndex(label); j > 0 {
+			values[i] = match[j]
+		}
+	}
+	return values, true
+}
+
+// WithLabelRules derives the variable label values of the objects of every kind from their names, using the
+// first matching rule. The values of the label store take precedence over the rules.
+func WithLabelRules(rules map[string][]LabelRule) NginxPlusCollectorOption {
+	return func(c *NginxPlusCollector) {
+		c.labelRules = rules
+	}
+}
+
+// labelNames returns the variable label names of the objects of kind.
+func (c *NginxPlusCollector) labelNames(kind string) []string {
+	if field := c.variableLabelNames.kind(kind); field != nil {
+		return *field
+	}
+	return nil
+}
+
+// validLabelValues returns the label values of the objects of kind that have a value for every label name. The
+// other objects are logged and left out when their values are set, rather than getting empty labels at every scrape.
+func (c *NginxPlusCollector) validLabelValues(kind string, values map[string][]string) map[string][]string {
+	labelNames := c.labelNames(kind)
+	valid := make(map[string][]string, len(values))
+	for name, v := range values {
+		if len(v) != len(labelNames) {
+			c.logger.Warn("ignoring label values with the wrong number of labels", "kind", kind, "name", name, "expected", len(labelNames), "got", len(v))
+			continue
+		}
+		valid[name] = v
+	}
+	return valid
+}
+
+// variableLabelValues returns the variable label values of the object of kind: the values of the label store,
+// or else the values captured by the first label rule matching name. The values are empty if there are none or
+// if the number of values doesn't match the label names.
+func (c *NginxPlusCollector) variableLabelValues(labels *scrapeLabels, kind string, name string) []string {
+	labelNames := c.labelNames(kind)
+	if labelNames == nil {
+		return nil
+	}
+
+	values := labels.values(kind, name)
+	if values == nil {
+		for _, rule := range c.labelRules[kind] {
+			if v, ok := rule.labelValues(name, labelNames); ok {
+				values = v
+				break
+			}
+		}
+	}
+	if len(values) != len(labelNames) {
+		if values != nil {
+			c.logger.Warn("wrong number of labels, empty labels will be used instead", "kind", kind, "name", name, "expected", len(labelNames), "got", len(values))
+		}
+		return make([]string, len(labelNames))
+	}
+	return values
+}
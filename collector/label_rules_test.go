@@ -0,0 +1,75 @@
+package collector
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNewLabelRule(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		expr           string
+		wantLabelNames []string
+		wantErr        bool
+	}{
+		{
+			name:           "named groups",
+			expr:           `(?P<namespace>[^-]+)-(?P<service>.+)-(\d+)`,
+			wantLabelNames: []string{"namespace", "service"},
+		},
+		{
+			name:    "no named group",
+			expr:    `([^-]+)-.+`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid expression",
+			expr:    `(?P<namespace>[^-]+`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			rule, err := NewLabelRule(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewLabelRule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := rule.LabelNames(); !reflect.DeepEqual(got, tt.wantLabelNames) {
+				t.Errorf("LabelNames() = %v, want %v", got, tt.wantLabelNames)
+			}
+		})
+	}
+}
+
+func TestNginxPlusCollectorLabelRules(t *testing.T) {
+	t.Parallel()
+
+	rule, err := NewLabelRule(`(?P<namespace>[^-]+)-(?P<service>.+)-\d+`)
+	if err != nil {
+		t.Fatalf("NewLabelRule() returned error: %v", err)
+	}
+	c := newTestPlusCollectorWithLabels(t, map[string]string{"/9/http/server_zones": `{"payments-api-8080": {}, "billing-web-80": {}, "legacy": {}}`},
+		map[string][]string{LabelKindServerZone: {"namespace", "service", "team"}},
+		WithSections(SectionServerZones), WithLabelRules(map[string][]LabelRule{LabelKindServerZone: {rule}}))
+	// the values set through the LabelUpdater take precedence over the rules
+	c.UpdateServerZoneLabels(map[string][]string{"billing-web-80": {"finance", "web", "billing"}})
+
+	want := `# HELP nginxplus_server_zone_requests Total client requests
+# TYPE nginxplus_server_zone_requests counter
+nginxplus_server_zone_requests{namespace="",server_zone="legacy",service="",team=""} 0
+nginxplus_server_zone_requests{namespace="finance",server_zone="billing-web-80",service="web",team="billing"} 0
+nginxplus_server_zone_requests{namespace="payments",server_zone="payments-api-8080",service="api",team=""} 0
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(want), "nginxplus_server_zone_requests"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,163 @@
+package collector
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// LabelStore holds the variable label values of NGINX Plus objects by kind and object name. The values are
+// changed by transactions applied at once, and every scrape reads the values of a single version of the
+// store, so that it never sees a transaction half applied.
+type LabelStore struct {
+	current atomic.Pointer[labelSnapshot]
+	// persist is called with every new version, in the order of the versions. It must not block, as it is called
+	// within the transaction.
+	persist func(snapshot *labelSnapshot)
+	mutex   sync.Mutex // serializes the transactions
+}
+
+// labelSnapshot is a version of the values of a LabelStore. It is never changed once published.
+type labelSnapshot struct {
+	values map[string]map[string][]string
+	// pinned are the objects whose values are never evicted by the label expiry.
+	pinned  map[labelKey]bool
+	version uint64
+}
+
+// NewLabelStore creates an empty LabelStore.
+func NewLabelStore() *LabelStore {
+	s := &LabelStore{}
+	s.current.Store(&labelSnapshot{values: make(map[string]map[string][]string)})
+	return s
+}
+
+func (s *LabelStore) snapshot() *labelSnapshot {
+	return s.current.Load()
+}
+
+// Version returns the version of the values, which is incremented by every transaction.
+func (s *LabelStore) Version() uint64 {
+	return s.snapshot().version
+}
+
+// Values returns a copy of the label values of the objects of kind.
+func (s *LabelStore) Values(kind string) map[string][]string {
+	values := make(map[string][]string)
+	for name, v := range s.snapshot().values[kind] {
+		values[name] = slices.Clone(v)
+	}
+	return values
+}
+
+// Update applies the changes made by fn to the transaction at once and returns the new version.
+func (s *LabelStore) Update(fn func(tx *LabelTx)) uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	current := s.snapshot()
+	tx := &LabelTx{
+		values: maps.Clone(current.values),
+		pinned: current.pinned,
+		copied: make(map[string]bool),
+	}
+	fn(tx)
+	next := &labelSnapshot{values: tx.values, pinned: tx.pinned, version: current.version + 1}
+	s.current.Store(next)
+	if s.persist != nil {
+		s.persist(next)
+	}
+	return next.version
+}
+
+// Replace replaces the label values of all objects of kind and returns the new version.
+func (s *LabelStore) Replace(kind string, values map[string][]string) uint64 {
+	return s.Update(func(tx *LabelTx) {
+		tx.Replace(kind, values)
+	})
+}
+
+// LabelTx is a transaction of a LabelStore. Its changes are only visible once the transaction is applied.
+type LabelTx struct {
+	values map[string]map[string][]string
+	pinned map[labelKey]bool
+	// copied are the kinds whose values were copied from the current version by the transaction.
+	copied map[string]bool
+	// pinnedCopied is set once the pinned objects were copied from the current version by the transaction.
+	pinnedCopied bool
+}
+
+// objects returns the values of kind, copying them from the current version on the first change.
+func (tx *LabelTx) objects(kind string) map[string][]string {
+	if !tx.copied[kind] {
+		objects := make(map[string][]string, len(tx.values[kind]))
+		maps.Copy(objects, tx.values[kind])
+		tx.values[kind] = objects
+		tx.copied[kind] = true
+	}
+	return tx.values[kind]
+}
+
+// pin pins or unpins an object of kind, copying the pinned objects from the current version on the first change.
+func (tx *LabelTx) pin(kind, name string, pinned bool) {
+	key := labelKey{kind: kind, name: name}
+	if tx.pinned[key] == pinned {
+		return
+	}
+	if !tx.pinnedCopied {
+		tx.pinned = maps.Clone(tx.pinned)
+		if tx.pinned == nil {
+			tx.pinned = make(map[labelKey]bool)
+		}
+		tx.pinnedCopied = true
+	}
+	if pinned {
+		tx.pinned[key] = true
+	} else {
+		delete(tx.pinned, key)
+	}
+}
+
+// Set sets the label values of the given objects of kind. The objects are no longer pinned.
+func (tx *LabelTx) Set(kind string, values map[string][]string) {
+	objects := tx.objects(kind)
+	for name, v := range values {
+		objects[name] = slices.Clone(v)
+		tx.pin(kind, name, false)
+	}
+}
+
+// SetPinned sets the label values of the given objects of kind and pins them, so that the label expiry never
+// evicts them. They stay pinned until they are set again or deleted.
+func (tx *LabelTx) SetPinned(kind string, values map[string][]string) {
+	objects := tx.objects(kind)
+	for name, v := range values {
+		objects[name] = slices.Clone(v)
+		tx.pin(kind, name, true)
+	}
+}
+
+// Delete deletes the label values of the given objects of kind.
+func (tx *LabelTx) Delete(kind string, names []string) {
+	objects := tx.objects(kind)
+	for _, name := range names {
+		delete(objects, name)
+		tx.pin(kind, name, false)
+	}
+}
+
+// Replace replaces the label values of all objects of kind. None of them is pinned.
+func (tx *LabelTx) Replace(kind string, values map[string][]string) {
+	for key := range tx.pinned {
+		if key.kind == kind {
+			tx.pin(kind, key.name, false)
+		}
+	}
+	objects := make(map[string][]string, len(values))
+	for name, v := range values {
+		objects[name] = slices.Clone(v)
+	}
+	tx.values[kind] = objects
+	tx.copied[kind] = true
+}
@@ -0,0 +1,180 @@
+package collector
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestLabelStore(t *testing.T) {
+	t.Parallel()
+
+	s := NewLabelStore()
+	if got := s.Version(); got != 0 {
+		t.Errorf("Version() = %v, want 0", got)
+	}
+
+	version := s.Update(func(tx *LabelTx) {
+		tx.Set(LabelKindUpstream, map[string][]string{"backend": {"payments"}, "legacy": {"core"}})
+		tx.Set(LabelKindServerZone, map[string][]string{"app": {"web"}})
+		// the changes are not visible before the transaction is applied
+		if got := s.Values(LabelKindUpstream); len(got) != 0 {
+			t.Errorf("Values() = %v during the transaction, want no values", got)
+		}
+	})
+	if version != 1 || s.Version() != 1 {
+		t.Errorf("Update() = %v and Version() = %v, want 1", version, s.Version())
+	}
+
+	s.Update(func(tx *LabelTx) {
+		tx.Delete(LabelKindUpstream, []string{"legacy"})
+		tx.Set(LabelKindUpstream, map[string][]string{"checkout": {"payments"}})
+	})
+	s.Replace(LabelKindServerZone, map[string][]string{"api": {"backend"}})
+
+	tests := []struct {
+		want map[string][]string
+		kind string
+	}{
+		{
+			kind: LabelKindUpstream,
+			want: map[string][]string{"backend": {"payments"}, "checkout": {"payments"}},
+		},
+		{
+			kind: LabelKindServerZone,
+			want: map[string][]string{"api": {"backend"}},
+		},
+		{
+			kind: LabelKindCacheZone,
+			want: map[string][]string{},
+		},
+	}
+	for _, tt := range tests {
+		if got := s.Values(tt.kind); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Values(%q) = %v, want %v", tt.kind, got, tt.want)
+		}
+	}
+	if got := s.Version(); got != 3 {
+		t.Errorf("Version() = %v, want 3", got)
+	}
+}
+
+func TestLabelStorePinned(t *testing.T) {
+	t.Parallel()
+
+	s := NewLabelStore()
+	s.Update(func(tx *LabelTx) {
+		tx.SetPinned(LabelKindUpstream, map[string][]string{"backend": {"payments"}, "legacy": {"core"}, "checkout": {"payments"}})
+		tx.SetPinned(LabelKindServerZone, map[string][]string{"app": {"web"}})
+	})
+	pinned := s.snapshot().pinned
+	s.Update(func(tx *LabelTx) {
+		tx.Set(LabelKindUpstream, map[string][]string{"legacy": {"platform"}})
+		tx.Delete(LabelKindUpstream, []string{"checkout"})
+		tx.Replace(LabelKindServerZone, map[string][]string{"app": {"web"}})
+	})
+
+	want := map[labelKey]bool{{kind: LabelKindUpstream, name: "backend"}: true}
+	if got := s.snapshot().pinned; !reflect.DeepEqual(got, want) {
+		t.Errorf("pinned = %v, want %v", got, want)
+	}
+	if len(pinned) != 4 {
+		t.Errorf("pinned objects of the previous version = %v, want 4", len(pinned))
+	}
+}
+
+func TestLabelStoreSnapshotIsolation(t *testing.T) {
+	t.Parallel()
+
+	s := NewLabelStore()
+	values := map[string][]string{"backend": {"payments"}}
+	s.Replace(LabelKindUpstream, values)
+	before := s.snapshot()
+
+	// neither later transactions nor the maps given to the store change a published version
+	values["backend"][0] = "changed"
+	s.Update(func(tx *LabelTx) {
+		tx.Set(LabelKindUpstream, map[string][]string{"backend": {"checkout"}})
+	})
+	if got := before.values[LabelKindUpstream]["backend"]; !reflect.DeepEqual(got, []string{"payments"}) {
+		t.Errorf("values of the previous version = %v, want [payments]", got)
+	}
+}
+
+// TestLabelStoreConcurrentScrapes checks that a scrape never sees a transaction half applied. It is meant to be
+// run with the race detector.
+func TestLabelStoreConcurrentScrapes(t *testing.T) {
+	t.Parallel()
+
+	const zones = 20
+	names := make([]string, 0, zones)
+	for i := range zones {
+		names = append(names, fmt.Sprintf(`"zone-%v": {}`, i))
+	}
+	body := "{" + strings.Join(names, ",") + "}"
+	c := newTestPlusCollectorWithLabels(t, map[string]string{"/9/http/server_zones": body},
+		map[string][]string{LabelKindServerZone: {"generation"}}, WithSections(SectionServerZones))
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for generation := 0; ; generation++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			values := make(map[string][]string, zones)
+			for i := range zones {
+				values[fmt.Sprintf("zone-%v", i)] = []string{fmt.Sprint(generation)}
+			}
+			c.LabelStore().Replace(LabelKindServerZone, values)
+		}
+	}()
+	go func() {
+		// the compatibility methods apply their changes concurrently with the transactions
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			c.UpdateCacheZoneLabels(map[string][]string{"images": {"media"}})
+			c.DeleteCacheZoneLabels([]string{"images"})
+		}
+	}()
+
+	for range 20 {
+		families, err := registry.Gather()
+		if err != nil {
+			t.Fatalf("Gather() returned error: %v", err)
+		}
+		generations := make(map[string]bool)
+		for _, family := range families {
+			if family.GetName() != "nginxplus_server_zone_requests" {
+				continue
+			}
+			for _, m := range family.GetMetric() {
+				for _, label := range m.GetLabel() {
+					if label.GetName() == "generation" {
+						generations[label.GetValue()] = true
+					}
+				}
+			}
+		}
+		if len(generations) != 1 {
+			t.Errorf("a scrape has the labels of generations %v, want a single generation", generations)
+		}
+	}
+	close(done)
+	wg.Wait()
+}
@@ -0,0 +1,25 @@
+package collector
+
+import (
+	"time"
+
+	plusclient "github.com/nginx/nginx-plus-go-client/v2/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func newLicenseMetric(namespace string, metricName string, docString string, constLabels prometheus.Labels) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "license", metricName), docString, nil, constLabels)
+}
+
+// collectLicense sends the license and the usage reporting state of NGINX Plus to ch. The remaining days are counted
+// from the clock of NGINX, which the license expiry is compared against.
+func (c *NginxPlusCollector) collectLicense(ch chan<- prometheus.Metric, license *plusclient.NginxLicense, clockSkew time.Duration) {
+	expiry := time.Unix(int64(license.ActiveTill), 0)
+	remaining := expiry.Sub(time.Now().Add(clockSkew))
+	ch <- prometheus.MustNewConstMetric(c.licenseMetrics["expiry_timestamp"], prometheus.GaugeValue, float64(expiry.Unix()))
+	ch <- prometheus.MustNewConstMetric(c.licenseMetrics["days_remaining"], prometheus.GaugeValue, remaining.Hours()/24)
+	ch <- prometheus.MustNewConstMetric(c.licenseMetrics["eval"], prometheus.GaugeValue, booleanToFloat64[license.Eval])
+	ch <- prometheus.MustNewConstMetric(c.licenseMetrics["reporting_healthy"], prometheus.GaugeValue, booleanToFloat64[license.Reporting.Healthy])
+	ch <- prometheus.MustNewConstMetric(c.licenseMetrics["reporting_fails"], prometheus.GaugeValue, float64(license.Reporting.Fails))
+	ch <- prometheus.MustNewConstMetric(c.licenseMetrics["reporting_grace_period"], prometheus.GaugeValue, float64(license.Reporting.Grace))
+}
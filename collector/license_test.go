@@ -0,0 +1,120 @@
+package collector
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNginxPlusCollectorLicense(t *testing.T) {
+	t.Parallel()
+
+	activeTill := time.Now().Add(10 * 24 * time.Hour).Unix()
+	tests := []struct {
+		name        string
+		build       string
+		want        string
+		wantLicense bool
+	}{
+		{
+			name:        "license endpoint",
+			build:       "nginx-plus-r33",
+			wantLicense: true,
+			want: fmt.Sprintf(`# HELP nginxplus_license_eval Whether the license is an evaluation license
+# TYPE nginxplus_license_eval gauge
+nginxplus_license_eval 0
+# HELP nginxplus_license_expiry_timestamp_seconds Time when the license expires, in seconds since the Unix epoch
+# TYPE nginxplus_license_expiry_timestamp_seconds gauge
+nginxplus_license_expiry_timestamp_seconds %v
+# HELP nginxplus_license_reporting_fails Number of unsuccessful usage reports
+# TYPE nginxplus_license_reporting_fails gauge
+nginxplus_license_reporting_fails 3
+# HELP nginxplus_license_reporting_grace_period_seconds Remaining grace period for a successful usage report before NGINX Plus stops processing traffic
+# TYPE nginxplus_license_reporting_grace_period_seconds gauge
+nginxplus_license_reporting_grace_period_seconds 86400
+# HELP nginxplus_license_reporting_healthy Whether the last usage report was successful
+# TYPE nginxplus_license_reporting_healthy gauge
+nginxplus_license_reporting_healthy 0
+`, activeTill),
+		},
+		{
+			// releases before R33 have no license endpoint
+			name:  "older release",
+			build: "nginx-plus-r32",
+		},
+		{
+			name: "build without a release",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var licenseRequested atomic.Bool
+			var infoRequests atomic.Int32
+			routes := plusRoutes(map[string]string{
+				"/9/nginx":   fmt.Sprintf(`{"version": "1.27.2", "build": %q}`, tt.build),
+				"/9/license": fmt.Sprintf(`{"active_till": %v, "eval": false, "reporting": {"healthy": false, "fails": 3, "grace": 86400}}`, activeTill),
+			})
+			nginx := newTestPlusServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/9/license":
+					licenseRequested.Store(true)
+				case "/9/nginx":
+					infoRequests.Add(1)
+				}
+				routes(w, r)
+			}))
+			c := newTestPlusCollectorOfClient(newTestPlusClient(t, nginx), nil, WithSections(SectionLicense),
+				WithAPIEndpoint(nginx.Client(), nginx.URL))
+
+			registry := prometheus.NewPedanticRegistry()
+			registry.MustRegister(c)
+			err := testutil.GatherAndCompare(registry, strings.NewReader(tt.want),
+				"nginxplus_license_eval", "nginxplus_license_expiry_timestamp_seconds", "nginxplus_license_reporting_fails",
+				"nginxplus_license_reporting_grace_period_seconds", "nginxplus_license_reporting_healthy")
+			if err != nil {
+				t.Error(err)
+			}
+			if got := testutil.ToFloat64(c.upMetric); got != nginxUp {
+				t.Errorf("nginxplus_up = %v, want %v", got, nginxUp)
+			}
+			if got := licenseRequested.Load(); got != tt.wantLicense {
+				t.Errorf("license requested = %v, want %v", got, tt.wantLicense)
+			}
+			// the license reuses the NGINX info of the scrape
+			if got := infoRequests.Load(); got != 1 {
+				t.Errorf("the NGINX info was requested %v times, want 1", got)
+			}
+
+			families, err := registry.Gather()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var days []float64
+			for _, family := range families {
+				if family.GetName() == "nginxplus_license_days_remaining" {
+					for _, m := range family.GetMetric() {
+						days = append(days, m.GetGauge().GetValue())
+					}
+				}
+			}
+			if !tt.wantLicense {
+				if len(days) != 0 {
+					t.Errorf("nginxplus_license_days_remaining = %v, want no metric", days)
+				}
+				return
+			}
+			if len(days) != 1 || math.Abs(days[0]-10) > 0.01 {
+				t.Errorf("nginxplus_license_days_remaining = %v, want about 10", days)
+			}
+		})
+	}
+}
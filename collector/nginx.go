@@ -1,8 +1,11 @@
 package collector
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"sync"
+	"time"
 
 	"github.com/nginx/nginx-prometheus-exporter/client"
 	"github.com/prometheus/client_golang/prometheus"
@@ -10,11 +13,13 @@ import (
 
 // NginxCollector collects NGINX metrics. It implements prometheus.Collector interface.
 type NginxCollector struct {
-	upMetric    prometheus.Gauge
-	logger      *slog.Logger
-	nginxClient *client.NginxClient
-	metrics     map[string]*prometheus.Desc
-	mutex       sync.Mutex
+	upMetric          prometheus.Gauge
+	logger            *slog.Logger
+	nginxClient       *client.NginxClient
+	poller            *poller[*client.StubStats]
+	snapshotAgeMetric *prometheus.Desc
+	metrics           map[string]*prometheus.Desc
+	mutex             sync.Mutex
 }
 
 // NewNginxCollector creates an NginxCollector.
@@ -31,31 +36,64 @@ func NewNginxCollector(nginxClient *client.NginxClient, namespace string, constL
 			"connections_waiting":  newGlobalMetric(namespace, "connections_waiting", "Idle client connections", constLabels),
 			"http_requests_total":  newGlobalMetric(namespace, "http_requests_total", "Total http requests", constLabels),
 		},
-		upMetric: newUpMetric(namespace, constLabels),
+		upMetric:          newUpMetric(namespace, constLabels),
+		snapshotAgeMetric: newSnapshotAgeMetric(namespace, constLabels),
 	}
 }
 
+// UpDesc returns the descriptor of the up metric, which reports whether the last scrape of NGINX succeeded.
+func (c *NginxCollector) UpDesc() *prometheus.Desc {
+	return c.upMetric.Desc()
+}
+
 // Describe sends the super-set of all possible descriptors of NGINX metrics
 // to the provided channel.
 func (c *NginxCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.upMetric.Desc()
+	ch <- c.snapshotAgeMetric
 
 	for _, m := range c.metrics {
 		ch <- m
 	}
 }
 
+// StartPolling makes the collector fetch the stats from NGINX every interval in the background
+// until ctx is done. Scrapes are then served from the latest stats, which are reported as down once
+// they are older than staleness, or three intervals if staleness is zero.
+// It must be called before the collector is registered.
+func (c *NginxCollector) StartPolling(ctx context.Context, interval, staleness time.Duration) {
+	c.poller = newPoller(c.nginxClient.GetStubStats, interval, staleness, c.logger)
+	go c.poller.run(ctx)
+}
+
 // Collect fetches metrics from NGINX and sends them to the provided channel.
 func (c *NginxCollector) Collect(ch chan<- prometheus.Metric) {
+	_ = c.Scrape(context.Background(), ch)
+}
+
+// Scrape fetches metrics from NGINX, sends them to the provided channel and
+// returns the error that prevented getting them, if any. The request to NGINX
+// is aborted when ctx is done.
+func (c *NginxCollector) Scrape(ctx context.Context, ch chan<- prometheus.Metric) error {
 	c.mutex.Lock() // To protect metrics from concurrent collects
 	defer c.mutex.Unlock()
 
-	stats, err := c.nginxClient.GetStubStats()
+	var stats *client.StubStats
+	var err error
+	if c.poller != nil {
+		stats, err = c.poller.collect(ch, c.snapshotAgeMetric)
+	} else {
+		stats, err = c.nginxClient.GetStubStats(ctx)
+	}
 	if err != nil {
 		c.upMetric.Set(nginxDown)
 		ch <- c.upMetric
-		c.logger.Error("error getting stats", "error", err.Error())
-		return
+		if ctx.Err() != nil {
+			c.logger.Debug("scrape cancelled", "error", err.Error())
+		} else {
+			c.logger.Error("error getting stats", "error", err.Error())
+		}
+		return fmt.Errorf("error getting stats: %w", err)
 	}
 
 	c.upMetric.Set(nginxUp)
@@ -75,4 +113,6 @@ func (c *NginxCollector) Collect(ch chan<- prometheus.Metric) {
 		prometheus.GaugeValue, float64(stats.Connections.Waiting))
 	ch <- prometheus.MustNewConstMetric(c.metrics["http_requests_total"],
 		prometheus.CounterValue, float64(stats.Requests))
+
+	return nil
 }
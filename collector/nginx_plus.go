@@ -5,13 +5,17 @@ import (
 	"fmt"
 	"log/slog"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	plusclient "github.com/nginx/nginx-plus-go-client/v2/client"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-// LabelUpdater updates the labels of upstream server and server zone metrics.
+// LabelUpdater updates the variable labels of the metrics of every kind of NGINX Plus objects. Every call is
+// applied as a separate transaction of the LabelStore of the collector, which also allows changing the labels
+// of several objects and kinds at once.
 type LabelUpdater interface {
 	UpdateUpstreamServerPeerLabels(upstreamServerPeerLabels map[string][]string)
 	DeleteUpstreamServerPeerLabels(peers []string)
@@ -27,206 +31,250 @@ type LabelUpdater interface {
 	DeleteStreamServerZoneLabels(zoneNames []string)
 	UpdateCacheZoneLabels(cacheLabelValues map[string][]string)
 	DeleteCacheZoneLabels(cacheNames []string)
+	UpdateLocationZoneLabels(locationZoneLabelValues map[string][]string)
+	DeleteLocationZoneLabels(zoneNames []string)
+	UpdateResolverLabels(resolverLabelValues map[string][]string)
+	DeleteResolverLabels(resolverNames []string)
+	UpdateLimitRequestLabels(limitRequestLabelValues map[string][]string)
+	DeleteLimitRequestLabels(zoneNames []string)
+	UpdateLimitConnectionLabels(limitConnectionLabelValues map[string][]string)
+	DeleteLimitConnectionLabels(zoneNames []string)
+	UpdateStreamLimitConnectionLabels(streamLimitConnectionLabelValues map[string][]string)
+	DeleteStreamLimitConnectionLabels(zoneNames []string)
+	UpdateStreamZoneSyncZoneLabels(streamZoneSyncZoneLabelValues map[string][]string)
+	DeleteStreamZoneSyncZoneLabels(zoneNames []string)
 }
 
 // NginxPlusCollector collects NGINX Plus metrics. It implements prometheus.Collector interface.
 type NginxPlusCollector struct {
-	upMetric                       prometheus.Gauge
-	logger                         *slog.Logger
-	cacheZoneMetrics               map[string]*prometheus.Desc
-	workerMetrics                  map[string]*prometheus.Desc
-	nginxClient                    *plusclient.NginxClient
-	streamServerZoneMetrics        map[string]*prometheus.Desc
-	streamZoneSyncMetrics          map[string]*prometheus.Desc
-	streamUpstreamMetrics          map[string]*prometheus.Desc
-	streamUpstreamServerMetrics    map[string]*prometheus.Desc
-	locationZoneMetrics            map[string]*prometheus.Desc
-	resolverMetrics                map[string]*prometheus.Desc
-	limitRequestMetrics            map[string]*prometheus.Desc
-	limitConnectionMetrics         map[string]*prometheus.Desc
-	streamLimitConnectionMetrics   map[string]*prometheus.Desc
-	upstreamServerMetrics          map[string]*prometheus.Desc
-	upstreamMetrics                map[string]*prometheus.Desc
-	streamUpstreamServerPeerLabels map[string][]string
-	serverZoneMetrics              map[string]*prometheus.Desc
-	upstreamServerLabels           map[string][]string
-	streamUpstreamServerLabels     map[string][]string
-	serverZoneLabels               map[string][]string
-	streamServerZoneLabels         map[string][]string
-	upstreamServerPeerLabels       map[string][]string
-	cacheZoneLabels                map[string][]string
-	totalMetrics                   map[string]*prometheus.Desc
-	variableLabelNames             VariableLabelNames
-	variableLabelsMutex            sync.RWMutex
-	mutex                          sync.Mutex
+	upMetric                     prometheus.Gauge
+	poller                       *poller[*plusStats]
+	api                          *apiEndpoint
+	responseCodes                map[string]ResponseCodes
+	snapshotAgeMetric            *prometheus.Desc
+	logger                       *slog.Logger
+	cacheZoneMetrics             map[string]*prometheus.Desc
+	workerMetrics                map[string]*prometheus.Desc
+	workerRestarts               workerRestarts
+	slabMetrics                  map[string]*prometheus.Desc
+	keyValMetrics                map[string]*prometheus.Desc
+	licenseMetrics               map[string]*prometheus.Desc
+	keyValExport                 *keyValExport
+	streamKeyVals                endpointCache
+	nginxClient                  *plusclient.NginxClient
+	sections                     map[Section]bool
+	filters                      map[string]NameFilter
+	filteredObjectsMetric        *prometheus.Desc
+	labelStoreEntriesMetric      *prometheus.Desc
+	streamServerZoneMetrics      map[string]*prometheus.Desc
+	streamZoneSyncMetrics        map[string]*prometheus.Desc
+	streamUpstreamMetrics        map[string]*prometheus.Desc
+	streamUpstreamServerMetrics  map[string]*prometheus.Desc
+	locationZoneMetrics          map[string]*prometheus.Desc
+	resolverMetrics              map[string]*prometheus.Desc
+	limitRequestMetrics          map[string]*prometheus.Desc
+	limitConnectionMetrics       map[string]*prometheus.Desc
+	streamLimitConnectionMetrics map[string]*prometheus.Desc
+	upstreamServerMetrics        map[string]*prometheus.Desc
+	upstreamMetrics              map[string]*prometheus.Desc
+	serverZoneMetrics            map[string]*prometheus.Desc
+	labelRules                   map[string][]LabelRule
+	labelExpiry                  *labelExpiry
+	labelFile                    *labelFileWriter
+	keyValLabels                 map[string]map[string][]string
+	totalMetrics                 map[string]*prometheus.Desc
+	nginxMetrics                 map[string]*prometheus.Desc
+	variableLabelNames           VariableLabelNames
+	labels                       *LabelStore
+	labelZone                    string
+	mutex                        sync.Mutex
+}
+
+// LabelStore returns the store of the variable label values of the collector.
+func (c *NginxPlusCollector) LabelStore() *LabelStore {
+	return c.labels
 }
 
 // UpdateUpstreamServerPeerLabels updates the Upstream Server Peer Labels.
 func (c *NginxPlusCollector) UpdateUpstreamServerPeerLabels(upstreamServerPeerLabels map[string][]string) {
-	c.variableLabelsMutex.Lock()
-	for k, v := range upstreamServerPeerLabels {
-		c.upstreamServerPeerLabels[k] = v
-	}
-	c.variableLabelsMutex.Unlock()
+	c.labels.Update(func(tx *LabelTx) {
+		tx.Set(LabelKindUpstreamPeer, c.validLabelValues(LabelKindUpstreamPeer, upstreamServerPeerLabels))
+	})
 }
 
 // DeleteUpstreamServerPeerLabels deletes the Upstream Server Peer Labels.
 func (c *NginxPlusCollector) DeleteUpstreamServerPeerLabels(peers []string) {
-	c.variableLabelsMutex.Lock()
-	for _, k := range peers {
-		delete(c.upstreamServerPeerLabels, k)
-	}
-	c.variableLabelsMutex.Unlock()
+	c.labels.Update(func(tx *LabelTx) {
+		tx.Delete(LabelKindUpstreamPeer, peers)
+	})
 }
 
 // UpdateStreamUpstreamServerPeerLabels updates the Upstream Server Peer Labels.
 func (c *NginxPlusCollector) UpdateStreamUpstreamServerPeerLabels(streamUpstreamServerPeerLabels map[string][]string) {
-	c.variableLabelsMutex.Lock()
-	for k, v := range streamUpstreamServerPeerLabels {
-		c.streamUpstreamServerPeerLabels[k] = v
-	}
-	c.variableLabelsMutex.Unlock()
+	c.labels.Update(func(tx *LabelTx) {
+		tx.Set(LabelKindStreamUpstreamPeer, c.validLabelValues(LabelKindStreamUpstreamPeer, streamUpstreamServerPeerLabels))
+	})
 }
 
 // DeleteStreamUpstreamServerPeerLabels deletes the Upstream Server Peer Labels.
 func (c *NginxPlusCollector) DeleteStreamUpstreamServerPeerLabels(peers []string) {
-	c.variableLabelsMutex.Lock()
-	for _, k := range peers {
-		delete(c.streamUpstreamServerPeerLabels, k)
-	}
-	c.variableLabelsMutex.Unlock()
+	c.labels.Update(func(tx *LabelTx) {
+		tx.Delete(LabelKindStreamUpstreamPeer, peers)
+	})
 }
 
 // UpdateUpstreamServerLabels updates the Upstream Server Labels.
 func (c *NginxPlusCollector) UpdateUpstreamServerLabels(upstreamServerLabelValues map[string][]string) {
-	c.variableLabelsMutex.Lock()
-	for k, v := range upstreamServerLabelValues {
-		c.upstreamServerLabels[k] = v
-	}
-	c.variableLabelsMutex.Unlock()
+	c.labels.Update(func(tx *LabelTx) {
+		tx.Set(LabelKindUpstream, c.validLabelValues(LabelKindUpstream, upstreamServerLabelValues))
+	})
 }
 
 // DeleteUpstreamServerLabels deletes the Upstream Server Labels.
 func (c *NginxPlusCollector) DeleteUpstreamServerLabels(upstreamNames []string) {
-	c.variableLabelsMutex.Lock()
-	for _, k := range upstreamNames {
-		delete(c.upstreamServerLabels, k)
-	}
-	c.variableLabelsMutex.Unlock()
+	c.labels.Update(func(tx *LabelTx) {
+		tx.Delete(LabelKindUpstream, upstreamNames)
+	})
 }
 
 // UpdateStreamUpstreamServerLabels updates the Upstream Server Labels.
 func (c *NginxPlusCollector) UpdateStreamUpstreamServerLabels(streamUpstreamServerLabelValues map[string][]string) {
-	c.variableLabelsMutex.Lock()
-	for k, v := range streamUpstreamServerLabelValues {
-		c.streamUpstreamServerLabels[k] = v
-	}
-	c.variableLabelsMutex.Unlock()
+	c.labels.Update(func(tx *LabelTx) {
+		tx.Set(LabelKindStreamUpstream, c.validLabelValues(LabelKindStreamUpstream, streamUpstreamServerLabelValues))
+	})
 }
 
 // DeleteStreamUpstreamServerLabels deletes the Upstream Server Labels.
 func (c *NginxPlusCollector) DeleteStreamUpstreamServerLabels(streamUpstreamNames []string) {
-	c.variableLabelsMutex.Lock()
-	for _, k := range streamUpstreamNames {
-		delete(c.streamUpstreamServerLabels, k)
-	}
-	c.variableLabelsMutex.Unlock()
+	c.labels.Update(func(tx *LabelTx) {
+		tx.Delete(LabelKindStreamUpstream, streamUpstreamNames)
+	})
 }
 
 // UpdateServerZoneLabels updates the Server Zone Labels.
 func (c *NginxPlusCollector) UpdateServerZoneLabels(serverZoneLabelValues map[string][]string) {
-	c.variableLabelsMutex.Lock()
-	for k, v := range serverZoneLabelValues {
-		c.serverZoneLabels[k] = v
-	}
-	c.variableLabelsMutex.Unlock()
+	c.labels.Update(func(tx *LabelTx) {
+		tx.Set(LabelKindServerZone, c.validLabelValues(LabelKindServerZone, serverZoneLabelValues))
+	})
 }
 
 // DeleteServerZoneLabels deletes the Server Zone Labels.
 func (c *NginxPlusCollector) DeleteServerZoneLabels(zoneNames []string) {
-	c.variableLabelsMutex.Lock()
-	for _, k := range zoneNames {
-		delete(c.serverZoneLabels, k)
-	}
-	c.variableLabelsMutex.Unlock()
+	c.labels.Update(func(tx *LabelTx) {
+		tx.Delete(LabelKindServerZone, zoneNames)
+	})
 }
 
 // UpdateStreamServerZoneLabels updates the Stream Server Zone Labels.
 func (c *NginxPlusCollector) UpdateStreamServerZoneLabels(streamServerZoneLabelValues map[string][]string) {
-	c.variableLabelsMutex.Lock()
-	for k, v := range streamServerZoneLabelValues {
-		c.streamServerZoneLabels[k] = v
-	}
-	c.variableLabelsMutex.Unlock()
+	c.labels.Update(func(tx *LabelTx) {
+		tx.Set(LabelKindStreamServerZone, c.validLabelValues(LabelKindStreamServerZone, streamServerZoneLabelValues))
+	})
 }
 
 // DeleteStreamServerZoneLabels deletes the Stream Server Zone Labels.
 func (c *NginxPlusCollector) DeleteStreamServerZoneLabels(zoneNames []string) {
-	c.variableLabelsMutex.Lock()
-	for _, k := range zoneNames {
-		delete(c.streamServerZoneLabels, k)
-	}
-	c.variableLabelsMutex.Unlock()
+	c.labels.Update(func(tx *LabelTx) {
+		tx.Delete(LabelKindStreamServerZone, zoneNames)
+	})
 }
 
 // UpdateCacheZoneLabels updates the Upstream Cache Zone labels.
 func (c *NginxPlusCollector) UpdateCacheZoneLabels(cacheZoneLabelValues map[string][]string) {
-	c.variableLabelsMutex.Lock()
-	for k, v := range cacheZoneLabelValues {
-		c.cacheZoneLabels[k] = v
-	}
-	c.variableLabelsMutex.Unlock()
+	c.labels.Update(func(tx *LabelTx) {
+		tx.Set(LabelKindCacheZone, c.validLabelValues(LabelKindCacheZone, cacheZoneLabelValues))
+	})
 }
 
 // DeleteCacheZoneLabels deletes the Cache Zone Labels.
 func (c *NginxPlusCollector) DeleteCacheZoneLabels(cacheZoneNames []string) {
-	c.variableLabelsMutex.Lock()
-	for _, k := range cacheZoneNames {
-		delete(c.cacheZoneLabels, k)
-	}
-	c.variableLabelsMutex.Unlock()
+	c.labels.Update(func(tx *LabelTx) {
+		tx.Delete(LabelKindCacheZone, cacheZoneNames)
+	})
+}
+
+// UpdateLocationZoneLabels updates the Location Zone Labels.
+func (c *NginxPlusCollector) UpdateLocationZoneLabels(locationZoneLabelValues map[string][]string) {
+	c.labels.Update(func(tx *LabelTx) {
+		tx.Set(LabelKindLocationZone, c.validLabelValues(LabelKindLocationZone, locationZoneLabelValues))
+	})
+}
+
+// DeleteLocationZoneLabels deletes the Location Zone Labels.
+func (c *NginxPlusCollector) DeleteLocationZoneLabels(zoneNames []string) {
+	c.labels.Update(func(tx *LabelTx) {
+		tx.Delete(LabelKindLocationZone, zoneNames)
+	})
 }
 
-func (c *NginxPlusCollector) getUpstreamServerLabelValues(upstreamName string) []string {
-	c.variableLabelsMutex.RLock()
-	defer c.variableLabelsMutex.RUnlock()
-	return c.upstreamServerLabels[upstreamName]
+// UpdateResolverLabels updates the Resolver Labels.
+func (c *NginxPlusCollector) UpdateResolverLabels(resolverLabelValues map[string][]string) {
+	c.labels.Update(func(tx *LabelTx) {
+		tx.Set(LabelKindResolver, c.validLabelValues(LabelKindResolver, resolverLabelValues))
+	})
 }
 
-func (c *NginxPlusCollector) getStreamUpstreamServerLabelValues(upstreamName string) []string {
-	c.variableLabelsMutex.RLock()
-	defer c.variableLabelsMutex.RUnlock()
-	return c.streamUpstreamServerLabels[upstreamName]
+// DeleteResolverLabels deletes the Resolver Labels.
+func (c *NginxPlusCollector) DeleteResolverLabels(resolverNames []string) {
+	c.labels.Update(func(tx *LabelTx) {
+		tx.Delete(LabelKindResolver, resolverNames)
+	})
 }
 
-func (c *NginxPlusCollector) getServerZoneLabelValues(zoneName string) []string {
-	c.variableLabelsMutex.RLock()
-	defer c.variableLabelsMutex.RUnlock()
-	return c.serverZoneLabels[zoneName]
+// UpdateLimitRequestLabels updates the Limit Request Labels.
+func (c *NginxPlusCollector) UpdateLimitRequestLabels(limitRequestLabelValues map[string][]string) {
+	c.labels.Update(func(tx *LabelTx) {
+		tx.Set(LabelKindLimitRequest, c.validLabelValues(LabelKindLimitRequest, limitRequestLabelValues))
+	})
 }
 
-func (c *NginxPlusCollector) getStreamServerZoneLabelValues(zoneName string) []string {
-	c.variableLabelsMutex.RLock()
-	defer c.variableLabelsMutex.RUnlock()
-	return c.streamServerZoneLabels[zoneName]
+// DeleteLimitRequestLabels deletes the Limit Request Labels.
+func (c *NginxPlusCollector) DeleteLimitRequestLabels(zoneNames []string) {
+	c.labels.Update(func(tx *LabelTx) {
+		tx.Delete(LabelKindLimitRequest, zoneNames)
+	})
 }
 
-func (c *NginxPlusCollector) getUpstreamServerPeerLabelValues(peer string) []string {
-	c.variableLabelsMutex.RLock()
-	defer c.variableLabelsMutex.RUnlock()
-	return c.upstreamServerPeerLabels[peer]
+// UpdateLimitConnectionLabels updates the Limit Connection Labels.
+func (c *NginxPlusCollector) UpdateLimitConnectionLabels(limitConnectionLabelValues map[string][]string) {
+	c.labels.Update(func(tx *LabelTx) {
+		tx.Set(LabelKindLimitConnection, c.validLabelValues(LabelKindLimitConnection, limitConnectionLabelValues))
+	})
 }
 
-func (c *NginxPlusCollector) getStreamUpstreamServerPeerLabelValues(peer string) []string {
-	c.variableLabelsMutex.RLock()
-	defer c.variableLabelsMutex.RUnlock()
-	return c.streamUpstreamServerPeerLabels[peer]
+// DeleteLimitConnectionLabels deletes the Limit Connection Labels.
+func (c *NginxPlusCollector) DeleteLimitConnectionLabels(zoneNames []string) {
+	c.labels.Update(func(tx *LabelTx) {
+		tx.Delete(LabelKindLimitConnection, zoneNames)
+	})
 }
 
-func (c *NginxPlusCollector) getCacheZoneLabelValues(cacheName string) []string {
-	c.variableLabelsMutex.RLock()
-	defer c.variableLabelsMutex.RUnlock()
-	return c.cacheZoneLabels[cacheName]
+// UpdateStreamLimitConnectionLabels updates the Stream Limit Connection Labels.
+func (c *NginxPlusCollector) UpdateStreamLimitConnectionLabels(streamLimitConnectionLabelValues map[string][]string) {
+	c.labels.Update(func(tx *LabelTx) {
+		tx.Set(LabelKindStreamLimitConnection, c.validLabelValues(LabelKindStreamLimitConnection, streamLimitConnectionLabelValues))
+	})
+}
+
+// DeleteStreamLimitConnectionLabels deletes the Stream Limit Connection Labels.
+func (c *NginxPlusCollector) DeleteStreamLimitConnectionLabels(zoneNames []string) {
+	c.labels.Update(func(tx *LabelTx) {
+		tx.Delete(LabelKindStreamLimitConnection, zoneNames)
+	})
+}
+
+// UpdateStreamZoneSyncZoneLabels updates the Stream Zone Sync Zone Labels.
+func (c *NginxPlusCollector) UpdateStreamZoneSyncZoneLabels(streamZoneSyncZoneLabelValues map[string][]string) {
+	c.labels.Update(func(tx *LabelTx) {
+		tx.Set(LabelKindStreamZoneSyncZone, c.validLabelValues(LabelKindStreamZoneSyncZone, streamZoneSyncZoneLabelValues))
+	})
+}
+
+// DeleteStreamZoneSyncZoneLabels deletes the Stream Zone Sync Zone Labels.
+func (c *NginxPlusCollector) DeleteStreamZoneSyncZoneLabels(zoneNames []string) {
+	c.labels.Update(func(tx *LabelTx) {
+		tx.Delete(LabelKindStreamZoneSyncZone, zoneNames)
+	})
 }
 
 // VariableLabelNames holds all the variable label names for the different metrics.
@@ -238,6 +286,12 @@ type VariableLabelNames struct {
 	StreamServerZoneVariableLabelNames         []string
 	StreamUpstreamServerVariableLabelNames     []string
 	CacheZoneVariableLabelNames                []string
+	LocationZoneVariableLabelNames             []string
+	ResolverVariableLabelNames                 []string
+	LimitRequestVariableLabelNames             []string
+	LimitConnectionVariableLabelNames          []string
+	StreamLimitConnectionVariableLabelNames    []string
+	StreamZoneSyncZoneVariableLabelNames       []string
 }
 
 // NewVariableLabelNames NewVariableLabels creates a new struct for VariableNames for the collector.
@@ -255,191 +309,183 @@ func NewVariableLabelNames(upstreamServerVariableLabelNames []string, serverZone
 	}
 }
 
-// NewNginxPlusCollector creates an NginxPlusCollector.
-func NewNginxPlusCollector(nginxClient *plusclient.NginxClient, namespace string, variableLabelNames VariableLabelNames, constLabels map[string]string, logger *slog.Logger) *NginxPlusCollector {
+// NewVariableLabelNamesByKind creates the variable label names of the NGINX Plus objects from the label names of every
+// kind, such as LabelKindLocationZone. The label names of unknown kinds are ignored.
+func NewVariableLabelNamesByKind(labelNames map[string][]string) VariableLabelNames {
+	var names VariableLabelNames
+	for kind, n := range labelNames {
+		if field := names.kind(kind); field != nil {
+			*field = n
+		}
+	}
+	return names
+}
+
+// kind returns the field of the label names of kind, nil if the kind is unknown.
+func (v *VariableLabelNames) kind(kind string) *[]string {
+	switch kind {
+	case LabelKindUpstream:
+		return &v.UpstreamServerVariableLabelNames
+	case LabelKindServerZone:
+		return &v.ServerZoneVariableLabelNames
+	case LabelKindUpstreamPeer:
+		return &v.UpstreamServerPeerVariableLabelNames
+	case LabelKindStreamUpstream:
+		return &v.StreamUpstreamServerVariableLabelNames
+	case LabelKindStreamServerZone:
+		return &v.StreamServerZoneVariableLabelNames
+	case LabelKindStreamUpstreamPeer:
+		return &v.StreamUpstreamServerPeerVariableLabelNames
+	case LabelKindCacheZone:
+		return &v.CacheZoneVariableLabelNames
+	case LabelKindLocationZone:
+		return &v.LocationZoneVariableLabelNames
+	case LabelKindResolver:
+		return &v.ResolverVariableLabelNames
+	case LabelKindLimitRequest:
+		return &v.LimitRequestVariableLabelNames
+	case LabelKindLimitConnection:
+		return &v.LimitConnectionVariableLabelNames
+	case LabelKindStreamLimitConnection:
+		return &v.StreamLimitConnectionVariableLabelNames
+	case LabelKindStreamZoneSyncZone:
+		return &v.StreamZoneSyncZoneVariableLabelNames
+	}
+	return nil
+}
+
+// NewNginxPlusCollector creates an NginxPlusCollector. The default sections of the NGINX Plus API are collected
+// unless others are selected with WithSections.
+func NewNginxPlusCollector(nginxClient *plusclient.NginxClient, namespace string, variableLabelNames VariableLabelNames, constLabels map[string]string, logger *slog.Logger, opts ...NginxPlusCollectorOption) *NginxPlusCollector {
 	upstreamServerVariableLabelNames := variableLabelNames.UpstreamServerVariableLabelNames
 	streamUpstreamServerVariableLabelNames := variableLabelNames.StreamUpstreamServerVariableLabelNames
 
 	upstreamServerVariableLabelNames = append(upstreamServerVariableLabelNames, variableLabelNames.UpstreamServerPeerVariableLabelNames...)
 	streamUpstreamServerVariableLabelNames = append(streamUpstreamServerVariableLabelNames, variableLabelNames.StreamUpstreamServerPeerVariableLabelNames...)
-	return &NginxPlusCollector{
-		variableLabelNames:             variableLabelNames,
-		upstreamServerLabels:           make(map[string][]string),
-		serverZoneLabels:               make(map[string][]string),
-		streamServerZoneLabels:         make(map[string][]string),
-		upstreamServerPeerLabels:       make(map[string][]string),
-		streamUpstreamServerPeerLabels: make(map[string][]string),
-		streamUpstreamServerLabels:     make(map[string][]string),
-		cacheZoneLabels:                make(map[string][]string),
-		nginxClient:                    nginxClient,
-		logger:                         logger,
+	c := &NginxPlusCollector{
+		variableLabelNames: variableLabelNames,
+		labels:             NewLabelStore(),
+		nginxClient:        nginxClient,
+		logger:             logger,
 		totalMetrics: map[string]*prometheus.Desc{
-			"connections_accepted":  newGlobalMetric(namespace, "connections_accepted", "Accepted client connections", constLabels),
-			"connections_dropped":   newGlobalMetric(namespace, "connections_dropped", "Dropped client connections", constLabels),
-			"connections_active":    newGlobalMetric(namespace, "connections_active", "Active client connections", constLabels),
-			"connections_idle":      newGlobalMetric(namespace, "connections_idle", "Idle client connections", constLabels),
-			"http_requests_total":   newGlobalMetric(namespace, "http_requests_total", "Total http requests", constLabels),
-			"http_requests_current": newGlobalMetric(namespace, "http_requests_current", "Current http requests", constLabels),
-			"ssl_handshakes":        newGlobalMetric(namespace, "ssl_handshakes", "Successful SSL handshakes", constLabels),
-			"ssl_handshakes_failed": newGlobalMetric(namespace, "ssl_handshakes_failed", "Failed SSL handshakes", constLabels),
-			"ssl_session_reuses":    newGlobalMetric(namespace, "ssl_session_reuses", "Session reuses during SSL handshake", constLabels),
+			"connections_accepted":   newGlobalMetric(namespace, "connections_accepted", "Accepted client connections", constLabels),
+			"connections_dropped":    newGlobalMetric(namespace, "connections_dropped", "Dropped client connections", constLabels),
+			"connections_active":     newGlobalMetric(namespace, "connections_active", "Active client connections", constLabels),
+			"connections_idle":       newGlobalMetric(namespace, "connections_idle", "Idle client connections", constLabels),
+			"http_requests_total":    newGlobalMetric(namespace, "http_requests_total", "Total http requests", constLabels),
+			"http_requests_current":  newGlobalMetric(namespace, "http_requests_current", "Current http requests", constLabels),
+			"ssl_handshakes":         newGlobalMetric(namespace, "ssl_handshakes", "Successful SSL handshakes", constLabels),
+			"ssl_handshakes_failed":  newGlobalMetric(namespace, "ssl_handshakes_failed", "Failed SSL handshakes", constLabels),
+			"ssl_session_reuses":     newGlobalMetric(namespace, "ssl_session_reuses", "Session reuses during SSL handshake", constLabels),
+			"ssl_handshake_failures": newSSLHandshakeFailuresMetric(namespace, "", nil, constLabels),
+			"ssl_verify_failures":    newSSLVerifyFailuresMetric(namespace, "", nil, constLabels),
+		},
+		nginxMetrics: map[string]*prometheus.Desc{
+			"info":                    prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "info"), "Version and build of NGINX Plus", []string{"version", "build"}, constLabels),
+			"config_generation":       newGlobalMetric(namespace, "config_generation", "Total number of configuration reloads", constLabels),
+			"config_reload_timestamp": newGlobalMetric(namespace, "config_reload_timestamp_seconds", "Time of the last configuration reload, in seconds since the Unix epoch", constLabels),
+			"master_pid":              newGlobalMetric(namespace, "master_pid", "ID of the master process", constLabels),
+			"clock_skew":              newGlobalMetric(namespace, "clock_skew_seconds", "Difference between the clock of NGINX and the clock of the exporter", constLabels),
 		},
 		serverZoneMetrics: map[string]*prometheus.Desc{
-			"processing":            newServerZoneMetric(namespace, "processing", "Client requests that are currently being processed", variableLabelNames.ServerZoneVariableLabelNames, constLabels),
-			"requests":              newServerZoneMetric(namespace, "requests", "Total client requests", variableLabelNames.ServerZoneVariableLabelNames, constLabels),
-			"responses_1xx":         newServerZoneMetric(namespace, "responses", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "1xx"})),
-			"responses_2xx":         newServerZoneMetric(namespace, "responses", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "2xx"})),
-			"responses_3xx":         newServerZoneMetric(namespace, "responses", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "3xx"})),
-			"responses_4xx":         newServerZoneMetric(namespace, "responses", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "4xx"})),
-			"responses_5xx":         newServerZoneMetric(namespace, "responses", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "5xx"})),
-			"discarded":             newServerZoneMetric(namespace, "discarded", "Requests completed without sending a response", variableLabelNames.ServerZoneVariableLabelNames, constLabels),
-			"received":              newServerZoneMetric(namespace, "received", "Bytes received from clients", variableLabelNames.ServerZoneVariableLabelNames, constLabels),
-			"sent":                  newServerZoneMetric(namespace, "sent", "Bytes sent to clients", variableLabelNames.ServerZoneVariableLabelNames, constLabels),
-			"codes_100":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "100"})),
-			"codes_101":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "101"})),
-			"codes_102":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "102"})),
-			"codes_200":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "200"})),
-			"codes_201":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "201"})),
-			"codes_202":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "202"})),
-			"codes_204":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "204"})),
-			"codes_206":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "206"})),
-			"codes_300":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "300"})),
-			"codes_301":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "301"})),
-			"codes_302":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "302"})),
-			"codes_303":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "303"})),
-			"codes_304":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "304"})),
-			"codes_307":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "307"})),
-			"codes_400":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "400"})),
-			"codes_401":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "401"})),
-			"codes_403":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "403"})),
-			"codes_404":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "404"})),
-			"codes_405":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "405"})),
-			"codes_408":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "408"})),
-			"codes_409":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "409"})),
-			"codes_411":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "411"})),
-			"codes_412":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "412"})),
-			"codes_413":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "413"})),
-			"codes_414":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "414"})),
-			"codes_415":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "415"})),
-			"codes_416":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "416"})),
-			"codes_429":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "429"})),
-			"codes_444":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "444"})),
-			"codes_494":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "494"})),
-			"codes_495":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "495"})),
-			"codes_496":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "496"})),
-			"codes_497":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "497"})),
-			"codes_499":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "499"})),
-			"codes_500":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "500"})),
-			"codes_501":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "501"})),
-			"codes_502":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "502"})),
-			"codes_503":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "503"})),
-			"codes_504":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "504"})),
-			"codes_507":             newServerZoneMetric(namespace, "responses_codes", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "507"})),
-			"ssl_handshakes":        newServerZoneMetric(namespace, "ssl_handshakes", "Successful SSL handshakes", variableLabelNames.ServerZoneVariableLabelNames, constLabels),
-			"ssl_handshakes_failed": newServerZoneMetric(namespace, "ssl_handshakes_failed", "Failed SSL handshakes", variableLabelNames.ServerZoneVariableLabelNames, constLabels),
-			"ssl_session_reuses":    newServerZoneMetric(namespace, "ssl_session_reuses", "Session reuses during SSL handshake", variableLabelNames.ServerZoneVariableLabelNames, constLabels),
+			"processing":             newServerZoneMetric(namespace, "processing", "Client requests that are currently being processed", variableLabelNames.ServerZoneVariableLabelNames, constLabels),
+			"requests":               newServerZoneMetric(namespace, "requests", "Total client requests", variableLabelNames.ServerZoneVariableLabelNames, constLabels),
+			"responses_1xx":          newServerZoneMetric(namespace, "responses", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "1xx"})),
+			"responses_2xx":          newServerZoneMetric(namespace, "responses", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "2xx"})),
+			"responses_3xx":          newServerZoneMetric(namespace, "responses", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "3xx"})),
+			"responses_4xx":          newServerZoneMetric(namespace, "responses", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "4xx"})),
+			"responses_5xx":          newServerZoneMetric(namespace, "responses", "Total responses sent to clients", variableLabelNames.ServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "5xx"})),
+			"discarded":              newServerZoneMetric(namespace, "discarded", "Requests completed without sending a response", variableLabelNames.ServerZoneVariableLabelNames, constLabels),
+			"received":               newServerZoneMetric(namespace, "received", "Bytes received from clients", variableLabelNames.ServerZoneVariableLabelNames, constLabels),
+			"sent":                   newServerZoneMetric(namespace, "sent", "Bytes sent to clients", variableLabelNames.ServerZoneVariableLabelNames, constLabels),
+			"responses_codes":        newResponseCodesMetric(namespace, "server_zone", append([]string{"server_zone"}, variableLabelNames.ServerZoneVariableLabelNames...), constLabels),
+			"ssl_handshakes":         newServerZoneMetric(namespace, "ssl_handshakes", "Successful SSL handshakes", variableLabelNames.ServerZoneVariableLabelNames, constLabels),
+			"ssl_handshakes_failed":  newServerZoneMetric(namespace, "ssl_handshakes_failed", "Failed SSL handshakes", variableLabelNames.ServerZoneVariableLabelNames, constLabels),
+			"ssl_session_reuses":     newServerZoneMetric(namespace, "ssl_session_reuses", "Session reuses during SSL handshake", variableLabelNames.ServerZoneVariableLabelNames, constLabels),
+			"ssl_handshake_failures": newSSLHandshakeFailuresMetric(namespace, "server_zone", append([]string{"server_zone"}, variableLabelNames.ServerZoneVariableLabelNames...), constLabels),
+			"ssl_verify_failures":    newSSLVerifyFailuresMetric(namespace, "server_zone", append([]string{"server_zone"}, variableLabelNames.ServerZoneVariableLabelNames...), constLabels),
 		},
 		streamServerZoneMetrics: map[string]*prometheus.Desc{
-			"processing":            newStreamServerZoneMetric(namespace, "processing", "Client connections that are currently being processed", variableLabelNames.StreamServerZoneVariableLabelNames, constLabels),
-			"connections":           newStreamServerZoneMetric(namespace, "connections", "Total connections", variableLabelNames.StreamServerZoneVariableLabelNames, constLabels),
-			"sessions_2xx":          newStreamServerZoneMetric(namespace, "sessions", "Total sessions completed", variableLabelNames.StreamServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "2xx"})),
-			"sessions_4xx":          newStreamServerZoneMetric(namespace, "sessions", "Total sessions completed", variableLabelNames.StreamServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "4xx"})),
-			"sessions_5xx":          newStreamServerZoneMetric(namespace, "sessions", "Total sessions completed", variableLabelNames.StreamServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "5xx"})),
-			"discarded":             newStreamServerZoneMetric(namespace, "discarded", "Connections completed without creating a session", variableLabelNames.StreamServerZoneVariableLabelNames, constLabels),
-			"received":              newStreamServerZoneMetric(namespace, "received", "Bytes received from clients", variableLabelNames.StreamServerZoneVariableLabelNames, constLabels),
-			"sent":                  newStreamServerZoneMetric(namespace, "sent", "Bytes sent to clients", variableLabelNames.StreamServerZoneVariableLabelNames, constLabels),
-			"ssl_handshakes":        newStreamServerZoneMetric(namespace, "ssl_handshakes", "Successful SSL handshakes", variableLabelNames.StreamServerZoneVariableLabelNames, constLabels),
-			"ssl_handshakes_failed": newStreamServerZoneMetric(namespace, "ssl_handshakes_failed", "Failed SSL handshakes", variableLabelNames.StreamServerZoneVariableLabelNames, constLabels),
-			"ssl_session_reuses":    newStreamServerZoneMetric(namespace, "ssl_session_reuses", "Session reuses during SSL handshake", variableLabelNames.StreamServerZoneVariableLabelNames, constLabels),
+			"processing":             newStreamServerZoneMetric(namespace, "processing", "Client connections that are currently being processed", variableLabelNames.StreamServerZoneVariableLabelNames, constLabels),
+			"connections":            newStreamServerZoneMetric(namespace, "connections", "Total connections", variableLabelNames.StreamServerZoneVariableLabelNames, constLabels),
+			"sessions_2xx":           newStreamServerZoneMetric(namespace, "sessions", "Total sessions completed", variableLabelNames.StreamServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "2xx"})),
+			"sessions_4xx":           newStreamServerZoneMetric(namespace, "sessions", "Total sessions completed", variableLabelNames.StreamServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "4xx"})),
+			"sessions_5xx":           newStreamServerZoneMetric(namespace, "sessions", "Total sessions completed", variableLabelNames.StreamServerZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "5xx"})),
+			"discarded":              newStreamServerZoneMetric(namespace, "discarded", "Connections completed without creating a session", variableLabelNames.StreamServerZoneVariableLabelNames, constLabels),
+			"received":               newStreamServerZoneMetric(namespace, "received", "Bytes received from clients", variableLabelNames.StreamServerZoneVariableLabelNames, constLabels),
+			"sent":                   newStreamServerZoneMetric(namespace, "sent", "Bytes sent to clients", variableLabelNames.StreamServerZoneVariableLabelNames, constLabels),
+			"ssl_handshakes":         newStreamServerZoneMetric(namespace, "ssl_handshakes", "Successful SSL handshakes", variableLabelNames.StreamServerZoneVariableLabelNames, constLabels),
+			"ssl_handshakes_failed":  newStreamServerZoneMetric(namespace, "ssl_handshakes_failed", "Failed SSL handshakes", variableLabelNames.StreamServerZoneVariableLabelNames, constLabels),
+			"ssl_session_reuses":     newStreamServerZoneMetric(namespace, "ssl_session_reuses", "Session reuses during SSL handshake", variableLabelNames.StreamServerZoneVariableLabelNames, constLabels),
+			"ssl_handshake_failures": newSSLHandshakeFailuresMetric(namespace, "stream_server_zone", append([]string{"server_zone"}, variableLabelNames.StreamServerZoneVariableLabelNames...), constLabels),
+			"ssl_verify_failures":    newSSLVerifyFailuresMetric(namespace, "stream_server_zone", append([]string{"server_zone"}, variableLabelNames.StreamServerZoneVariableLabelNames...), constLabels),
 		},
 		upstreamMetrics: map[string]*prometheus.Desc{
-			"keepalive": newUpstreamMetric(namespace, "keepalive", "Idle keepalive connections", constLabels),
-			"zombies":   newUpstreamMetric(namespace, "zombies", "Servers removed from the group but still processing active client requests", constLabels),
+			"keepalive":       newUpstreamMetric(namespace, "keepalive", "Idle keepalive connections", constLabels),
+			"zombies":         newUpstreamMetric(namespace, "zombies", "Servers removed from the group but still processing active client requests", constLabels),
+			"queue_size":      newUpstreamMetric(namespace, "queue_size", "Current number of requests in the queue", constLabels),
+			"queue_max_size":  newUpstreamMetric(namespace, "queue_max_size", "Maximum number of requests that can be in the queue at the same time", constLabels),
+			"queue_overflows": newUpstreamMetric(namespace, "queue_overflows", "Total number of requests rejected due to the queue overflow", constLabels),
 		},
 		streamUpstreamMetrics: map[string]*prometheus.Desc{
 			"zombies": newStreamUpstreamMetric(namespace, "zombies", "Servers removed from the group but still processing active client connections", constLabels),
 		},
 		upstreamServerMetrics: map[string]*prometheus.Desc{
-			"state":                   newUpstreamServerMetric(namespace, "state", "Current state", upstreamServerVariableLabelNames, constLabels),
-			"active":                  newUpstreamServerMetric(namespace, "active", "Active connections", upstreamServerVariableLabelNames, constLabels),
-			"limit":                   newUpstreamServerMetric(namespace, "limit", "Limit for connections which corresponds to the max_conns parameter of the upstream server. Zero value means there is no limit", upstreamServerVariableLabelNames, constLabels),
-			"requests":                newUpstreamServerMetric(namespace, "requests", "Total client requests", upstreamServerVariableLabelNames, constLabels),
-			"responses_1xx":           newUpstreamServerMetric(namespace, "responses", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "1xx"})),
-			"responses_2xx":           newUpstreamServerMetric(namespace, "responses", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "2xx"})),
-			"responses_3xx":           newUpstreamServerMetric(namespace, "responses", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "3xx"})),
-			"responses_4xx":           newUpstreamServerMetric(namespace, "responses", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "4xx"})),
-			"responses_5xx":           newUpstreamServerMetric(namespace, "responses", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "5xx"})),
-			"sent":                    newUpstreamServerMetric(namespace, "sent", "Bytes sent to this server", upstreamServerVariableLabelNames, constLabels),
-			"received":                newUpstreamServerMetric(namespace, "received", "Bytes received to this server", upstreamServerVariableLabelNames, constLabels),
-			"fails":                   newUpstreamServerMetric(namespace, "fails", "Number of unsuccessful attempts to communicate with the server", upstreamServerVariableLabelNames, constLabels),
-			"unavail":                 newUpstreamServerMetric(namespace, "unavail", "How many times the server became unavailable for client requests (state 'unavail') due to the number of unsuccessful attempts reaching the max_fails threshold", upstreamServerVariableLabelNames, constLabels),
-			"header_time":             newUpstreamServerMetric(namespace, "header_time", "Average time to get the response header from the server", upstreamServerVariableLabelNames, constLabels),
-			"response_time":           newUpstreamServerMetric(namespace, "response_time", "Average time to get the full response from the server", upstreamServerVariableLabelNames, constLabels),
-			"health_checks_checks":    newUpstreamServerMetric(namespace, "health_checks_checks", "Total health check requests", upstreamServerVariableLabelNames, constLabels),
-			"health_checks_fails":     newUpstreamServerMetric(namespace, "health_checks_fails", "Failed health checks", upstreamServerVariableLabelNames, constLabels),
-			"health_checks_unhealthy": newUpstreamServerMetric(namespace, "health_checks_unhealthy", "How many times the server became unhealthy (state 'unhealthy')", upstreamServerVariableLabelNames, constLabels),
-			"codes_100":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "100"})),
-			"codes_101":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "101"})),
-			"codes_102":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "102"})),
-			"codes_200":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "200"})),
-			"codes_201":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "201"})),
-			"codes_202":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "202"})),
-			"codes_204":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "204"})),
-			"codes_206":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "206"})),
-			"codes_300":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "300"})),
-			"codes_301":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "301"})),
-			"codes_302":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "302"})),
-			"codes_303":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "303"})),
-			"codes_304":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "304"})),
-			"codes_307":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "307"})),
-			"codes_400":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "400"})),
-			"codes_401":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "401"})),
-			"codes_403":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "403"})),
-			"codes_404":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "404"})),
-			"codes_405":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "405"})),
-			"codes_408":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "408"})),
-			"codes_409":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "409"})),
-			"codes_411":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "411"})),
-			"codes_412":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "412"})),
-			"codes_413":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "413"})),
-			"codes_414":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "414"})),
-			"codes_415":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "415"})),
-			"codes_416":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "416"})),
-			"codes_429":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "429"})),
-			"codes_444":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "444"})),
-			"codes_494":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "494"})),
-			"codes_495":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "495"})),
-			"codes_496":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "496"})),
-			"codes_497":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "497"})),
-			"codes_499":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "499"})),
-			"codes_500":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "500"})),
-			"codes_501":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "501"})),
-			"codes_502":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "502"})),
-			"codes_503":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "503"})),
-			"codes_504":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "504"})),
-			"codes_507":               newUpstreamServerMetric(namespace, "responses_codes", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "507"})),
-			"ssl_handshakes":          newUpstreamServerMetric(namespace, "ssl_handshakes", "Successful SSL handshakes", upstreamServerVariableLabelNames, constLabels),
-			"ssl_handshakes_failed":   newUpstreamServerMetric(namespace, "ssl_handshakes_failed", "Failed SSL handshakes", upstreamServerVariableLabelNames, constLabels),
-			"ssl_session_reuses":      newUpstreamServerMetric(namespace, "ssl_session_reuses", "Session reuses during SSL handshake", upstreamServerVariableLabelNames, constLabels),
+			"state":                     newUpstreamServerMetric(namespace, "state", "Current state", upstreamServerVariableLabelNames, constLabels),
+			"active":                    newUpstreamServerMetric(namespace, "active", "Active connections", upstreamServerVariableLabelNames, constLabels),
+			"limit":                     newUpstreamServerMetric(namespace, "limit", "Limit for connections which corresponds to the max_conns parameter of the upstream server. Zero value means there is no limit", upstreamServerVariableLabelNames, constLabels),
+			"requests":                  newUpstreamServerMetric(namespace, "requests", "Total client requests", upstreamServerVariableLabelNames, constLabels),
+			"responses_1xx":             newUpstreamServerMetric(namespace, "responses", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "1xx"})),
+			"responses_2xx":             newUpstreamServerMetric(namespace, "responses", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "2xx"})),
+			"responses_3xx":             newUpstreamServerMetric(namespace, "responses", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "3xx"})),
+			"responses_4xx":             newUpstreamServerMetric(namespace, "responses", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "4xx"})),
+			"responses_5xx":             newUpstreamServerMetric(namespace, "responses", "Total responses sent to clients", upstreamServerVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "5xx"})),
+			"sent":                      newUpstreamServerMetric(namespace, "sent", "Bytes sent to this server", upstreamServerVariableLabelNames, constLabels),
+			"received":                  newUpstreamServerMetric(namespace, "received", "Bytes received to this server", upstreamServerVariableLabelNames, constLabels),
+			"fails":                     newUpstreamServerMetric(namespace, "fails", "Number of unsuccessful attempts to communicate with the server", upstreamServerVariableLabelNames, constLabels),
+			"unavail":                   newUpstreamServerMetric(namespace, "unavail", "How many times the server became unavailable for client requests (state 'unavail') due to the number of unsuccessful attempts reaching the max_fails threshold", upstreamServerVariableLabelNames, constLabels),
+			"header_time":               newUpstreamServerMetric(namespace, "header_time", "Average time to get the response header from the server", upstreamServerVariableLabelNames, constLabels),
+			"response_time":             newUpstreamServerMetric(namespace, "response_time", "Average time to get the full response from the server", upstreamServerVariableLabelNames, constLabels),
+			"health_checks_checks":      newUpstreamServerMetric(namespace, "health_checks_checks", "Total health check requests", upstreamServerVariableLabelNames, constLabels),
+			"health_checks_fails":       newUpstreamServerMetric(namespace, "health_checks_fails", "Failed health checks", upstreamServerVariableLabelNames, constLabels),
+			"health_checks_unhealthy":   newUpstreamServerMetric(namespace, "health_checks_unhealthy", "How many times the server became unhealthy (state 'unhealthy')", upstreamServerVariableLabelNames, constLabels),
+			"responses_codes":           newResponseCodesMetric(namespace, "upstream_server", append([]string{"upstream", "server"}, upstreamServerVariableLabelNames...), constLabels),
+			"ssl_handshakes":            newUpstreamServerMetric(namespace, "ssl_handshakes", "Successful SSL handshakes", upstreamServerVariableLabelNames, constLabels),
+			"ssl_handshakes_failed":     newUpstreamServerMetric(namespace, "ssl_handshakes_failed", "Failed SSL handshakes", upstreamServerVariableLabelNames, constLabels),
+			"ssl_session_reuses":        newUpstreamServerMetric(namespace, "ssl_session_reuses", "Session reuses during SSL handshake", upstreamServerVariableLabelNames, constLabels),
+			"weight":                    newUpstreamServerMetric(namespace, "weight", "Weight of the server", upstreamServerVariableLabelNames, constLabels),
+			"downtime":                  newUpstreamServerMetric(namespace, "downtime", "Total time the server was in the unavail, checking and unhealthy states, in milliseconds", upstreamServerVariableLabelNames, constLabels),
+			"downstart":                 newUpstreamServerMetric(namespace, "downstart_timestamp_seconds", "Time when the server became unavail, checking or unhealthy, in seconds since the Unix epoch", upstreamServerVariableLabelNames, constLabels),
+			"selected":                  newUpstreamServerMetric(namespace, "selected_timestamp_seconds", "Time when the server was last selected to process a request, in seconds since the Unix epoch", upstreamServerVariableLabelNames, constLabels),
+			"health_checks_last_passed": newUpstreamServerMetric(namespace, "health_checks_last_passed", "Whether the last health check request was successful and passed tests", upstreamServerVariableLabelNames, constLabels),
+			"info":                      newPeerInfoMetric(namespace, "upstream_server", constLabels),
+			"ssl_handshake_failures":    newSSLHandshakeFailuresMetric(namespace, "upstream_server", append([]string{"upstream", "server"}, upstreamServerVariableLabelNames...), constLabels),
+			"ssl_verify_failures":       newSSLVerifyFailuresMetric(namespace, "upstream_server", append([]string{"upstream", "server"}, upstreamServerVariableLabelNames...), constLabels),
 		},
 		streamUpstreamServerMetrics: map[string]*prometheus.Desc{
-			"state":                   newStreamUpstreamServerMetric(namespace, "state", "Current state", streamUpstreamServerVariableLabelNames, constLabels),
-			"active":                  newStreamUpstreamServerMetric(namespace, "active", "Active connections", streamUpstreamServerVariableLabelNames, constLabels),
-			"limit":                   newStreamUpstreamServerMetric(namespace, "limit", "Limit for connections which corresponds to the max_conns parameter of the upstream server. Zero value means there is no limit", streamUpstreamServerVariableLabelNames, constLabels),
-			"sent":                    newStreamUpstreamServerMetric(namespace, "sent", "Bytes sent to this server", streamUpstreamServerVariableLabelNames, constLabels),
-			"received":                newStreamUpstreamServerMetric(namespace, "received", "Bytes received from this server", streamUpstreamServerVariableLabelNames, constLabels),
-			"fails":                   newStreamUpstreamServerMetric(namespace, "fails", "Number of unsuccessful attempts to communicate with the server", streamUpstreamServerVariableLabelNames, constLabels),
-			"unavail":                 newStreamUpstreamServerMetric(namespace, "unavail", "How many times the server became unavailable for client connections (state 'unavail') due to the number of unsuccessful attempts reaching the max_fails threshold", streamUpstreamServerVariableLabelNames, constLabels),
-			"connections":             newStreamUpstreamServerMetric(namespace, "connections", "Total number of client connections forwarded to this server", streamUpstreamServerVariableLabelNames, constLabels),
-			"connect_time":            newStreamUpstreamServerMetric(namespace, "connect_time", "Average time to connect to the upstream server", streamUpstreamServerVariableLabelNames, constLabels),
-			"first_byte_time":         newStreamUpstreamServerMetric(namespace, "first_byte_time", "Average time to receive the first byte of data", streamUpstreamServerVariableLabelNames, constLabels),
-			"response_time":           newStreamUpstreamServerMetric(namespace, "response_time", "Average time to receive the last byte of data", streamUpstreamServerVariableLabelNames, constLabels),
-			"health_checks_checks":    newStreamUpstreamServerMetric(namespace, "health_checks_checks", "Total health check requests", streamUpstreamServerVariableLabelNames, constLabels),
-			"health_checks_fails":     newStreamUpstreamServerMetric(namespace, "health_checks_fails", "Failed health checks", streamUpstreamServerVariableLabelNames, constLabels),
-			"health_checks_unhealthy": newStreamUpstreamServerMetric(namespace, "health_checks_unhealthy", "How many times the server became unhealthy (state 'unhealthy')", streamUpstreamServerVariableLabelNames, constLabels),
-			"ssl_handshakes":          newStreamUpstreamServerMetric(namespace, "ssl_handshakes", "Successful SSL handshakes", streamUpstreamServerVariableLabelNames, constLabels),
-			"ssl_handshakes_failed":   newStreamUpstreamServerMetric(namespace, "ssl_handshakes_failed", "Failed SSL handshakes", streamUpstreamServerVariableLabelNames, constLabels),
-			"ssl_session_reuses":      newStreamUpstreamServerMetric(namespace, "ssl_session_reuses", "Session reuses during SSL handshake", streamUpstreamServerVariableLabelNames, constLabels),
+			"state":                     newStreamUpstreamServerMetric(namespace, "state", "Current state", streamUpstreamServerVariableLabelNames, constLabels),
+			"active":                    newStreamUpstreamServerMetric(namespace, "active", "Active connections", streamUpstreamServerVariableLabelNames, constLabels),
+			"limit":                     newStreamUpstreamServerMetric(namespace, "limit", "Limit for connections which corresponds to the max_conns parameter of the upstream server. Zero value means there is no limit", streamUpstreamServerVariableLabelNames, constLabels),
+			"sent":                      newStreamUpstreamServerMetric(namespace, "sent", "Bytes sent to this server", streamUpstreamServerVariableLabelNames, constLabels),
+			"received":                  newStreamUpstreamServerMetric(namespace, "received", "Bytes received from this server", streamUpstreamServerVariableLabelNames, constLabels),
+			"fails":                     newStreamUpstreamServerMetric(namespace, "fails", "Number of unsuccessful attempts to communicate with the server", streamUpstreamServerVariableLabelNames, constLabels),
+			"unavail":                   newStreamUpstreamServerMetric(namespace, "unavail", "How many times the server became unavailable for client connections (state 'unavail') due to the number of unsuccessful attempts reaching the max_fails threshold", streamUpstreamServerVariableLabelNames, constLabels),
+			"connections":               newStreamUpstreamServerMetric(namespace, "connections", "Total number of client connections forwarded to this server", streamUpstreamServerVariableLabelNames, constLabels),
+			"connect_time":              newStreamUpstreamServerMetric(namespace, "connect_time", "Average time to connect to the upstream server", streamUpstreamServerVariableLabelNames, constLabels),
+			"first_byte_time":           newStreamUpstreamServerMetric(namespace, "first_byte_time", "Average time to receive the first byte of data", streamUpstreamServerVariableLabelNames, constLabels),
+			"response_time":             newStreamUpstreamServerMetric(namespace, "response_time", "Average time to receive the last byte of data", streamUpstreamServerVariableLabelNames, constLabels),
+			"health_checks_checks":      newStreamUpstreamServerMetric(namespace, "health_checks_checks", "Total health check requests", streamUpstreamServerVariableLabelNames, constLabels),
+			"health_checks_fails":       newStreamUpstreamServerMetric(namespace, "health_checks_fails", "Failed health checks", streamUpstreamServerVariableLabelNames, constLabels),
+			"health_checks_unhealthy":   newStreamUpstreamServerMetric(namespace, "health_checks_unhealthy", "How many times the server became unhealthy (state 'unhealthy')", streamUpstreamServerVariableLabelNames, constLabels),
+			"ssl_handshakes":            newStreamUpstreamServerMetric(namespace, "ssl_handshakes", "Successful SSL handshakes", streamUpstreamServerVariableLabelNames, constLabels),
+			"ssl_handshakes_failed":     newStreamUpstreamServerMetric(namespace, "ssl_handshakes_failed", "Failed SSL handshakes", streamUpstreamServerVariableLabelNames, constLabels),
+			"ssl_session_reuses":        newStreamUpstreamServerMetric(namespace, "ssl_session_reuses", "Session reuses during SSL handshake", streamUpstreamServerVariableLabelNames, constLabels),
+			"weight":                    newStreamUpstreamServerMetric(namespace, "weight", "Weight of the server", streamUpstreamServerVariableLabelNames, constLabels),
+			"downtime":                  newStreamUpstreamServerMetric(namespace, "downtime", "Total time the server was in the unavail, checking and unhealthy states, in milliseconds", streamUpstreamServerVariableLabelNames, constLabels),
+			"downstart":                 newStreamUpstreamServerMetric(namespace, "downstart_timestamp_seconds", "Time when the server became unavail, checking or unhealthy, in seconds since the Unix epoch", streamUpstreamServerVariableLabelNames, constLabels),
+			"selected":                  newStreamUpstreamServerMetric(namespace, "selected_timestamp_seconds", "Time when the server was last selected to process a request, in seconds since the Unix epoch", streamUpstreamServerVariableLabelNames, constLabels),
+			"health_checks_last_passed": newStreamUpstreamServerMetric(namespace, "health_checks_last_passed", "Whether the last health check request was successful and passed tests", streamUpstreamServerVariableLabelNames, constLabels),
+			"info":                      newPeerInfoMetric(namespace, "stream_upstream_server", constLabels),
 		},
 		streamZoneSyncMetrics: map[string]*prometheus.Desc{
 			"bytes_in":        newStreamZoneSyncMetric(namespace, "bytes_in", "Bytes received by this node", constLabels),
@@ -447,91 +493,55 @@ func NewNginxPlusCollector(nginxClient *plusclient.NginxClient, namespace string
 			"msgs_in":         newStreamZoneSyncMetric(namespace, "msgs_in", "Total messages received by this node", constLabels),
 			"msgs_out":        newStreamZoneSyncMetric(namespace, "msgs_out", "Total messages sent by this node", constLabels),
 			"nodes_online":    newStreamZoneSyncMetric(namespace, "nodes_online", "Number of peers this node is connected to", constLabels),
-			"records_pending": newStreamZoneSyncZoneMetric(namespace, "records_pending", "The number of records that need to be sent to the cluster", constLabels),
-			"records_total":   newStreamZoneSyncZoneMetric(namespace, "records_total", "The total number of records stored in the shared memory zone", constLabels),
+			"records_pending": newStreamZoneSyncZoneMetric(namespace, "records_pending", "The number of records that need to be sent to the cluster", variableLabelNames.StreamZoneSyncZoneVariableLabelNames, constLabels),
+			"records_total":   newStreamZoneSyncZoneMetric(namespace, "records_total", "The total number of records stored in the shared memory zone", variableLabelNames.StreamZoneSyncZoneVariableLabelNames, constLabels),
 		},
 		locationZoneMetrics: map[string]*prometheus.Desc{
-			"requests":      newLocationZoneMetric(namespace, "requests", "Total client requests", constLabels),
-			"responses_1xx": newLocationZoneMetric(namespace, "responses", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "1xx"})),
-			"responses_2xx": newLocationZoneMetric(namespace, "responses", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "2xx"})),
-			"responses_3xx": newLocationZoneMetric(namespace, "responses", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "3xx"})),
-			"responses_4xx": newLocationZoneMetric(namespace, "responses", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "4xx"})),
-			"responses_5xx": newLocationZoneMetric(namespace, "responses", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "5xx"})),
-			"discarded":     newLocationZoneMetric(namespace, "discarded", "Requests completed without sending a response", constLabels),
-			"received":      newLocationZoneMetric(namespace, "received", "Bytes received from clients", constLabels),
-			"sent":          newLocationZoneMetric(namespace, "sent", "Bytes sent to clients", constLabels),
-			"codes_100":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "100"})),
-			"codes_101":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "101"})),
-			"codes_102":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "102"})),
-			"codes_200":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "200"})),
-			"codes_201":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "201"})),
-			"codes_202":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "202"})),
-			"codes_204":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "204"})),
-			"codes_206":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "206"})),
-			"codes_300":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "300"})),
-			"codes_301":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "301"})),
-			"codes_302":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "302"})),
-			"codes_303":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "303"})),
-			"codes_304":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "304"})),
-			"codes_307":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "307"})),
-			"codes_400":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "400"})),
-			"codes_401":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "401"})),
-			"codes_403":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "403"})),
-			"codes_404":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "404"})),
-			"codes_405":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "405"})),
-			"codes_408":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "408"})),
-			"codes_409":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "409"})),
-			"codes_411":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "411"})),
-			"codes_412":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "412"})),
-			"codes_413":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "413"})),
-			"codes_414":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "414"})),
-			"codes_415":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "415"})),
-			"codes_416":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "416"})),
-			"codes_429":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "429"})),
-			"codes_444":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "444"})),
-			"codes_494":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "494"})),
-			"codes_495":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "495"})),
-			"codes_496":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "496"})),
-			"codes_497":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "497"})),
-			"codes_499":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "499"})),
-			"codes_500":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "500"})),
-			"codes_501":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "501"})),
-			"codes_502":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "502"})),
-			"codes_503":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "503"})),
-			"codes_504":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "504"})),
-			"codes_507":     newLocationZoneMetric(namespace, "responses_codes", "Total responses sent to clients", MergeLabels(constLabels, prometheus.Labels{"code": "507"})),
+			"requests":        newLocationZoneMetric(namespace, "requests", "Total client requests", variableLabelNames.LocationZoneVariableLabelNames, constLabels),
+			"responses_1xx":   newLocationZoneMetric(namespace, "responses", "Total responses sent to clients", variableLabelNames.LocationZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "1xx"})),
+			"responses_2xx":   newLocationZoneMetric(namespace, "responses", "Total responses sent to clients", variableLabelNames.LocationZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "2xx"})),
+			"responses_3xx":   newLocationZoneMetric(namespace, "responses", "Total responses sent to clients", variableLabelNames.LocationZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "3xx"})),
+			"responses_4xx":   newLocationZoneMetric(namespace, "responses", "Total responses sent to clients", variableLabelNames.LocationZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "4xx"})),
+			"responses_5xx":   newLocationZoneMetric(namespace, "responses", "Total responses sent to clients", variableLabelNames.LocationZoneVariableLabelNames, MergeLabels(constLabels, prometheus.Labels{"code": "5xx"})),
+			"discarded":       newLocationZoneMetric(namespace, "discarded", "Requests completed without sending a response", variableLabelNames.LocationZoneVariableLabelNames, constLabels),
+			"received":        newLocationZoneMetric(namespace, "received", "Bytes received from clients", variableLabelNames.LocationZoneVariableLabelNames, constLabels),
+			"sent":            newLocationZoneMetric(namespace, "sent", "Bytes sent to clients", variableLabelNames.LocationZoneVariableLabelNames, constLabels),
+			"responses_codes": newResponseCodesMetric(namespace, "location_zone", append([]string{"location_zone"}, variableLabelNames.LocationZoneVariableLabelNames...), constLabels),
 		},
 		resolverMetrics: map[string]*prometheus.Desc{
-			"name":     newResolverMetric(namespace, "name", "Total requests to resolve names to addresses", constLabels),
-			"srv":      newResolverMetric(namespace, "srv", "Total requests to resolve SRV records", constLabels),
-			"addr":     newResolverMetric(namespace, "addr", "Total requests to resolve addresses to names", constLabels),
-			"noerror":  newResolverMetric(namespace, "noerror", "Total number of successful responses", constLabels),
-			"formerr":  newResolverMetric(namespace, "formerr", "Total number of FORMERR responses", constLabels),
-			"servfail": newResolverMetric(namespace, "servfail", "Total number of SERVFAIL responses", constLabels),
-			"nxdomain": newResolverMetric(namespace, "nxdomain", "Total number of NXDOMAIN responses", constLabels),
-			"notimp":   newResolverMetric(namespace, "notimp", "Total number of NOTIMP responses", constLabels),
-			"refused":  newResolverMetric(namespace, "refused", "Total number of REFUSED responses", constLabels),
-			"timedout": newResolverMetric(namespace, "timedout", "Total number of timed out requests", constLabels),
-			"unknown":  newResolverMetric(namespace, "unknown", "Total requests completed with an unknown error", constLabels),
+			"name":     newResolverMetric(namespace, "name", "Total requests to resolve names to addresses", variableLabelNames.ResolverVariableLabelNames, constLabels),
+			"srv":      newResolverMetric(namespace, "srv", "Total requests to resolve SRV records", variableLabelNames.ResolverVariableLabelNames, constLabels),
+			"addr":     newResolverMetric(namespace, "addr", "Total requests to resolve addresses to names", variableLabelNames.ResolverVariableLabelNames, constLabels),
+			"noerror":  newResolverMetric(namespace, "noerror", "Total number of successful responses", variableLabelNames.ResolverVariableLabelNames, constLabels),
+			"formerr":  newResolverMetric(namespace, "formerr", "Total number of FORMERR responses", variableLabelNames.ResolverVariableLabelNames, constLabels),
+			"servfail": newResolverMetric(namespace, "servfail", "Total number of SERVFAIL responses", variableLabelNames.ResolverVariableLabelNames, constLabels),
+			"nxdomain": newResolverMetric(namespace, "nxdomain", "Total number of NXDOMAIN responses", variableLabelNames.ResolverVariableLabelNames, constLabels),
+			"notimp":   newResolverMetric(namespace, "notimp", "Total number of NOTIMP responses", variableLabelNames.ResolverVariableLabelNames, constLabels),
+			"refused":  newResolverMetric(namespace, "refused", "Total number of REFUSED responses", variableLabelNames.ResolverVariableLabelNames, constLabels),
+			"timedout": newResolverMetric(namespace, "timedout", "Total number of timed out requests", variableLabelNames.ResolverVariableLabelNames, constLabels),
+			"unknown":  newResolverMetric(namespace, "unknown", "Total requests completed with an unknown error", variableLabelNames.ResolverVariableLabelNames, constLabels),
 		},
 		limitRequestMetrics: map[string]*prometheus.Desc{
-			"passed":           newLimitRequestMetric(namespace, "passed", "Total number of requests that were neither limited nor accounted as limited", constLabels),
-			"delayed":          newLimitRequestMetric(namespace, "delayed", "Total number of requests that were delayed", constLabels),
-			"rejected":         newLimitRequestMetric(namespace, "rejected", "Total number of requests that were rejected", constLabels),
-			"delayed_dry_run":  newLimitRequestMetric(namespace, "delayed_dry_run", "Total number of requests accounted as delayed in the dry run mode", constLabels),
-			"rejected_dry_run": newLimitRequestMetric(namespace, "rejected_dry_run", "Total number of requests accounted as rejected in the dry run mode", constLabels),
+			"passed":           newLimitRequestMetric(namespace, "passed", "Total number of requests that were neither limited nor accounted as limited", variableLabelNames.LimitRequestVariableLabelNames, constLabels),
+			"delayed":          newLimitRequestMetric(namespace, "delayed", "Total number of requests that were delayed", variableLabelNames.LimitRequestVariableLabelNames, constLabels),
+			"rejected":         newLimitRequestMetric(namespace, "rejected", "Total number of requests that were rejected", variableLabelNames.LimitRequestVariableLabelNames, constLabels),
+			"delayed_dry_run":  newLimitRequestMetric(namespace, "delayed_dry_run", "Total number of requests accounted as delayed in the dry run mode", variableLabelNames.LimitRequestVariableLabelNames, constLabels),
+			"rejected_dry_run": newLimitRequestMetric(namespace, "rejected_dry_run", "Total number of requests accounted as rejected in the dry run mode", variableLabelNames.LimitRequestVariableLabelNames, constLabels),
 		},
 		limitConnectionMetrics: map[string]*prometheus.Desc{
-			"passed":           newLimitConnectionMetric(namespace, "passed", "Total number of connections that were neither limited nor accounted as limited", constLabels),
-			"rejected":         newLimitConnectionMetric(namespace, "rejected", "Total number of connections that were rejected", constLabels),
-			"rejected_dry_run": newLimitConnectionMetric(namespace, "rejected_dry_run", "Total number of connections accounted as rejected in the dry run mode", constLabels),
+			"passed":           newLimitConnectionMetric(namespace, "passed", "Total number of connections that were neither limited nor accounted as limited", variableLabelNames.LimitConnectionVariableLabelNames, constLabels),
+			"rejected":         newLimitConnectionMetric(namespace, "rejected", "Total number of connections that were rejected", variableLabelNames.LimitConnectionVariableLabelNames, constLabels),
+			"rejected_dry_run": newLimitConnectionMetric(namespace, "rejected_dry_run", "Total number of connections accounted as rejected in the dry run mode", variableLabelNames.LimitConnectionVariableLabelNames, constLabels),
 		},
 		streamLimitConnectionMetrics: map[string]*prometheus.Desc{
-			"passed":           newStreamLimitConnectionMetric(namespace, "passed", "Total number of connections that were neither limited nor accounted as limited", constLabels),
-			"rejected":         newStreamLimitConnectionMetric(namespace, "rejected", "Total number of connections that were rejected", constLabels),
-			"rejected_dry_run": newStreamLimitConnectionMetric(namespace, "rejected_dry_run", "Total number of connections accounted as rejected in the dry run mode", constLabels),
+			"passed":           newStreamLimitConnectionMetric(namespace, "passed", "Total number of connections that were neither limited nor accounted as limited", variableLabelNames.StreamLimitConnectionVariableLabelNames, constLabels),
+			"rejected":         newStreamLimitConnectionMetric(namespace, "rejected", "Total number of connections that were rejected", variableLabelNames.StreamLimitConnectionVariableLabelNames, constLabels),
+			"rejected_dry_run": newStreamLimitConnectionMetric(namespace, "rejected_dry_run", "Total number of connections accounted as rejected in the dry run mode", variableLabelNames.StreamLimitConnectionVariableLabelNames, constLabels),
 		},
-		upMetric: newUpMetric(namespace, constLabels),
+		upMetric:                newUpMetric(namespace, constLabels),
+		snapshotAgeMetric:       newSnapshotAgeMetric(namespace, constLabels),
+		filteredObjectsMetric:   newFilteredObjectsMetric(constLabels),
+		labelStoreEntriesMetric: newLabelStoreEntriesMetric(constLabels),
 		cacheZoneMetrics: map[string]*prometheus.Desc{
 			"size":                      newCacheZoneMetric(namespace, "size", "Total size of the cache", variableLabelNames.CacheZoneVariableLabelNames, constLabels),
 			"max_size":                  newCacheZoneMetric(namespace, "max_size", "Maximum size of the cache", variableLabelNames.CacheZoneVariableLabelNames, constLabels),
@@ -555,6 +565,29 @@ func NewNginxPlusCollector(nginxClient *plusclient.NginxClient, namespace string
 			"bypass_responses_written":  newCacheZoneMetric(namespace, "bypass_responses_written", "Total number of cache bypasses written to cache", variableLabelNames.CacheZoneVariableLabelNames, constLabels),
 			"bypass_bytes_written":      newCacheZoneMetric(namespace, "bypass_bytes_written", "Total number of bytes written to cache from cache bypasses", variableLabelNames.CacheZoneVariableLabelNames, constLabels),
 		},
+		slabMetrics: map[string]*prometheus.Desc{
+			"pages_used": newSlabMetric(namespace, "pages_used", "Number of used memory pages of the shared memory zone", nil, constLabels),
+			"pages_free": newSlabMetric(namespace, "pages_free", "Number of free memory pages of the shared memory zone", nil, constLabels),
+			"slot_used":  newSlabMetric(namespace, "slot_used", "Number of used memory slots of the size", []string{"slot"}, constLabels),
+			"slot_free":  newSlabMetric(namespace, "slot_free", "Number of free memory slots of the size", []string{"slot"}, constLabels),
+			"slot_reqs":  newSlabMetric(namespace, "slot_reqs", "Total number of attempts to allocate memory of the size", []string{"slot"}, constLabels),
+			"slot_fails": newSlabMetric(namespace, "slot_fails", "Total number of unsuccessful attempts to allocate memory of the size", []string{"slot"}, constLabels),
+		},
+		licenseMetrics: map[string]*prometheus.Desc{
+			"expiry_timestamp":       newLicenseMetric(namespace, "expiry_timestamp_seconds", "Time when the license expires, in seconds since the Unix epoch", constLabels),
+			"days_remaining":         newLicenseMetric(namespace, "days_remaining", "Number of days until the license expires", constLabels),
+			"eval":                   newLicenseMetric(namespace, "eval", "Whether the license is an evaluation license", constLabels),
+			"reporting_healthy":      newLicenseMetric(namespace, "reporting_healthy", "Whether the last usage report was successful", constLabels),
+			"reporting_fails":        newLicenseMetric(namespace, "reporting_fails", "Number of unsuccessful usage reports", constLabels),
+			"reporting_grace_period": newLicenseMetric(namespace, "reporting_grace_period_seconds", "Remaining grace period for a successful usage report before NGINX Plus stops processing traffic", constLabels),
+		},
+		keyValMetrics: map[string]*prometheus.Desc{
+			"entries":         newKeyValMetric(namespace, "keyval", "entries", "Number of key-value pairs in the zone", nil, constLabels),
+			"stream_entries":  newKeyValMetric(namespace, "stream_keyval", "entries", "Number of key-value pairs in the zone", nil, constLabels),
+			"key_info":        newKeyValMetric(namespace, "keyval", "key_info", "Value of an exported key of the zone", []string{"key", "value"}, constLabels),
+			"stream_key_info": newKeyValMetric(namespace, "stream_keyval", "key_info", "Value of an exported key of the zone", []string{"key", "value"}, constLabels),
+			"keys_dropped":    newKeyValKeysDroppedMetric(constLabels),
+		},
 		workerMetrics: map[string]*prometheus.Desc{
 			"connection_accepted":   newWorkerMetric(namespace, "connection_accepted", "The total number of accepted client connections", constLabels),
 			"connection_dropped":    newWorkerMetric(namespace, "connection_dropped", "The total number of dropped client connections", constLabels),
@@ -562,110 +595,193 @@ func NewNginxPlusCollector(nginxClient *plusclient.NginxClient, namespace string
 			"connection_idle":       newWorkerMetric(namespace, "connection_idle", "The current number of idle client connections", constLabels),
 			"http_requests_total":   newWorkerMetric(namespace, "http_requests_total", "The total number of client requests received by the worker process", constLabels),
 			"http_requests_current": newWorkerMetric(namespace, "http_requests_current", "The current number of client requests that are currently being processed by the worker process", constLabels),
+			"restarts":              newWorkerRestartsMetric(namespace, constLabels),
+			"workers":               newGlobalMetric(namespace, "workers", "Number of worker processes", constLabels),
+			"processes_respawned":   newGlobalMetric(namespace, "processes_respawned", "Total number of abnormally terminated and respawned child processes", constLabels),
 		},
 	}
+	WithSections(DefaultSections()...)(c)
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// UpDesc returns the descriptor of the up metric, which reports whether the last scrape of NGINX Plus succeeded.
+func (c *NginxPlusCollector) UpDesc() *prometheus.Desc {
+	return c.upMetric.Desc()
 }
 
 // Describe sends the super-set of all possible descriptors of NGINX Plus metrics
-// to the provided channel.
+// to the provided channel. The metrics of disabled sections are not described.
 func (c *NginxPlusCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.upMetric.Desc()
-
-	for _, m := range c.totalMetrics {
-		ch <- m
-	}
-	for _, m := range c.serverZoneMetrics {
-		ch <- m
-	}
-	for _, m := range c.upstreamMetrics {
-		ch <- m
-	}
-	for _, m := range c.upstreamServerMetrics {
-		ch <- m
-	}
-	for _, m := range c.streamServerZoneMetrics {
-		ch <- m
-	}
-	for _, m := range c.streamUpstreamMetrics {
-		ch <- m
-	}
-	for _, m := range c.streamUpstreamServerMetrics {
-		ch <- m
+	ch <- c.snapshotAgeMetric
+	if len(c.filters) > 0 {
+		ch <- c.filteredObjectsMetric
 	}
-	for _, m := range c.streamZoneSyncMetrics {
-		ch <- m
-	}
-	for _, m := range c.locationZoneMetrics {
-		ch <- m
-	}
-	for _, m := range c.resolverMetrics {
-		ch <- m
-	}
-	for _, m := range c.limitRequestMetrics {
-		ch <- m
+	ch <- c.labelStoreEntriesMetric
+
+	for name, m := range c.totalMetrics {
+		if c.sections[totalSection(name)] {
+			ch <- m
+		}
 	}
-	for _, m := range c.limitConnectionMetrics {
+	for _, m := range c.nginxMetrics {
 		ch <- m
 	}
-	for _, m := range c.streamLimitConnectionMetrics {
-		ch <- m
+	for _, section := range AllSections() {
+		if !c.sections[section] {
+			continue
+		}
+		for _, metrics := range c.sectionMetrics(section) {
+			for _, m := range metrics {
+				ch <- m
+			}
+		}
 	}
-	for _, m := range c.cacheZoneMetrics {
-		ch <- m
+}
+
+// totalSection returns the section of the total metric with the given name.
+func totalSection(name string) Section {
+	switch {
+	case strings.HasPrefix(name, "connections_"):
+		return SectionConnections
+	case strings.HasPrefix(name, "http_requests_"):
+		return SectionHTTPRequests
+	default:
+		return SectionSSL
 	}
-	for _, m := range c.workerMetrics {
-		ch <- m
+}
+
+// sectionMetrics returns the descriptors of the metrics of a section.
+func (c *NginxPlusCollector) sectionMetrics(section Section) []map[string]*prometheus.Desc {
+	switch section {
+	case SectionServerZones:
+		return []map[string]*prometheus.Desc{c.serverZoneMetrics}
+	case SectionUpstreams:
+		return []map[string]*prometheus.Desc{c.upstreamMetrics, c.upstreamServerMetrics}
+	case SectionStreamServerZones:
+		return []map[string]*prometheus.Desc{c.streamServerZoneMetrics}
+	case SectionStreamUpstreams:
+		return []map[string]*prometheus.Desc{c.streamUpstreamMetrics, c.streamUpstreamServerMetrics}
+	case SectionStreamZoneSync:
+		return []map[string]*prometheus.Desc{c.streamZoneSyncMetrics}
+	case SectionLocationZones:
+		return []map[string]*prometheus.Desc{c.locationZoneMetrics}
+	case SectionResolvers:
+		return []map[string]*prometheus.Desc{c.resolverMetrics}
+	case SectionLimitRequests:
+		return []map[string]*prometheus.Desc{c.limitRequestMetrics}
+	case SectionLimitConnections:
+		return []map[string]*prometheus.Desc{c.limitConnectionMetrics}
+	case SectionStreamLimitConnections:
+		return []map[string]*prometheus.Desc{c.streamLimitConnectionMetrics}
+	case SectionCaches:
+		return []map[string]*prometheus.Desc{c.cacheZoneMetrics}
+	case SectionWorkers:
+		return []map[string]*prometheus.Desc{c.workerMetrics}
+	case SectionSlabs:
+		return []map[string]*prometheus.Desc{c.slabMetrics}
+	case SectionLicense:
+		return []map[string]*prometheus.Desc{c.licenseMetrics}
+	case SectionKeyVals:
+		return []map[string]*prometheus.Desc{c.keyValMetrics}
 	}
+	return nil
+}
+
+// StartPolling makes the collector fetch the stats from NGINX Plus every interval in the background
+// until ctx is done. Scrapes are then served from the latest stats, which are reported as down once
+// they are older than staleness, or three intervals if staleness is zero.
+// It must be called before the collector is registered.
+func (c *NginxPlusCollector) StartPolling(ctx context.Context, interval, staleness time.Duration) {
+	c.poller = newPoller(c.fetchStats, interval, staleness, c.logger)
+	go c.poller.run(ctx)
 }
 
 // Collect fetches metrics from NGINX Plus and sends them to the provided channel.
 func (c *NginxPlusCollector) Collect(ch chan<- prometheus.Metric) {
+	_ = c.Scrape(context.Background(), ch)
+}
+
+// Scrape fetches metrics from NGINX Plus, sends them to the provided channel and
+// returns the error that prevented getting them, if any. The requests to NGINX Plus
+// are aborted when ctx is done.
+func (c *NginxPlusCollector) Scrape(ctx context.Context, ch chan<- prometheus.Metric) error {
 	c.mutex.Lock() // To protect metrics from concurrent collects
 	defer c.mutex.Unlock()
 
-	// FIXME: https://github.com/nginx/nginx-prometheus-exporter/issues/858
-	stats, err := c.nginxClient.GetStats(context.TODO())
+	var stats *plusStats
+	var err error
+	if c.poller != nil {
+		stats, err = c.poller.collect(ch, c.snapshotAgeMetric)
+	} else {
+		stats, err = c.fetchStats(ctx)
+	}
 	if err != nil {
 		c.upMetric.Set(nginxDown)
 		ch <- c.upMetric
-		c.logger.Warn("error getting stats", "error", err.Error())
-		return
+		if ctx.Err() != nil {
+			c.logger.Debug("scrape cancelled", "error", err.Error())
+		} else {
+			c.logger.Warn("error getting stats", "error", err.Error())
+		}
+		return fmt.Errorf("error getting stats: %w", err)
 	}
 
 	c.upMetric.Set(nginxUp)
 	ch <- c.upMetric
 
-	ch <- prometheus.MustNewConstMetric(c.totalMetrics["connections_accepted"],
-		prometheus.CounterValue, float64(stats.Connections.Accepted))
-	ch <- prometheus.MustNewConstMetric(c.totalMetrics["connections_dropped"],
-		prometheus.CounterValue, float64(stats.Connections.Dropped))
-	ch <- prometheus.MustNewConstMetric(c.totalMetrics["connections_active"],
-		prometheus.GaugeValue, float64(stats.Connections.Active))
-	ch <- prometheus.MustNewConstMetric(c.totalMetrics["connections_idle"],
-		prometheus.GaugeValue, float64(stats.Connections.Idle))
-	ch <- prometheus.MustNewConstMetric(c.totalMetrics["http_requests_total"],
-		prometheus.CounterValue, float64(stats.HTTPRequests.Total))
-	ch <- prometheus.MustNewConstMetric(c.totalMetrics["http_requests_current"],
-		prometheus.GaugeValue, float64(stats.HTTPRequests.Current))
-	ch <- prometheus.MustNewConstMetric(c.totalMetrics["ssl_handshakes"],
-		prometheus.CounterValue, float64(stats.SSL.Handshakes))
-	ch <- prometheus.MustNewConstMetric(c.totalMetrics["ssl_handshakes_failed"],
-		prometheus.CounterValue, float64(stats.SSL.HandshakesFailed))
-	ch <- prometheus.MustNewConstMetric(c.totalMetrics["ssl_session_reuses"],
-		prometheus.CounterValue, float64(stats.SSL.SessionReuses))
+	filter := newObjectFilter(c.filters)
+	// all the metrics of a scrape take their labels from the same version of the store
+	labels := newScrapeLabels(c.labels.snapshot())
+
+	if c.sections[SectionConnections] {
+		ch <- prometheus.MustNewConstMetric(c.totalMetrics["connections_accepted"],
+			prometheus.CounterValue, float64(stats.Connections.Accepted))
+		ch <- prometheus.MustNewConstMetric(c.totalMetrics["connections_dropped"],
+			prometheus.CounterValue, float64(stats.Connections.Dropped))
+		ch <- prometheus.MustNewConstMetric(c.totalMetrics["connections_active"],
+			prometheus.GaugeValue, float64(stats.Connections.Active))
+		ch <- prometheus.MustNewConstMetric(c.totalMetrics["connections_idle"],
+			prometheus.GaugeValue, float64(stats.Connections.Idle))
+	}
+	if c.sections[SectionHTTPRequests] {
+		ch <- prometheus.MustNewConstMetric(c.totalMetrics["http_requests_total"],
+			prometheus.CounterValue, float64(stats.HTTPRequests.Total))
+		ch <- prometheus.MustNewConstMetric(c.totalMetrics["http_requests_current"],
+			prometheus.GaugeValue, float64(stats.HTTPRequests.Current))
+	}
+	if c.sections[SectionSSL] {
+		ch <- prometheus.MustNewConstMetric(c.totalMetrics["ssl_handshakes"],
+			prometheus.CounterValue, float64(stats.SSL.Handshakes))
+		ch <- prometheus.MustNewConstMetric(c.totalMetrics["ssl_handshakes_failed"],
+			prometheus.CounterValue, float64(stats.SSL.HandshakesFailed))
+		ch <- prometheus.MustNewConstMetric(c.totalMetrics["ssl_session_reuses"],
+			prometheus.CounterValue, float64(stats.SSL.SessionReuses))
+		c.collectSSLFailures(ch, c.totalMetrics, stats.SSL, nil)
+	}
 
-	for name, zone := range stats.ServerZones {
-		labelValues := []string{name}
-		varLabelValues := c.getServerZoneLabelValues(name)
+	ch <- prometheus.MustNewConstMetric(c.nginxMetrics["info"],
+		prometheus.GaugeValue, 1, stats.NginxInfo.Version, stats.NginxInfo.Build)
+	ch <- prometheus.MustNewConstMetric(c.nginxMetrics["config_generation"],
+		prometheus.CounterValue, float64(stats.NginxInfo.Generation))
+	if reload, err := time.Parse(time.RFC3339, stats.NginxInfo.LoadTimestamp); err == nil {
+		ch <- prometheus.MustNewConstMetric(c.nginxMetrics["config_reload_timestamp"],
+			prometheus.GaugeValue, float64(reload.UnixMilli())/1000)
+	}
+	ch <- prometheus.MustNewConstMetric(c.nginxMetrics["master_pid"],
+		prometheus.GaugeValue, float64(stats.NginxInfo.ProcessID))
+	ch <- prometheus.MustNewConstMetric(c.nginxMetrics["clock_skew"],
+		prometheus.GaugeValue, stats.clockSkew.Seconds())
 
-		if c.variableLabelNames.ServerZoneVariableLabelNames != nil && len(varLabelValues) != len(c.variableLabelNames.ServerZoneVariableLabelNames) {
-			c.logger.Warn("wrong number of labels for http zone, empty labels will be used instead", "zone", name, "expected", len(c.variableLabelNames.ServerZoneVariableLabelNames), "got", len(varLabelValues))
-			for range c.variableLabelNames.ServerZoneVariableLabelNames {
-				labelValues = append(labelValues, "")
-			}
-		} else {
-			labelValues = append(labelValues, varLabelValues...)
+	for name, zone := range stats.ServerZones {
+		if !filter.keep(FamilyServerZone, name) {
+			continue
 		}
+		labelValues := []string{name}
+		labelValues = append(labelValues, c.variableLabelValues(labels, LabelKindServerZone, name)...)
 
 		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["processing"],
 			prometheus.GaugeValue, float64(zone.Processing), labelValues...)
@@ -687,106 +803,22 @@ func (c *NginxPlusCollector) Collect(ch chan<- prometheus.Metric) {
 			prometheus.CounterValue, float64(zone.Received), labelValues...)
 		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["sent"],
 			prometheus.CounterValue, float64(zone.Sent), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_100"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPContinue), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_101"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPSwitchingProtocols), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_102"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPProcessing), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_200"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPOk), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_201"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPCreated), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_202"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPAccepted), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_204"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPNoContent), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_206"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPPartialContent), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_300"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPSpecialResponse), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_301"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPMovedPermanently), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_302"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPMovedTemporarily), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_303"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPSeeOther), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_304"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPNotModified), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_307"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPTemporaryRedirect), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_400"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPBadRequest), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_401"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPUnauthorized), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_403"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPForbidden), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_404"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPNotFound), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_405"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPNotAllowed), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_408"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPRequestTimeOut), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_409"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPConflict), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_411"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPLengthRequired), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_412"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPPreconditionFailed), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_413"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPRequestEntityTooLarge), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_414"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPRequestURITooLarge), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_415"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPUnsupportedMediaType), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_416"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPRangeNotSatisfiable), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_429"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPTooManyRequests), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_444"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPClose), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_494"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPRequestHeaderTooLarge), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_495"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPSCertError), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_496"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPSNoCert), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_497"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPToHTTPS), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_499"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPClientClosedRequest), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_500"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPInternalServerError), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_501"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPNotImplemented), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_502"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPBadGateway), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_503"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPServiceUnavailable), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_504"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPGatewayTimeOut), labelValues...)
-		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["codes_507"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPInsufficientStorage), labelValues...)
+		c.collectResponseCodes(ch, c.serverZoneMetrics["responses_codes"], FamilyServerZone, stats.codes.serverZones[name], labelValues)
 		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["ssl_handshakes"],
 			prometheus.CounterValue, float64(zone.SSL.Handshakes), labelValues...)
 		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["ssl_handshakes_failed"],
 			prometheus.CounterValue, float64(zone.SSL.HandshakesFailed), labelValues...)
 		ch <- prometheus.MustNewConstMetric(c.serverZoneMetrics["ssl_session_reuses"],
 			prometheus.CounterValue, float64(zone.SSL.SessionReuses), labelValues...)
+		c.collectSSLFailures(ch, c.serverZoneMetrics, zone.SSL, labelValues)
 	}
 
 	for name, zone := range stats.StreamServerZones {
-		labelValues := []string{name}
-		varLabelValues := c.getStreamServerZoneLabelValues(name)
-
-		if c.variableLabelNames.StreamServerZoneVariableLabelNames != nil && len(varLabelValues) != len(c.variableLabelNames.StreamServerZoneVariableLabelNames) {
-			c.logger.Warn("wrong number of labels for stream server zone, empty labels will be used instead", "zone", name, "expected", len(c.variableLabelNames.StreamServerZoneVariableLabelNames), "got", len(varLabelValues))
-			for range c.variableLabelNames.StreamServerZoneVariableLabelNames {
-				labelValues = append(labelValues, "")
-			}
-		} else {
-			labelValues = append(labelValues, varLabelValues...)
+		if !filter.keep(FamilyStreamServerZone, name) {
+			continue
 		}
+		labelValues := []string{name}
+		labelValues = append(labelValues, c.variableLabelValues(labels, LabelKindStreamServerZone, name)...)
 		ch <- prometheus.MustNewConstMetric(c.streamServerZoneMetrics["processing"],
 			prometheus.GaugeValue, float64(zone.Processing), labelValues...)
 		ch <- prometheus.MustNewConstMetric(c.streamServerZoneMetrics["connections"],
@@ -809,32 +841,17 @@ func (c *NginxPlusCollector) Collect(ch chan<- prometheus.Metric) {
 			prometheus.CounterValue, float64(zone.SSL.HandshakesFailed), labelValues...)
 		ch <- prometheus.MustNewConstMetric(c.streamServerZoneMetrics["ssl_session_reuses"],
 			prometheus.CounterValue, float64(zone.SSL.SessionReuses), labelValues...)
+		c.collectSSLFailures(ch, c.streamServerZoneMetrics, zone.SSL, labelValues)
 	}
 
 	for name, upstream := range stats.Upstreams {
+		if !filter.keep(FamilyUpstream, name) {
+			continue
+		}
 		for _, peer := range upstream.Peers {
 			labelValues := []string{name, peer.Server}
-			varLabelValues := c.getUpstreamServerLabelValues(name)
-
-			if c.variableLabelNames.UpstreamServerVariableLabelNames != nil && len(varLabelValues) != len(c.variableLabelNames.UpstreamServerVariableLabelNames) {
-				c.logger.Warn("wrong number of labels for upstream, empty labels will be used instead", "upstream", name, "expected", len(c.variableLabelNames.UpstreamServerVariableLabelNames), "got", len(varLabelValues))
-				for range c.variableLabelNames.UpstreamServerVariableLabelNames {
-					labelValues = append(labelValues, "")
-				}
-			} else {
-				labelValues = append(labelValues, varLabelValues...)
-			}
-
-			upstreamServer := fmt.Sprintf("%v/%v", name, peer.Server)
-			varPeerLabelValues := c.getUpstreamServerPeerLabelValues(upstreamServer)
-			if c.variableLabelNames.UpstreamServerPeerVariableLabelNames != nil && len(varPeerLabelValues) != len(c.variableLabelNames.UpstreamServerPeerVariableLabelNames) {
-				c.logger.Warn("wrong number of labels for upstream peer, empty labels will be used instead", "upstream", name, "peer", peer.Server, "expected", len(c.variableLabelNames.UpstreamServerPeerVariableLabelNames), "got", len(varPeerLabelValues))
-				for range c.variableLabelNames.UpstreamServerPeerVariableLabelNames {
-					labelValues = append(labelValues, "")
-				}
-			} else {
-				labelValues = append(labelValues, varPeerLabelValues...)
-			}
+			labelValues = append(labelValues, c.variableLabelValues(labels, LabelKindUpstream, name)...)
+			labelValues = append(labelValues, c.variableLabelValues(labels, LabelKindUpstreamPeer, fmt.Sprintf("%v/%v", name, peer.Server))...)
 
 			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["state"],
 				prometheus.GaugeValue, upstreamServerStates[peer.State], labelValues...)
@@ -875,123 +892,50 @@ func (c *NginxPlusCollector) Collect(ch chan<- prometheus.Metric) {
 				ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["health_checks_unhealthy"],
 					prometheus.CounterValue, float64(peer.HealthChecks.Unhealthy), labelValues...)
 			}
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_100"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPContinue), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_101"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPSwitchingProtocols), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_102"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPProcessing), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_200"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPOk), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_201"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPCreated), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_202"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPAccepted), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_204"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPNoContent), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_206"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPPartialContent), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_300"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPSpecialResponse), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_301"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPMovedPermanently), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_302"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPMovedTemporarily), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_303"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPSeeOther), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_304"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPNotModified), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_307"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPTemporaryRedirect), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_400"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPBadRequest), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_401"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPUnauthorized), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_403"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPForbidden), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_404"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPNotFound), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_405"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPNotAllowed), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_408"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPRequestTimeOut), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_409"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPConflict), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_411"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPLengthRequired), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_412"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPPreconditionFailed), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_413"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPRequestEntityTooLarge), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_414"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPRequestURITooLarge), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_415"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPUnsupportedMediaType), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_416"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPRangeNotSatisfiable), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_429"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPTooManyRequests), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_444"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPClose), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_494"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPRequestHeaderTooLarge), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_495"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPSCertError), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_496"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPSNoCert), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_497"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPToHTTPS), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_499"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPClientClosedRequest), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_500"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPInternalServerError), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_501"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPNotImplemented), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_502"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPBadGateway), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_503"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPServiceUnavailable), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_504"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPGatewayTimeOut), labelValues...)
-			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["codes_507"],
-				prometheus.CounterValue, float64(peer.Responses.Codes.HTTPInsufficientStorage), labelValues...)
+			c.collectResponseCodes(ch, c.upstreamServerMetrics["responses_codes"], FamilyUpstream, stats.codes.peer(name, peer.ID), labelValues)
 			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["ssl_handshakes"],
 				prometheus.CounterValue, float64(peer.SSL.Handshakes), labelValues...)
 			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["ssl_handshakes_failed"],
 				prometheus.CounterValue, float64(peer.SSL.HandshakesFailed), labelValues...)
 			ch <- prometheus.MustNewConstMetric(c.upstreamServerMetrics["ssl_session_reuses"],
 				prometheus.CounterValue, float64(peer.SSL.SessionReuses), labelValues...)
+			c.collectSSLFailures(ch, c.upstreamServerMetrics, peer.SSL, labelValues)
+			collectPeerAttributes(ch, c.upstreamServerMetrics, peerAttributes{
+				upstream:     name,
+				server:       peer.Server,
+				name:         peer.Name,
+				service:      peer.Service,
+				selected:     peer.Selected,
+				downstart:    peer.Downstart,
+				healthChecks: peer.HealthChecks,
+				downtime:     peer.Downtime,
+				weight:       peer.Weight,
+				backup:       peer.Backup,
+			}, labelValues)
 		}
 		ch <- prometheus.MustNewConstMetric(c.upstreamMetrics["keepalive"],
 			prometheus.GaugeValue, float64(upstream.Keepalive), name)
 		ch <- prometheus.MustNewConstMetric(c.upstreamMetrics["zombies"],
 			prometheus.GaugeValue, float64(upstream.Zombies), name)
+		// the queue is only reported for the upstreams that have one
+		if upstream.Queue != (plusclient.Queue{}) {
+			ch <- prometheus.MustNewConstMetric(c.upstreamMetrics["queue_size"],
+				prometheus.GaugeValue, float64(upstream.Queue.Size), name)
+			ch <- prometheus.MustNewConstMetric(c.upstreamMetrics["queue_max_size"],
+				prometheus.GaugeValue, float64(upstream.Queue.MaxSize), name)
+			ch <- prometheus.MustNewConstMetric(c.upstreamMetrics["queue_overflows"],
+				prometheus.CounterValue, float64(upstream.Queue.Overflows), name)
+		}
 	}
 
 	for name, upstream := range stats.StreamUpstreams {
+		if !filter.keep(FamilyStreamUpstream, name) {
+			continue
+		}
 		for _, peer := range upstream.Peers {
 			labelValues := []string{name, peer.Server}
-			varLabelValues := c.getStreamUpstreamServerLabelValues(name)
-
-			if c.variableLabelNames.StreamUpstreamServerVariableLabelNames != nil && len(varLabelValues) != len(c.variableLabelNames.StreamUpstreamServerVariableLabelNames) {
-				c.logger.Warn("wrong number of labels for stream server, empty labels will be used instead", "server", name, "labels", c.variableLabelNames.StreamUpstreamServerVariableLabelNames, "values", varLabelValues)
-				for range c.variableLabelNames.StreamUpstreamServerVariableLabelNames {
-					labelValues = append(labelValues, "")
-				}
-			} else {
-				labelValues = append(labelValues, varLabelValues...)
-			}
-
-			upstreamServer := fmt.Sprintf("%v/%v", name, peer.Server)
-			varPeerLabelValues := c.getStreamUpstreamServerPeerLabelValues(upstreamServer)
-			if c.variableLabelNames.StreamUpstreamServerPeerVariableLabelNames != nil && len(varPeerLabelValues) != len(c.variableLabelNames.StreamUpstreamServerPeerVariableLabelNames) {
-				c.logger.Warn("wrong number of labels for stream upstream peer, empty labels will be used instead", "server", upstreamServer, "labels", c.variableLabelNames.StreamUpstreamServerPeerVariableLabelNames, "values", varPeerLabelValues)
-				for range c.variableLabelNames.StreamUpstreamServerPeerVariableLabelNames {
-					labelValues = append(labelValues, "")
-				}
-			} else {
-				labelValues = append(labelValues, varPeerLabelValues...)
-			}
+			labelValues = append(labelValues, c.variableLabelValues(labels, LabelKindStreamUpstream, name)...)
+			labelValues = append(labelValues, c.variableLabelValues(labels, LabelKindStreamUpstreamPeer, fmt.Sprintf("%v/%v", name, peer.Server))...)
 
 			ch <- prometheus.MustNewConstMetric(c.streamUpstreamServerMetrics["state"],
 				prometheus.GaugeValue, upstreamServerStates[peer.State], labelValues...)
@@ -1029,6 +973,18 @@ func (c *NginxPlusCollector) Collect(ch chan<- prometheus.Metric) {
 				prometheus.CounterValue, float64(peer.SSL.HandshakesFailed), labelValues...)
 			ch <- prometheus.MustNewConstMetric(c.streamUpstreamServerMetrics["ssl_session_reuses"],
 				prometheus.CounterValue, float64(peer.SSL.SessionReuses), labelValues...)
+			collectPeerAttributes(ch, c.streamUpstreamServerMetrics, peerAttributes{
+				upstream:     name,
+				server:       peer.Server,
+				name:         peer.Name,
+				service:      peer.Service,
+				selected:     peer.Selected,
+				downstart:    peer.Downstart,
+				healthChecks: peer.HealthChecks,
+				downtime:     peer.Downtime,
+				weight:       peer.Weight,
+				backup:       peer.Backup,
+			}, labelValues)
 		}
 		ch <- prometheus.MustNewConstMetric(c.streamUpstreamMetrics["zombies"],
 			prometheus.GaugeValue, float64(upstream.Zombies), name)
@@ -1036,10 +992,13 @@ func (c *NginxPlusCollector) Collect(ch chan<- prometheus.Metric) {
 
 	if stats.StreamZoneSync != nil {
 		for name, zone := range stats.StreamZoneSync.Zones {
+			labelValues := []string{name}
+			labelValues = append(labelValues, c.variableLabelValues(labels, LabelKindStreamZoneSyncZone, name)...)
+
 			ch <- prometheus.MustNewConstMetric(c.streamZoneSyncMetrics["records_pending"],
-				prometheus.GaugeValue, float64(zone.RecordsPending), name)
+				prometheus.GaugeValue, float64(zone.RecordsPending), labelValues...)
 			ch <- prometheus.MustNewConstMetric(c.streamZoneSyncMetrics["records_total"],
-				prometheus.GaugeValue, float64(zone.RecordsTotal), name)
+				prometheus.GaugeValue, float64(zone.RecordsTotal), labelValues...)
 		}
 
 		ch <- prometheus.MustNewConstMetric(c.streamZoneSyncMetrics["bytes_in"],
@@ -1055,163 +1014,108 @@ func (c *NginxPlusCollector) Collect(ch chan<- prometheus.Metric) {
 	}
 
 	for name, zone := range stats.LocationZones {
+		if !filter.keep(FamilyLocationZone, name) {
+			continue
+		}
+		labelValues := []string{name}
+		labelValues = append(labelValues, c.variableLabelValues(labels, LabelKindLocationZone, name)...)
+
 		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["requests"],
-			prometheus.CounterValue, float64(zone.Requests), name)
+			prometheus.CounterValue, float64(zone.Requests), labelValues...)
 		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["responses_1xx"],
-			prometheus.CounterValue, float64(zone.Responses.Responses1xx), name)
+			prometheus.CounterValue, float64(zone.Responses.Responses1xx), labelValues...)
 		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["responses_2xx"],
-			prometheus.CounterValue, float64(zone.Responses.Responses2xx), name)
+			prometheus.CounterValue, float64(zone.Responses.Responses2xx), labelValues...)
 		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["responses_3xx"],
-			prometheus.CounterValue, float64(zone.Responses.Responses3xx), name)
+			prometheus.CounterValue, float64(zone.Responses.Responses3xx), labelValues...)
 		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["responses_4xx"],
-			prometheus.CounterValue, float64(zone.Responses.Responses4xx), name)
+			prometheus.CounterValue, float64(zone.Responses.Responses4xx), labelValues...)
 		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["responses_5xx"],
-			prometheus.CounterValue, float64(zone.Responses.Responses5xx), name)
+			prometheus.CounterValue, float64(zone.Responses.Responses5xx), labelValues...)
 		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["discarded"],
-			prometheus.CounterValue, float64(zone.Discarded), name)
+			prometheus.CounterValue, float64(zone.Discarded), labelValues...)
 		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["received"],
-			prometheus.CounterValue, float64(zone.Received), name)
+			prometheus.CounterValue, float64(zone.Received), labelValues...)
 		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["sent"],
-			prometheus.CounterValue, float64(zone.Sent), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_100"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPContinue), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_101"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPSwitchingProtocols), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_102"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPProcessing), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_200"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPOk), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_201"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPCreated), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_202"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPAccepted), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_204"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPNoContent), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_206"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPPartialContent), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_300"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPSpecialResponse), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_301"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPMovedPermanently), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_302"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPMovedTemporarily), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_303"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPSeeOther), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_304"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPNotModified), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_307"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPTemporaryRedirect), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_400"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPBadRequest), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_401"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPUnauthorized), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_403"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPForbidden), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_404"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPNotFound), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_405"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPNotAllowed), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_408"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPRequestTimeOut), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_409"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPConflict), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_411"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPLengthRequired), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_412"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPPreconditionFailed), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_413"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPRequestEntityTooLarge), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_414"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPRequestURITooLarge), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_415"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPUnsupportedMediaType), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_416"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPRangeNotSatisfiable), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_429"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPTooManyRequests), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_444"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPClose), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_494"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPRequestHeaderTooLarge), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_495"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPSCertError), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_496"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPSNoCert), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_497"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPToHTTPS), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_499"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPClientClosedRequest), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_500"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPInternalServerError), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_501"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPNotImplemented), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_502"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPBadGateway), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_503"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPServiceUnavailable), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_504"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPGatewayTimeOut), name)
-		ch <- prometheus.MustNewConstMetric(c.locationZoneMetrics["codes_507"],
-			prometheus.CounterValue, float64(zone.Responses.Codes.HTTPInsufficientStorage), name)
+			prometheus.CounterValue, float64(zone.Sent), labelValues...)
+		c.collectResponseCodes(ch, c.locationZoneMetrics["responses_codes"], FamilyLocationZone, stats.codes.locationZones[name], labelValues)
 	}
 
 	for name, zone := range stats.Resolvers {
+		if !filter.keep(FamilyResolver, name) {
+			continue
+		}
+		labelValues := []string{name}
+		labelValues = append(labelValues, c.variableLabelValues(labels, LabelKindResolver, name)...)
+
 		ch <- prometheus.MustNewConstMetric(c.resolverMetrics["name"],
-			prometheus.CounterValue, float64(zone.Requests.Name), name)
+			prometheus.CounterValue, float64(zone.Requests.Name), labelValues...)
 		ch <- prometheus.MustNewConstMetric(c.resolverMetrics["srv"],
-			prometheus.CounterValue, float64(zone.Requests.Srv), name)
+			prometheus.CounterValue, float64(zone.Requests.Srv), labelValues...)
 		ch <- prometheus.MustNewConstMetric(c.resolverMetrics["addr"],
-			prometheus.CounterValue, float64(zone.Requests.Addr), name)
+			prometheus.CounterValue, float64(zone.Requests.Addr), labelValues...)
 		ch <- prometheus.MustNewConstMetric(c.resolverMetrics["noerror"],
-			prometheus.CounterValue, float64(zone.Responses.Noerror), name)
+			prometheus.CounterValue, float64(zone.Responses.Noerror), labelValues...)
 		ch <- prometheus.MustNewConstMetric(c.resolverMetrics["formerr"],
-			prometheus.CounterValue, float64(zone.Responses.Formerr), name)
+			prometheus.CounterValue, float64(zone.Responses.Formerr), labelValues...)
 		ch <- prometheus.MustNewConstMetric(c.resolverMetrics["servfail"],
-			prometheus.CounterValue, float64(zone.Responses.Servfail), name)
+			prometheus.CounterValue, float64(zone.Responses.Servfail), labelValues...)
 		ch <- prometheus.MustNewConstMetric(c.resolverMetrics["nxdomain"],
-			prometheus.CounterValue, float64(zone.Responses.Nxdomain), name)
+			prometheus.CounterValue, float64(zone.Responses.Nxdomain), labelValues...)
 		ch <- prometheus.MustNewConstMetric(c.resolverMetrics["notimp"],
-			prometheus.CounterValue, float64(zone.Responses.Notimp), name)
+			prometheus.CounterValue, float64(zone.Responses.Notimp), labelValues...)
 		ch <- prometheus.MustNewConstMetric(c.resolverMetrics["refused"],
-			prometheus.CounterValue, float64(zone.Responses.Refused), name)
+			prometheus.CounterValue, float64(zone.Responses.Refused), labelValues...)
 		ch <- prometheus.MustNewConstMetric(c.resolverMetrics["timedout"],
-			prometheus.CounterValue, float64(zone.Responses.Timedout), name)
+			prometheus.CounterValue, float64(zone.Responses.Timedout), labelValues...)
 		ch <- prometheus.MustNewConstMetric(c.resolverMetrics["unknown"],
-			prometheus.CounterValue, float64(zone.Responses.Unknown), name)
+			prometheus.CounterValue, float64(zone.Responses.Unknown), labelValues...)
 	}
 
 	for name, zone := range stats.HTTPLimitRequests {
-		ch <- prometheus.MustNewConstMetric(c.limitRequestMetrics["passed"], prometheus.CounterValue, float64(zone.Passed), name)
-		ch <- prometheus.MustNewConstMetric(c.limitRequestMetrics["rejected"], prometheus.CounterValue, float64(zone.Rejected), name)
-		ch <- prometheus.MustNewConstMetric(c.limitRequestMetrics["delayed"], prometheus.CounterValue, float64(zone.Delayed), name)
-		ch <- prometheus.MustNewConstMetric(c.limitRequestMetrics["rejected_dry_run"], prometheus.CounterValue, float64(zone.RejectedDryRun), name)
-		ch <- prometheus.MustNewConstMetric(c.limitRequestMetrics["delayed_dry_run"], prometheus.CounterValue, float64(zone.DelayedDryRun), name)
+		if !filter.keep(FamilyLimitRequest, name) {
+			continue
+		}
+		labelValues := []string{name}
+		labelValues = append(labelValues, c.variableLabelValues(labels, LabelKindLimitRequest, name)...)
+
+		ch <- prometheus.MustNewConstMetric(c.limitRequestMetrics["passed"], prometheus.CounterValue, float64(zone.Passed), labelValues...)
+		ch <- prometheus.MustNewConstMetric(c.limitRequestMetrics["rejected"], prometheus.CounterValue, float64(zone.Rejected), labelValues...)
+		ch <- prometheus.MustNewConstMetric(c.limitRequestMetrics["delayed"], prometheus.CounterValue, float64(zone.Delayed), labelValues...)
+		ch <- prometheus.MustNewConstMetric(c.limitRequestMetrics["rejected_dry_run"], prometheus.CounterValue, float64(zone.RejectedDryRun), labelValues...)
+		ch <- prometheus.MustNewConstMetric(c.limitRequestMetrics["delayed_dry_run"], prometheus.CounterValue, float64(zone.DelayedDryRun), labelValues...)
 	}
 
 	for name, zone := range stats.HTTPLimitConnections {
-		ch <- prometheus.MustNewConstMetric(c.limitConnectionMetrics["passed"], prometheus.CounterValue, float64(zone.Passed), name)
-		ch <- prometheus.MustNewConstMetric(c.limitConnectionMetrics["rejected"], prometheus.CounterValue, float64(zone.Rejected), name)
-		ch <- prometheus.MustNewConstMetric(c.limitConnectionMetrics["rejected_dry_run"], prometheus.CounterValue, float64(zone.RejectedDryRun), name)
+		if !filter.keep(FamilyLimitConnection, name) {
+			continue
+		}
+		labelValues := []string{name}
+		labelValues = append(labelValues, c.variableLabelValues(labels, LabelKindLimitConnection, name)...)
+
+		ch <- prometheus.MustNewConstMetric(c.limitConnectionMetrics["passed"], prometheus.CounterValue, float64(zone.Passed), labelValues...)
+		ch <- prometheus.MustNewConstMetric(c.limitConnectionMetrics["rejected"], prometheus.CounterValue, float64(zone.Rejected), labelValues...)
+		ch <- prometheus.MustNewConstMetric(c.limitConnectionMetrics["rejected_dry_run"], prometheus.CounterValue, float64(zone.RejectedDryRun), labelValues...)
 	}
 
 	for name, zone := range stats.StreamLimitConnections {
-		ch <- prometheus.MustNewConstMetric(c.streamLimitConnectionMetrics["passed"], prometheus.CounterValue, float64(zone.Passed), name)
-		ch <- prometheus.MustNewConstMetric(c.streamLimitConnectionMetrics["rejected"], prometheus.CounterValue, float64(zone.Rejected), name)
-		ch <- prometheus.MustNewConstMetric(c.streamLimitConnectionMetrics["rejected_dry_run"], prometheus.CounterValue, float64(zone.RejectedDryRun), name)
+		if !filter.keep(FamilyStreamLimitConnection, name) {
+			continue
+		}
+		labelValues := []string{name}
+		labelValues = append(labelValues, c.variableLabelValues(labels, LabelKindStreamLimitConnection, name)...)
+
+		ch <- prometheus.MustNewConstMetric(c.streamLimitConnectionMetrics["passed"], prometheus.CounterValue, float64(zone.Passed), labelValues...)
+		ch <- prometheus.MustNewConstMetric(c.streamLimitConnectionMetrics["rejected"], prometheus.CounterValue, float64(zone.Rejected), labelValues...)
+		ch <- prometheus.MustNewConstMetric(c.streamLimitConnectionMetrics["rejected_dry_run"], prometheus.CounterValue, float64(zone.RejectedDryRun), labelValues...)
 	}
 
 	for name, zone := range stats.Caches {
-		labelValues := []string{name}
-		varLabelValues := c.getCacheZoneLabelValues(name)
-
-		if c.variableLabelNames.CacheZoneVariableLabelNames != nil && len(varLabelValues) != len(c.variableLabelNames.CacheZoneVariableLabelNames) {
-			c.logger.Warn("wrong number of labels for cache zone, empty labels will be used instead", "zone", name, "labels", c.variableLabelNames.CacheZoneVariableLabelNames, "values", varLabelValues)
-			for range c.variableLabelNames.CacheZoneVariableLabelNames {
-				labelValues = append(labelValues, "")
-			}
-		} else {
-			labelValues = append(labelValues, varLabelValues...)
+		if !filter.keep(FamilyCache, name) {
+			continue
 		}
+		labelValues := []string{name}
+		labelValues = append(labelValues, c.variableLabelValues(labels, LabelKindCacheZone, name)...)
 
 		ch <- prometheus.MustNewConstMetric(c.cacheZoneMetrics["size"], prometheus.GaugeValue, float64(zone.Size), labelValues...)
 		ch <- prometheus.MustNewConstMetric(c.cacheZoneMetrics["max_size"], prometheus.GaugeValue, float64(zone.MaxSize), labelValues...)
@@ -1238,14 +1142,39 @@ func (c *NginxPlusCollector) Collect(ch chan<- prometheus.Metric) {
 
 	for id, worker := range stats.Workers {
 		workerID := strconv.FormatInt(int64(id), 10)
-		workerPID := strconv.FormatUint(worker.ProcessID, 10)
-		ch <- prometheus.MustNewConstMetric(c.workerMetrics["connection_accepted"], prometheus.CounterValue, float64(worker.Connections.Accepted), workerID, workerPID)
-		ch <- prometheus.MustNewConstMetric(c.workerMetrics["connection_dropped"], prometheus.CounterValue, float64(worker.Connections.Dropped), workerID, workerPID)
-		ch <- prometheus.MustNewConstMetric(c.workerMetrics["connection_active"], prometheus.GaugeValue, float64(worker.Connections.Active), workerID, workerPID)
-		ch <- prometheus.MustNewConstMetric(c.workerMetrics["connection_idle"], prometheus.GaugeValue, float64(worker.Connections.Idle), workerID, workerPID)
-		ch <- prometheus.MustNewConstMetric(c.workerMetrics["http_requests_total"], prometheus.CounterValue, float64(worker.HTTP.HTTPRequests.Total), workerID, workerPID)
-		ch <- prometheus.MustNewConstMetric(c.workerMetrics["http_requests_current"], prometheus.GaugeValue, float64(worker.HTTP.HTTPRequests.Current), workerID, workerPID)
+		ch <- prometheus.MustNewConstMetric(c.workerMetrics["connection_accepted"], prometheus.CounterValue, float64(worker.Connections.Accepted), workerID)
+		ch <- prometheus.MustNewConstMetric(c.workerMetrics["connection_dropped"], prometheus.CounterValue, float64(worker.Connections.Dropped), workerID)
+		ch <- prometheus.MustNewConstMetric(c.workerMetrics["connection_active"], prometheus.GaugeValue, float64(worker.Connections.Active), workerID)
+		ch <- prometheus.MustNewConstMetric(c.workerMetrics["connection_idle"], prometheus.GaugeValue, float64(worker.Connections.Idle), workerID)
+		ch <- prometheus.MustNewConstMetric(c.workerMetrics["http_requests_total"], prometheus.CounterValue, float64(worker.HTTP.HTTPRequests.Total), workerID)
+		ch <- prometheus.MustNewConstMetric(c.workerMetrics["http_requests_current"], prometheus.GaugeValue, float64(worker.HTTP.HTTPRequests.Current), workerID)
+	}
+	if c.sections[SectionWorkers] {
+		c.collectWorkerProcesses(ch, stats)
+	}
+
+	for name, slab := range stats.Slabs {
+		ch <- prometheus.MustNewConstMetric(c.slabMetrics["pages_used"], prometheus.GaugeValue, float64(slab.Pages.Used), name)
+		ch <- prometheus.MustNewConstMetric(c.slabMetrics["pages_free"], prometheus.GaugeValue, float64(slab.Pages.Free), name)
+		for size, slot := range slab.Slots {
+			ch <- prometheus.MustNewConstMetric(c.slabMetrics["slot_used"], prometheus.GaugeValue, float64(slot.Used), name, size)
+			ch <- prometheus.MustNewConstMetric(c.slabMetrics["slot_free"], prometheus.GaugeValue, float64(slot.Free), name, size)
+			ch <- prometheus.MustNewConstMetric(c.slabMetrics["slot_reqs"], prometheus.CounterValue, float64(slot.Reqs), name, size)
+			ch <- prometheus.MustNewConstMetric(c.slabMetrics["slot_fails"], prometheus.CounterValue, float64(slot.Fails), name, size)
+		}
+	}
+
+	if stats.license != nil {
+		c.collectLicense(ch, stats.license, stats.clockSkew)
+	}
+	if c.sections[SectionKeyVals] {
+		c.collectKeyVals(ch, stats)
 	}
+
+	filter.collect(ch, c.filteredObjectsMetric)
+	c.expireLabels(labels)
+	c.collectLabelStoreEntries(ch)
+	return nil
 }
 
 var upstreamServerStates = map[string]float64{
@@ -1298,28 +1227,40 @@ func newStreamZoneSyncMetric(namespace string, metricName string, docString stri
 	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "stream_zone_sync_status", metricName), docString, nil, constLabels)
 }
 
-func newStreamZoneSyncZoneMetric(namespace string, metricName string, docString string, constLabels prometheus.Labels) *prometheus.Desc {
-	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "stream_zone_sync_zone", metricName), docString, []string{"zone"}, constLabels)
+func newStreamZoneSyncZoneMetric(namespace string, metricName string, docString string, variableLabelNames []string, constLabels prometheus.Labels) *prometheus.Desc {
+	labels := []string{"zone"}
+	labels = append(labels, variableLabelNames...)
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "stream_zone_sync_zone", metricName), docString, labels, constLabels)
 }
 
-func newLocationZoneMetric(namespace string, metricName string, docString string, constLabels prometheus.Labels) *prometheus.Desc {
-	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "location_zone", metricName), docString, []string{"location_zone"}, constLabels)
+func newLocationZoneMetric(namespace string, metricName string, docString string, variableLabelNames []string, constLabels prometheus.Labels) *prometheus.Desc {
+	labels := []string{"location_zone"}
+	labels = append(labels, variableLabelNames...)
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "location_zone", metricName), docString, labels, constLabels)
 }
 
-func newResolverMetric(namespace string, metricName string, docString string, constLabels prometheus.Labels) *prometheus.Desc {
-	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "resolver", metricName), docString, []string{"resolver"}, constLabels)
+func newResolverMetric(namespace string, metricName string, docString string, variableLabelNames []string, constLabels prometheus.Labels) *prometheus.Desc {
+	labels := []string{"resolver"}
+	labels = append(labels, variableLabelNames...)
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "resolver", metricName), docString, labels, constLabels)
 }
 
-func newLimitRequestMetric(namespace string, metricName string, docString string, constLabels prometheus.Labels) *prometheus.Desc {
-	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "limit_request", metricName), docString, []string{"zone"}, constLabels)
+func newLimitRequestMetric(namespace string, metricName string, docString string, variableLabelNames []string, constLabels prometheus.Labels) *prometheus.Desc {
+	labels := []string{"zone"}
+	labels = append(labels, variableLabelNames...)
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "limit_request", metricName), docString, labels, constLabels)
 }
 
-func newLimitConnectionMetric(namespace string, metricName string, docString string, constLabels prometheus.Labels) *prometheus.Desc {
-	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "limit_connection", metricName), docString, []string{"zone"}, constLabels)
+func newLimitConnectionMetric(namespace string, metricName string, docString string, variableLabelNames []string, constLabels prometheus.Labels) *prometheus.Desc {
+	labels := []string{"zone"}
+	labels = append(labels, variableLabelNames...)
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "limit_connection", metricName), docString, labels, constLabels)
 }
 
-func newStreamLimitConnectionMetric(namespace string, metricName string, docString string, constLabels prometheus.Labels) *prometheus.Desc {
-	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "stream_limit_connection", metricName), docString, []string{"zone"}, constLabels)
+func newStreamLimitConnectionMetric(namespace string, metricName string, docString string, variableLabelNames []string, constLabels prometheus.Labels) *prometheus.Desc {
+	labels := []string{"zone"}
+	labels = append(labels, variableLabelNames...)
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "stream_limit_connection", metricName), docString, labels, constLabels)
 }
 
 func newCacheZoneMetric(namespace string, metricName string, docString string, variableLabelNames []string, constLabels prometheus.Labels) *prometheus.Desc {
@@ -1328,6 +1269,14 @@ func newCacheZoneMetric(namespace string, metricName string, docString string, v
 	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "cache", metricName), docString, labels, constLabels)
 }
 
+func newSlabMetric(namespace string, metricName string, docString string, labelNames []string, constLabels prometheus.Labels) *prometheus.Desc {
+	labels := []string{"zone"}
+	labels = append(labels, labelNames...)
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "slab", metricName), docString, labels, constLabels)
+}
+
+// newWorkerMetric returns a metric of the worker processes. The process ID is not a label, so that a restarted worker
+// keeps its series; the restarts are counted by the worker_restarts metric instead.
 func newWorkerMetric(namespace string, metricName string, docString string, constLabels prometheus.Labels) *prometheus.Desc {
-	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "worker", metricName), docString, []string{"id", "pid"}, constLabels)
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "worker", metricName), docString, []string{"id"}, constLabels)
 }
@@ -0,0 +1,294 @@
+package collector
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	plusclient "github.com/nginx/nginx-plus-go-client/v2/client"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNewVariableLabelNamesByKind(t *testing.T) {
+	t.Parallel()
+
+	for _, kind := range labelKinds {
+		c := NginxPlusCollector{variableLabelNames: NewVariableLabelNamesByKind(map[string][]string{kind: {"team"}, "unknown": {"tier"}})}
+		for _, other := range labelKinds {
+			var want []string
+			if other == kind {
+				want = []string{"team"}
+			}
+			if got := c.labelNames(other); !reflect.DeepEqual(got, want) {
+				t.Errorf("label names of %q = %v with the label names of %q, want %v", other, got, kind, want)
+			}
+		}
+	}
+}
+
+func TestNginxPlusCollectorVariableLabels(t *testing.T) {
+	t.Parallel()
+
+	labelNames := map[string][]string{
+		LabelKindLocationZone:          {"team"},
+		LabelKindResolver:              {"team"},
+		LabelKindLimitRequest:          {"team"},
+		LabelKindLimitConnection:       {"team"},
+		LabelKindStreamLimitConnection: {"team"},
+		LabelKindStreamZoneSyncZone:    {"team"},
+	}
+	c := newTestPlusCollectorWithLabels(t, map[string]string{
+		"/9/http/location_zones": `{"checkout": {"requests": 1}}`,
+		"/9/resolvers":           `{"dns": {"requests": {"name": 2}}}`,
+		"/9/http/limit_reqs":     `{"login": {"passed": 3}}`,
+		"/9/http/limit_conns":    `{"downloads": {"passed": 4}, "uploads": {"passed": 5}}`,
+		"/9/stream/limit_conns":  `{"tcp": {"passed": 6}}`,
+		"/9/stream/zone_sync":    `{"zones": {"sessions": {"records_total": 7}}, "status": {}}`,
+	}, labelNames,
+		WithSections(SectionLocationZones, SectionResolvers, SectionLimitRequests, SectionLimitConnections, SectionStreamLimitConnections, SectionStreamZoneSync))
+
+	c.UpdateLocationZoneLabels(map[string][]string{"checkout": {"payments"}})
+	c.UpdateResolverLabels(map[string][]string{"dns": {"network"}})
+	c.UpdateLimitRequestLabels(map[string][]string{"login": {"identity"}})
+	// uploads has the wrong number of values, so they are not set and its labels are empty
+	c.UpdateLimitConnectionLabels(map[string][]string{"downloads": {"media"}, "uploads": {"media", "extra"}})
+	if got, want := c.LabelStore().Values(LabelKindLimitConnection), map[string][]string{"downloads": {"media"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Values() = %v, want %v", got, want)
+	}
+	c.UpdateStreamLimitConnectionLabels(map[string][]string{"tcp": {"edge"}})
+	c.UpdateStreamZoneSyncZoneLabels(map[string][]string{"sessions": {"identity"}, "deleted": {"core"}})
+	c.DeleteStreamZoneSyncZoneLabels([]string{"deleted"})
+
+	want := `# HELP nginxplus_limit_connection_passed Total number of connections that were neither limited nor accounted as limited
+# TYPE nginxplus_limit_connection_passed counter
+nginxplus_limit_connection_passed{team="",zone="uploads"} 5
+nginxplus_limit_connection_passed{team="media",zone="downloads"} 4
+# HELP nginxplus_limit_request_passed Total number of requests that were neither limited nor accounted as limited
+# TYPE nginxplus_limit_request_passed counter
+nginxplus_limit_request_passed{team="identity",zone="login"} 3
+# HELP nginxplus_location_zone_requests Total client requests
+# TYPE nginxplus_location_zone_requests counter
+nginxplus_location_zone_requests{location_zone="checkout",team="payments"} 1
+# HELP nginxplus_resolver_name Total requests to resolve names to addresses
+# TYPE nginxplus_resolver_name counter
+nginxplus_resolver_name{resolver="dns",team="network"} 2
+# HELP nginxplus_stream_limit_connection_passed Total number of connections that were neither limited nor accounted as limited
+# TYPE nginxplus_stream_limit_connection_passed counter
+nginxplus_stream_limit_connection_passed{team="edge",zone="tcp"} 6
+# HELP nginxplus_stream_zone_sync_zone_records_total The total number of records stored in the shared memory zone
+# TYPE nginxplus_stream_zone_sync_zone_records_total gauge
+nginxplus_stream_zone_sync_zone_records_total{team="identity",zone="sessions"} 7
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(want),
+		"nginxplus_limit_connection_passed", "nginxplus_limit_request_passed", "nginxplus_location_zone_requests",
+		"nginxplus_resolver_name", "nginxplus_stream_limit_connection_passed", "nginxplus_stream_zone_sync_zone_records_total")
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNginxPlusCollectorNginxInfo(t *testing.T) {
+	t.Parallel()
+
+	// the clock of NGINX is an hour ahead
+	timestamp := time.Now().Add(time.Hour).UTC().Format("2006-01-02T15:04:05.000Z")
+	c := newTestPlusCollector(t, map[string]string{
+		"/9/nginx": `{"version": "1.27.2", "build": "nginx-plus-r33", "generation": 4, "pid": 42,
+			"load_timestamp": "2024-11-19T10:00:00.500Z", "timestamp": "` + timestamp + `"}`,
+	}, WithSections())
+
+	want := `# HELP nginxplus_config_generation Total number of configuration reloads
+# TYPE nginxplus_config_generation counter
+nginxplus_config_generation 4
+# HELP nginxplus_config_reload_timestamp_seconds Time of the last configuration reload, in seconds since the Unix epoch
+# TYPE nginxplus_config_reload_timestamp_seconds gauge
+nginxplus_config_reload_timestamp_seconds 1.7320104005e+09
+# HELP nginxplus_info Version and build of NGINX Plus
+# TYPE nginxplus_info gauge
+nginxplus_info{build="nginx-plus-r33",version="1.27.2"} 1
+# HELP nginxplus_master_pid ID of the master process
+# TYPE nginxplus_master_pid gauge
+nginxplus_master_pid 42
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(want),
+		"nginxplus_config_generation", "nginxplus_config_reload_timestamp_seconds", "nginxplus_info", "nginxplus_master_pid")
+	if err != nil {
+		t.Error(err)
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() returned error: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != "nginxplus_clock_skew_seconds" {
+			continue
+		}
+		// the timestamps of NGINX have a millisecond precision
+		if skew := family.GetMetric()[0].GetGauge().GetValue(); skew < 3599 || skew > 3601 {
+			t.Errorf("nginxplus_clock_skew_seconds = %v, want about an hour", skew)
+		}
+		return
+	}
+	t.Error("nginxplus_clock_skew_seconds is missing")
+}
+
+func TestNginxPlusCollectorSlabs(t *testing.T) {
+	t.Parallel()
+
+	c := newTestPlusCollector(t, map[string]string{
+		"/9/slabs": `{"limits": {"pages": {"used": 2, "free": 60},
+			"slots": {"8": {"used": 1, "free": 503, "reqs": 7, "fails": 0}, "128": {"used": 32, "free": 0, "reqs": 40, "fails": 8}}}}`,
+	}, WithSections(SectionSlabs))
+
+	want := `# HELP nginxplus_slab_pages_free Number of free memory pages of the shared memory zone
+# TYPE nginxplus_slab_pages_free gauge
+nginxplus_slab_pages_free{zone="limits"} 60
+# HELP nginxplus_slab_pages_used Number of used memory pages of the shared memory zone
+# TYPE nginxplus_slab_pages_used gauge
+nginxplus_slab_pages_used{zone="limits"} 2
+# HELP nginxplus_slab_slot_fails Total number of unsuccessful attempts to allocate memory of the size
+# TYPE nginxplus_slab_slot_fails counter
+nginxplus_slab_slot_fails{slot="128",zone="limits"} 8
+nginxplus_slab_slot_fails{slot="8",zone="limits"} 0
+# HELP nginxplus_slab_slot_free Number of free memory slots of the size
+# TYPE nginxplus_slab_slot_free gauge
+nginxplus_slab_slot_free{slot="128",zone="limits"} 0
+nginxplus_slab_slot_free{slot="8",zone="limits"} 503
+# HELP nginxplus_slab_slot_reqs Total number of attempts to allocate memory of the size
+# TYPE nginxplus_slab_slot_reqs counter
+nginxplus_slab_slot_reqs{slot="128",zone="limits"} 40
+nginxplus_slab_slot_reqs{slot="8",zone="limits"} 7
+# HELP nginxplus_slab_slot_used Number of used memory slots of the size
+# TYPE nginxplus_slab_slot_used gauge
+nginxplus_slab_slot_used{slot="128",zone="limits"} 32
+nginxplus_slab_slot_used{slot="8",zone="limits"} 1
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(want),
+		"nginxplus_slab_pages_free", "nginxplus_slab_pages_used", "nginxplus_slab_slot_fails", "nginxplus_slab_slot_free",
+		"nginxplus_slab_slot_reqs", "nginxplus_slab_slot_used")
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNginxPlusCollectorSSLFailures(t *testing.T) {
+	t.Parallel()
+
+	c := newTestPlusCollector(t, map[string]string{
+		"/9/ssl":               `{"handshakes_failed": 5, "no_common_cipher": 2, "handshake_timeout": 3, "verify_failures": {"expired_cert": 1}}`,
+		"/9/http/server_zones": `{"app": {"ssl": {"no_common_protocol": 4, "verify_failures": {"no_cert": 6}}}}`,
+		"/9/http/upstreams":    `{"backend": {"peers": [{"server": "10.0.0.1:443", "ssl": {"verify_failures": {"hostname_mismatch": 7}}}]}}`,
+	}, WithSections(SectionSSL, SectionServerZones, SectionUpstreams))
+
+	want := `# HELP nginxplus_server_zone_ssl_verify_failures Failed SSL certificate verifications by reason
+# TYPE nginxplus_server_zone_ssl_verify_failures counter
+nginxplus_server_zone_ssl_verify_failures{reason="expired_cert",server_zone="app"} 0
+nginxplus_server_zone_ssl_verify_failures{reason="hostname_mismatch",server_zone="app"} 0
+nginxplus_server_zone_ssl_verify_failures{reason="no_cert",server_zone="app"} 6
+nginxplus_server_zone_ssl_verify_failures{reason="other",server_zone="app"} 0
+nginxplus_server_zone_ssl_verify_failures{reason="revoked_cert",server_zone="app"} 0
+# HELP nginxplus_ssl_handshake_failures Failed SSL handshakes by reason
+# TYPE nginxplus_ssl_handshake_failures counter
+nginxplus_ssl_handshake_failures{reason="handshake_timeout"} 3
+nginxplus_ssl_handshake_failures{reason="no_common_cipher"} 2
+nginxplus_ssl_handshake_failures{reason="no_common_protocol"} 0
+nginxplus_ssl_handshake_failures{reason="peer_rejected_cert"} 0
+# HELP nginxplus_upstream_server_ssl_verify_failures Failed SSL certificate verifications by reason
+# TYPE nginxplus_upstream_server_ssl_verify_failures counter
+nginxplus_upstream_server_ssl_verify_failures{reason="expired_cert",server="10.0.0.1:443",upstream="backend"} 0
+nginxplus_upstream_server_ssl_verify_failures{reason="hostname_mismatch",server="10.0.0.1:443",upstream="backend"} 7
+nginxplus_upstream_server_ssl_verify_failures{reason="no_cert",server="10.0.0.1:443",upstream="backend"} 0
+nginxplus_upstream_server_ssl_verify_failures{reason="other",server="10.0.0.1:443",upstream="backend"} 0
+nginxplus_upstream_server_ssl_verify_failures{reason="revoked_cert",server="10.0.0.1:443",upstream="backend"} 0
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(want), "nginxplus_server_zone_ssl_verify_failures",
+		"nginxplus_ssl_handshake_failures", "nginxplus_upstream_server_ssl_verify_failures")
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNginxPlusCollectorSSLFailuresBeforeAPIVersion8(t *testing.T) {
+	t.Parallel()
+
+	nginx := newTestPlusServer(t, plusRoutes(map[string]string{
+		"/7/ssl":               `{"handshakes_failed": 5}`,
+		"/7/http/server_zones": `{"app": {"ssl": {"handshakes_failed": 1}}}`,
+	}))
+	c := newTestPlusCollectorOfClient(newTestPlusClient(t, nginx, plusclient.WithAPIVersion(7)), nil, WithSections(SectionSSL, SectionServerZones))
+
+	// the API doesn't report the reasons, which would all be zero
+	want := `# HELP nginxplus_ssl_handshakes_failed Failed SSL handshakes
+# TYPE nginxplus_ssl_handshakes_failed counter
+nginxplus_ssl_handshakes_failed 5
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(want), "nginxplus_ssl_handshakes_failed", "nginxplus_ssl_handshake_failures",
+		"nginxplus_ssl_verify_failures", "nginxplus_server_zone_ssl_handshake_failures", "nginxplus_server_zone_ssl_verify_failures")
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNginxPlusCollectorPeerAttributes(t *testing.T) {
+	t.Parallel()
+
+	c := newTestPlusCollector(t, map[string]string{
+		"/9/http/upstreams": `{
+			"backend": {"queue": {"size": 3, "max_size": 100, "overflows": 12}, "peers": [
+				{"server": "10.0.0.1:80", "name": "app1.example.com:80", "service": "_http._tcp", "weight": 5, "downtime": 1500,
+					"downstart": "2024-03-01T10:00:00.5Z", "selected": "2024-03-01T09:59:00Z", "health_checks": {"checks": 4, "last_passed": false}},
+				{"server": "10.0.0.2:80", "name": "10.0.0.2:80", "weight": 1, "backup": true}
+			]},
+			"legacy": {"peers": []}
+		}`,
+		"/9/stream/upstreams": `{"dns": {"peers": [{"server": "10.0.0.3:53", "name": "10.0.0.3:53", "weight": 2, "health_checks": {"checks": 1, "last_passed": true}}]}}`,
+	}, WithSections(SectionUpstreams, SectionStreamUpstreams))
+
+	want := `# HELP nginxplus_stream_upstream_server_health_checks_last_passed Whether the last health check request was successful and passed tests
+# TYPE nginxplus_stream_upstream_server_health_checks_last_passed gauge
+nginxplus_stream_upstream_server_health_checks_last_passed{server="10.0.0.3:53",upstream="dns"} 1
+# HELP nginxplus_stream_upstream_server_info Resolved name, service and backup flag of the server
+# TYPE nginxplus_stream_upstream_server_info gauge
+nginxplus_stream_upstream_server_info{backup="false",name="10.0.0.3:53",server="10.0.0.3:53",service="",upstream="dns"} 1
+# HELP nginxplus_stream_upstream_server_weight Weight of the server
+# TYPE nginxplus_stream_upstream_server_weight gauge
+nginxplus_stream_upstream_server_weight{server="10.0.0.3:53",upstream="dns"} 2
+# HELP nginxplus_upstream_queue_max_size Maximum number of requests that can be in the queue at the same time
+# TYPE nginxplus_upstream_queue_max_size gauge
+nginxplus_upstream_queue_max_size{upstream="backend"} 100
+# HELP nginxplus_upstream_queue_overflows Total number of requests rejected due to the queue overflow
+# TYPE nginxplus_upstream_queue_overflows counter
+nginxplus_upstream_queue_overflows{upstream="backend"} 12
+# HELP nginxplus_upstream_server_downstart_timestamp_seconds Time when the server became unavail, checking or unhealthy, in seconds since the Unix epoch
+# TYPE nginxplus_upstream_server_downstart_timestamp_seconds gauge
+nginxplus_upstream_server_downstart_timestamp_seconds{server="10.0.0.1:80",upstream="backend"} 1.7092872005e+09
+# HELP nginxplus_upstream_server_downtime Total time the server was in the unavail, checking and unhealthy states, in milliseconds
+# TYPE nginxplus_upstream_server_downtime counter
+nginxplus_upstream_server_downtime{server="10.0.0.1:80",upstream="backend"} 1500
+nginxplus_upstream_server_downtime{server="10.0.0.2:80",upstream="backend"} 0
+# HELP nginxplus_upstream_server_health_checks_last_passed Whether the last health check request was successful and passed tests
+# TYPE nginxplus_upstream_server_health_checks_last_passed gauge
+nginxplus_upstream_server_health_checks_last_passed{server="10.0.0.1:80",upstream="backend"} 0
+# HELP nginxplus_upstream_server_info Resolved name, service and backup flag of the server
+# TYPE nginxplus_upstream_server_info gauge
+nginxplus_upstream_server_info{backup="false",name="app1.example.com:80",server="10.0.0.1:80",service="_http._tcp",upstream="backend"} 1
+nginxplus_upstream_server_info{backup="true",name="10.0.0.2:80",server="10.0.0.2:80",service="",upstream="backend"} 1
+# HELP nginxplus_upstream_server_selected_timestamp_seconds Time when the server was last selected to process a request, in seconds since the Unix epoch
+# TYPE nginxplus_upstream_server_selected_timestamp_seconds gauge
+nginxplus_upstream_server_selected_timestamp_seconds{server="10.0.0.1:80",upstream="backend"} 1.70928714e+09
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(want),
+		"nginxplus_stream_upstream_server_health_checks_last_passed", "nginxplus_stream_upstream_server_info",
+		"nginxplus_stream_upstream_server_weight", "nginxplus_upstream_queue_max_size", "nginxplus_upstream_queue_overflows",
+		"nginxplus_upstream_server_downstart_timestamp_seconds", "nginxplus_upstream_server_downtime",
+		"nginxplus_upstream_server_health_checks_last_passed", "nginxplus_upstream_server_info",
+		"nginxplus_upstream_server_selected_timestamp_seconds")
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,48 @@
+package collector
+
+import (
+	"strconv"
+	"time"
+
+	plusclient "github.com/nginx/nginx-plus-go-client/v2/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// peerAttributes are the attributes shared by the peers of HTTP and stream upstreams.
+type peerAttributes struct {
+	upstream     string
+	server       string
+	name         string
+	service      string
+	selected     string
+	downstart    string
+	healthChecks plusclient.HealthChecks
+	downtime     uint64
+	weight       int
+	backup       bool
+}
+
+// newPeerInfoMetric returns the info family of the peers. It only has the upstream and server labels, so its label
+// names don't clash with the variable labels of the peers.
+func newPeerInfoMetric(namespace string, subsystem string, constLabels prometheus.Labels) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, "info"), "Resolved name, service and backup flag of the server",
+		[]string{"upstream", "server", "name", "service", "backup"}, constLabels)
+}
+
+// collectPeerAttributes sends the attributes of a peer to ch. The timestamps are only sent when NGINX Plus
+// reports them: the peer was selected at least once, or it is currently down.
+func collectPeerAttributes(ch chan<- prometheus.Metric, metrics map[string]*prometheus.Desc, peer peerAttributes, labelValues []string) {
+	ch <- prometheus.MustNewConstMetric(metrics["weight"], prometheus.GaugeValue, float64(peer.weight), labelValues...)
+	ch <- prometheus.MustNewConstMetric(metrics["downtime"], prometheus.CounterValue, float64(peer.downtime), labelValues...)
+	if downstart, err := time.Parse(time.RFC3339, peer.downstart); err == nil {
+		ch <- prometheus.MustNewConstMetric(metrics["downstart"], prometheus.GaugeValue, float64(downstart.UnixMilli())/1000, labelValues...)
+	}
+	if selected, err := time.Parse(time.RFC3339, peer.selected); err == nil {
+		ch <- prometheus.MustNewConstMetric(metrics["selected"], prometheus.GaugeValue, float64(selected.UnixMilli())/1000, labelValues...)
+	}
+	if peer.healthChecks != (plusclient.HealthChecks{}) {
+		ch <- prometheus.MustNewConstMetric(metrics["health_checks_last_passed"], prometheus.GaugeValue, booleanToFloat64[peer.healthChecks.LastPassed], labelValues...)
+	}
+	ch <- prometheus.MustNewConstMetric(metrics["info"], prometheus.GaugeValue, 1,
+		peer.upstream, peer.server, peer.name, peer.service, strconv.FormatBool(peer.backup))
+}
@@ -0,0 +1,97 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var errNoSnapshot = errors.New("no stats have been fetched yet")
+
+// poller fetches stats in the background and keeps the latest successful result, so that
+// scrapes can be served without querying NGINX every time.
+type poller[T any] struct {
+	fetched   time.Time
+	latest    T
+	err       error
+	fetch     func(ctx context.Context) (T, error)
+	logger    *slog.Logger
+	interval  time.Duration
+	staleness time.Duration
+	mutex     sync.RWMutex
+}
+
+// newPoller creates a poller that fetches every interval. Stats older than staleness are
+// reported as stale; a zero staleness means three intervals.
+func newPoller[T any](fetch func(ctx context.Context) (T, error), interval, staleness time.Duration, logger *slog.Logger) *poller[T] {
+	if staleness == 0 {
+		staleness = 3 * interval
+	}
+	return &poller[T]{
+		fetch:     fetch,
+		logger:    logger,
+		interval:  interval,
+		staleness: staleness,
+	}
+}
+
+// run fetches the stats right away and then every interval until ctx is done.
+func (p *poller[T]) run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.poll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *poller[T]) poll(ctx context.Context) {
+	stats, err := p.fetch(ctx)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.err = err
+	if err != nil {
+		p.logger.Warn("error polling stats", "error", err.Error())
+		return
+	}
+	p.latest = stats
+	p.fetched = time.Now()
+}
+
+// collect sends the age of the latest stats to ch using ageDesc and returns the stats.
+// It returns an error if there are no stats yet or if they are stale.
+func (p *poller[T]) collect(ch chan<- prometheus.Metric, ageDesc *prometheus.Desc) (T, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	var zero T
+	if p.fetched.IsZero() {
+		if p.err != nil {
+			return zero, p.err
+		}
+		return zero, errNoSnapshot
+	}
+
+	age := time.Since(p.fetched)
+	ch <- prometheus.MustNewConstMetric(ageDesc, prometheus.GaugeValue, age.Seconds())
+
+	if age > p.staleness {
+		if p.err != nil {
+			return zero, fmt.Errorf("stats are %v old, older than %v: %w", age.Round(time.Millisecond), p.staleness, p.err)
+		}
+		return zero, fmt.Errorf("stats are %v old, older than %v", age.Round(time.Millisecond), p.staleness)
+	}
+	return p.latest, nil
+}
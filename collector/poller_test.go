@@ -0,0 +1,124 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nginx/nginx-prometheus-exporter/client"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestPollerCollect(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ageDesc := newSnapshotAgeMetric("test", nil)
+	errFetch := errors.New("fetch failed")
+
+	tests := []struct {
+		wantErrIs error
+		fetch     func(context.Context) (int, error)
+		name      string
+		staleness time.Duration
+		want      int
+		wantAge   bool
+		polls     int
+		wantErr   bool
+	}{
+		{
+			name:      "no snapshot yet",
+			fetch:     func(context.Context) (int, error) { return 1, nil },
+			wantErr:   true,
+			wantErrIs: errNoSnapshot,
+		},
+		{
+			name:      "first poll failed",
+			fetch:     func(context.Context) (int, error) { return 0, errFetch },
+			polls:     1,
+			wantErr:   true,
+			wantErrIs: errFetch,
+		},
+		{
+			name:      "fresh snapshot",
+			fetch:     func(context.Context) (int, error) { return 42, nil },
+			polls:     1,
+			staleness: time.Hour,
+			want:      42,
+			wantAge:   true,
+		},
+		{
+			name:      "stale snapshot",
+			fetch:     func(context.Context) (int, error) { return 42, nil },
+			polls:     1,
+			staleness: time.Nanosecond,
+			wantAge:   true,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			p := newPoller(tt.fetch, time.Minute, tt.staleness, logger)
+			for range tt.polls {
+				p.poll(context.Background())
+			}
+			time.Sleep(time.Millisecond)
+
+			ch := make(chan prometheus.Metric, 1)
+			got, err := p.collect(ch, ageDesc)
+			close(ch)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("collect() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErrIs != nil && !errors.Is(err, tt.wantErrIs) {
+				t.Errorf("collect() error = %v, want %v", err, tt.wantErrIs)
+			}
+			if got != tt.want {
+				t.Errorf("collect() = %v, want %v", got, tt.want)
+			}
+			if gotAge := len(ch) == 1; gotAge != tt.wantAge {
+				t.Errorf("collect() sent age metric = %v, want %v", gotAge, tt.wantAge)
+			}
+		})
+	}
+}
+
+func TestNginxCollectorPolling(t *testing.T) {
+	t.Parallel()
+
+	var requests atomic.Int32
+	nginx := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		_, _ = io.WriteString(w, "Active connections: 1 \nserver accepts handled requests\n 2 2 3 \nReading: 0 Writing: 1 Waiting: 0 \n")
+	}))
+	t.Cleanup(nginx.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	c := NewNginxCollector(client.NewNginxClient(nginx.Client(), nginx.URL), "nginx", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	c.StartPolling(ctx, time.Hour, 0)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for requests.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for range 3 {
+		if got := testutil.CollectAndCount(c, "nginx_up", "nginx_connections_active", "nginx_snapshot_age_seconds"); got != 3 {
+			t.Errorf("CollectAndCount() = %v, want 3", got)
+		}
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("NGINX got %v requests, want 1", got)
+	}
+}
@@ -0,0 +1,360 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	plusclient "github.com/nginx/nginx-plus-go-client/v2/client"
+	"golang.org/x/sync/errgroup"
+)
+
+// Section is a part of the NGINX Plus API that can be collected independently of the others.
+type Section string
+
+// Sections of the NGINX Plus API. The NGINX info is always collected.
+const (
+	SectionConnections            Section = "connections"
+	SectionHTTPRequests           Section = "http-requests"
+	SectionSSL                    Section = "ssl"
+	SectionServerZones            Section = "server-zones"
+	SectionUpstreams              Section = "upstreams"
+	SectionStreamServerZones      Section = "stream-server-zones"
+	SectionStreamUpstreams        Section = "stream-upstreams"
+	SectionStreamZoneSync         Section = "stream-zone-sync"
+	SectionLocationZones          Section = "location-zones"
+	SectionResolvers              Section = "resolvers"
+	SectionLimitRequests          Section = "limit-requests"
+	SectionLimitConnections       Section = "limit-connections"
+	SectionStreamLimitConnections Section = "stream-limit-connections"
+	SectionCaches                 Section = "caches"
+	SectionWorkers                Section = "workers"
+	SectionSlabs                  Section = "slabs"
+	SectionLicense                Section = "license"
+	SectionKeyVals                Section = "keyvals"
+)
+
+// AllSections returns all the sections of the NGINX Plus API.
+func AllSections() []Section {
+	return []Section{
+		SectionConnections,
+		SectionHTTPRequests,
+		SectionSSL,
+		SectionServerZones,
+		SectionUpstreams,
+		SectionStreamServerZones,
+		SectionStreamUpstreams,
+		SectionStreamZoneSync,
+		SectionLocationZones,
+		SectionResolvers,
+		SectionLimitRequests,
+		SectionLimitConnections,
+		SectionStreamLimitConnections,
+		SectionCaches,
+		SectionWorkers,
+		SectionSlabs,
+		SectionLicense,
+		SectionKeyVals,
+	}
+}
+
+// DefaultSections returns the sections of the NGINX Plus API that are collected by default. The keyvals section
+// is left out, as it reads every key-value pair stored in NGINX Plus.
+func DefaultSections() []Section {
+	return slices.DeleteFunc(AllSections(), func(s Section) bool {
+		return s == SectionKeyVals
+	})
+}
+
+// NginxPlusCollectorOption configures an NginxPlusCollector.
+type NginxPlusCollectorOption func(*NginxPlusCollector)
+
+// WithSections limits the collector to the given sections of the NGINX Plus API.
+// The API endpoints of the other sections are not requested.
+func WithSections(sections ...Section) NginxPlusCollectorOption {
+	return func(c *NginxPlusCollector) {
+		c.sections = make(map[Section]bool, len(sections))
+		for _, s := range sections {
+			c.sections[s] = true
+		}
+	}
+}
+
+// maxConcurrentRequests is the maximum number of requests sent to the NGINX Plus API at the same time by a fetch
+// of the stats, so that they can reuse the idle connections to NGINX Plus instead of opening new ones.
+const maxConcurrentRequests = 4
+
+type sectionGetter func(ctx context.Context, c *NginxPlusCollector, stats *plusStats) error
+
+// sectionGetters fetch every section into its own field of the stats, so they can run concurrently. The license
+// is fetched along with the NGINX info, which it depends on.
+var sectionGetters = map[Section]sectionGetter{
+	SectionConnections: func(ctx context.Context, c *NginxPlusCollector, stats *plusStats) error {
+		connections, err := c.nginxClient.GetConnections(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get connections: %w", err)
+		}
+		stats.Connections = *connections
+		return nil
+	},
+	SectionHTTPRequests: func(ctx context.Context, c *NginxPlusCollector, stats *plusStats) error {
+		requests, err := c.nginxClient.GetHTTPRequests(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get HTTP requests: %w", err)
+		}
+		stats.HTTPRequests = *requests
+		return nil
+	},
+	SectionSSL: func(ctx context.Context, c *NginxPlusCollector, stats *plusStats) error {
+		ssl, err := c.nginxClient.GetSSL(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get SSL: %w", err)
+		}
+		stats.SSL = *ssl
+		return nil
+	},
+	SectionServerZones: func(ctx context.Context, c *NginxPlusCollector, stats *plusStats) error {
+		if c.api != nil {
+			var raw map[string]rawServerZone
+			if err := c.getRaw(ctx, "http/server_zones", &raw); err != nil {
+				return fmt.Errorf("failed to get server zones: %w", err)
+			}
+			stats.ServerZones, stats.codes.serverZones = serverZones(raw)
+			return nil
+		}
+		zones, err := c.nginxClient.GetServerZones(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get server zones: %w", err)
+		}
+		stats.ServerZones = *zones
+		stats.codes.serverZones = make(map[string]map[string]uint64, len(*zones))
+		for name, zone := range *zones {
+			stats.codes.serverZones[name] = typedCodes(zone.Responses.Codes)
+		}
+		return nil
+	},
+	SectionUpstreams: func(ctx context.Context, c *NginxPlusCollector, stats *plusStats) error {
+		if c.api != nil {
+			var raw map[string]rawUpstream
+			if err := c.getRaw(ctx, "http/upstreams", &raw); err != nil {
+				return fmt.Errorf("failed to get upstreams: %w", err)
+			}
+			stats.Upstreams, stats.codes.upstreamPeers = upstreams(raw)
+			return nil
+		}
+		upstreams, err := c.nginxClient.GetUpstreams(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get upstreams: %w", err)
+		}
+		stats.Upstreams = *upstreams
+		stats.codes.upstreamPeers = make(map[string]map[int]map[string]uint64, len(*upstreams))
+		for name, upstream := range *upstreams {
+			stats.codes.upstreamPeers[name] = make(map[int]map[string]uint64, len(upstream.Peers))
+			for _, peer := range upstream.Peers {
+				stats.codes.upstreamPeers[name][peer.ID] = typedCodes(peer.Responses.Codes)
+			}
+		}
+		return nil
+	},
+	SectionStreamServerZones: func(ctx context.Context, c *NginxPlusCollector, stats *plusStats) error {
+		zones, err := c.nginxClient.GetStreamServerZones(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get stream server zones: %w", err)
+		}
+		stats.StreamServerZones = *zones
+		return nil
+	},
+	SectionStreamUpstreams: func(ctx context.Context, c *NginxPlusCollector, stats *plusStats) error {
+		upstreams, err := c.nginxClient.GetStreamUpstreams(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get stream upstreams: %w", err)
+		}
+		stats.StreamUpstreams = *upstreams
+		return nil
+	},
+	SectionStreamZoneSync: func(ctx context.Context, c *NginxPlusCollector, stats *plusStats) error {
+		zoneSync, err := c.nginxClient.GetStreamZoneSync(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get stream zone sync: %w", err)
+		}
+		stats.StreamZoneSync = zoneSync
+		return nil
+	},
+	SectionLocationZones: func(ctx context.Context, c *NginxPlusCollector, stats *plusStats) error {
+		// location zones are available since version 5 of the API
+		if c.api != nil && c.nginxClient.Version() >= 5 {
+			var raw map[string]rawLocationZone
+			if err := c.getRaw(ctx, "http/location_zones", &raw); err != nil {
+				return fmt.Errorf("failed to get location zones: %w", err)
+			}
+			stats.LocationZones, stats.codes.locationZones = locationZones(raw)
+			return nil
+		}
+		zones, err := c.nginxClient.GetLocationZones(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get location zones: %w", err)
+		}
+		stats.LocationZones = *zones
+		stats.codes.locationZones = make(map[string]map[string]uint64, len(*zones))
+		for name, zone := range *zones {
+			stats.codes.locationZones[name] = typedCodes(zone.Responses.Codes)
+		}
+		return nil
+	},
+	SectionResolvers: func(ctx context.Context, c *NginxPlusCollector, stats *plusStats) error {
+		resolvers, err := c.nginxClient.GetResolvers(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get resolvers: %w", err)
+		}
+		stats.Resolvers = *resolvers
+		return nil
+	},
+	SectionLimitRequests: func(ctx context.Context, c *NginxPlusCollector, stats *plusStats) error {
+		limitReqs, err := c.nginxClient.GetHTTPLimitReqs(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get limit requests: %w", err)
+		}
+		stats.HTTPLimitRequests = *limitReqs
+		return nil
+	},
+	SectionLimitConnections: func(ctx context.Context, c *NginxPlusCollector, stats *plusStats) error {
+		limitConns, err := c.nginxClient.GetHTTPConnectionsLimit(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get limit connections: %w", err)
+		}
+		stats.HTTPLimitConnections = *limitConns
+		return nil
+	},
+	SectionStreamLimitConnections: func(ctx context.Context, c *NginxPlusCollector, stats *plusStats) error {
+		limitConns, err := c.nginxClient.GetStreamConnectionsLimit(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get stream limit connections: %w", err)
+		}
+		stats.StreamLimitConnections = *limitConns
+		return nil
+	},
+	SectionCaches: func(ctx context.Context, c *NginxPlusCollector, stats *plusStats) error {
+		caches, err := c.nginxClient.GetCaches(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get caches: %w", err)
+		}
+		stats.Caches = *caches
+		return nil
+	},
+	SectionWorkers: func(ctx context.Context, c *NginxPlusCollector, stats *plusStats) error {
+		workers, err := c.nginxClient.GetWorkers(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get workers: %w", err)
+		}
+		stats.Workers = workers
+		processes, err := c.nginxClient.GetProcesses(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get processes: %w", err)
+		}
+		stats.Processes = *processes
+		return nil
+	},
+	SectionSlabs: func(ctx context.Context, c *NginxPlusCollector, stats *plusStats) error {
+		slabs, err := c.nginxClient.GetSlabs(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get slabs: %w", err)
+		}
+		stats.Slabs = *slabs
+		return nil
+	},
+	SectionKeyVals: getKeyVals,
+}
+
+// plusRelease matches the release of NGINX Plus in its build, such as nginx-plus-r33.
+var plusRelease = regexp.MustCompile(`-r(\d+)`)
+
+// fetchLicense fetches the license of NGINX Plus, once the NGINX info is in stats. The license endpoint is available
+// since NGINX Plus R33 and version 9 of the API, and builds without a release are skipped.
+func (c *NginxPlusCollector) fetchLicense(ctx context.Context, stats *plusStats) error {
+	matches := plusRelease.FindStringSubmatch(stats.NginxInfo.Build)
+	if matches == nil {
+		return nil
+	}
+	release, err := strconv.Atoi(matches[1])
+	if err != nil || release < 33 || c.nginxClient.Version() < 9 {
+		return nil
+	}
+
+	var license *plusclient.NginxLicense
+	if c.api != nil {
+		license = &plusclient.NginxLicense{}
+		err = c.getRaw(ctx, "license", license)
+	} else {
+		// the client fetches the NGINX info again to check the release
+		license, err = c.nginxClient.GetNginxLicense(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get license: %w", err)
+	}
+	if license.ActiveTill != 0 {
+		stats.license = license
+	}
+	return nil
+}
+
+// fetchStats fetches the NGINX info and the enabled sections of the NGINX Plus API concurrently, sending at most
+// maxConcurrentRequests requests at the same time.
+func (c *NginxPlusCollector) fetchStats(ctx context.Context) (*plusStats, error) {
+	stats := &plusStats{Stats: &plusclient.Stats{}}
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(maxConcurrentRequests)
+
+	group.Go(func() error {
+		start := time.Now()
+		info, err := c.nginxClient.GetNginxInfo(groupCtx)
+		if err != nil {
+			return fmt.Errorf("failed to get NGINX info: %w", err)
+		}
+		stats.NginxInfo = *info
+		// NGINX read its clock halfway through the request, on average
+		now := start.Add(time.Since(start) / 2)
+		if timestamp, err := time.Parse(time.RFC3339, info.Timestamp); err == nil {
+			stats.clockSkew = timestamp.Sub(now)
+		}
+		if c.sections[SectionLicense] {
+			return c.fetchLicense(groupCtx, stats)
+		}
+		return nil
+	})
+
+	if c.labelZone != "" {
+		group.Go(func() error {
+			c.fetchKeyValLabels(groupCtx, stats)
+			return nil
+		})
+	}
+
+	for _, section := range AllSections() {
+		if !c.sections[section] {
+			continue
+		}
+		get, ok := sectionGetters[section]
+		if !ok {
+			continue
+		}
+		group.Go(func() error {
+			return get(groupCtx, c, stats)
+		})
+	}
+
+	if err := group.Wait(); err != nil {
+		return nil, fmt.Errorf("error returned from contacting Plus API: %w", err)
+	}
+	c.streamKeyVals.reloaded(stats.NginxInfo.Generation)
+	// the stats are never fetched concurrently, by the poller or by scrapes holding the mutex of the collector
+	if stats.labelPairs != nil {
+		c.applyKeyValLabels(stats.labelPairs)
+	}
+	if c.sections[SectionWorkers] && c.nginxClient.Version() >= 9 {
+		stats.workerRestarts = c.workerRestarts.update(stats.Workers, stats.NginxInfo.Generation)
+	}
+	return stats, nil
+}
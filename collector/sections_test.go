@@ -0,0 +1,144 @@
+package collector
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNginxPlusCollectorSections(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name             string
+		sections         []Section
+		wantPaths        []string
+		wantNoPaths      []string
+		wantDescribed    string
+		wantNotDescribed string
+	}{
+		{
+			name:          "all sections",
+			sections:      AllSections(),
+			wantPaths:     []string{"/9/connections", "/9/http/upstreams", "/9/stream/upstreams", "/9/workers", "/9/http/keyvals"},
+			wantDescribed: "nginxplus_upstream_server_requests",
+		},
+		{
+			name:             "default sections",
+			sections:         DefaultSections(),
+			wantPaths:        []string{"/9/connections", "/9/http/upstreams", "/9/slabs"},
+			wantNoPaths:      []string{"/9/http/keyvals", "/9/stream/keyvals"},
+			wantDescribed:    "nginxplus_upstream_server_requests",
+			wantNotDescribed: "nginxplus_keyval_entries",
+		},
+		{
+			name:             "upstreams only",
+			sections:         []Section{SectionUpstreams},
+			wantPaths:        []string{"/9/nginx", "/9/http/upstreams"},
+			wantNoPaths:      []string{"/9/connections", "/9/http/requests", "/9/ssl", "/9/http/server_zones", "/9/stream/upstreams", "/9/workers", "/9/http/caches"},
+			wantDescribed:    "nginxplus_upstream_server_requests",
+			wantNotDescribed: "nginxplus_server_zone_requests",
+		},
+		{
+			name:             "no sections",
+			sections:         []Section{},
+			wantPaths:        []string{"/9/nginx"},
+			wantNoPaths:      []string{"/9/connections", "/9/http/upstreams", "/9/stream/server_zones"},
+			wantNotDescribed: "nginxplus_upstream_server_requests",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mutex sync.Mutex
+			requested := make(map[string]bool)
+			routes := plusRoutes(nil)
+			nginx := newTestPlusServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mutex.Lock()
+				requested[r.URL.Path] = true
+				mutex.Unlock()
+				routes(w, r)
+			}))
+			c := newTestPlusCollectorOfClient(newTestPlusClient(t, nginx), nil, WithSections(tt.sections...))
+
+			if got := testutil.CollectAndCount(c, "nginxplus_up"); got != 1 {
+				t.Fatalf("CollectAndCount() = %v, want 1", got)
+			}
+			if got := testutil.ToFloat64(c.upMetric); got != nginxUp {
+				t.Errorf("nginxplus_up = %v, want %v", got, nginxUp)
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			for _, path := range tt.wantPaths {
+				if !requested[path] {
+					t.Errorf("%v was not requested", path)
+				}
+			}
+			for _, path := range tt.wantNoPaths {
+				if requested[path] {
+					t.Errorf("%v was requested, want it skipped", path)
+				}
+			}
+
+			described := describedNames(c)
+			if tt.wantDescribed != "" && !described[tt.wantDescribed] {
+				t.Errorf("%v was not described", tt.wantDescribed)
+			}
+			if tt.wantNotDescribed != "" && described[tt.wantNotDescribed] {
+				t.Errorf("%v was described, want it dropped", tt.wantNotDescribed)
+			}
+		})
+	}
+}
+
+func TestNginxPlusCollectorConcurrentRequests(t *testing.T) {
+	t.Parallel()
+
+	var current, most atomic.Int32
+	routes := plusRoutes(nil)
+	nginx := newTestPlusServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := current.Add(1)
+		defer current.Add(-1)
+		for {
+			m := most.Load()
+			if n <= m || most.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		routes(w, r)
+	}))
+	c := newTestPlusCollectorOfClient(newTestPlusClient(t, nginx), nil, WithSections(AllSections()...))
+
+	if got := testutil.CollectAndCount(c, "nginxplus_up"); got != 1 {
+		t.Fatalf("CollectAndCount() = %v, want 1", got)
+	}
+	if got := most.Load(); got > maxConcurrentRequests {
+		t.Errorf("%v requests were sent at the same time, want at most %v", got, maxConcurrentRequests)
+	}
+}
+
+// describedNames returns the fully-qualified names of the metrics described by c.
+func describedNames(c prometheus.Collector) map[string]bool {
+	ch := make(chan *prometheus.Desc)
+	go func() {
+		c.Describe(ch)
+		close(ch)
+	}()
+
+	names := make(map[string]bool)
+	for d := range ch {
+		// the string of a descriptor has the form Desc{fqName: "name", ...}
+		name, _, _ := strings.Cut(strings.TrimPrefix(d.String(), `Desc{fqName: "`), `"`)
+		names[name] = true
+	}
+	return names
+}
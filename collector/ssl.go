@@ -0,0 +1,50 @@
+package collector
+
+import (
+	plusclient "github.com/nginx/nginx-plus-go-client/v2/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func newSSLHandshakeFailuresMetric(namespace string, subsystem string, labelNames []string, constLabels prometheus.Labels) *prometheus.Desc {
+	labels := make([]string, 0, len(labelNames)+1)
+	labels = append(labels, labelNames...)
+	labels = append(labels, "reason")
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, "ssl_handshake_failures"), "Failed SSL handshakes by reason", labels, constLabels)
+}
+
+func newSSLVerifyFailuresMetric(namespace string, subsystem string, labelNames []string, constLabels prometheus.Labels) *prometheus.Desc {
+	labels := make([]string, 0, len(labelNames)+1)
+	labels = append(labels, labelNames...)
+	labels = append(labels, "reason")
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, "ssl_verify_failures"), "Failed SSL certificate verifications by reason", labels, constLabels)
+}
+
+// collectSSLFailures sends the failed SSL handshakes and certificate verifications of an object by reason to ch.
+// The metrics of the given map are looked up by the ssl_handshake_failures and ssl_verify_failures keys. The reasons
+// are only reported since version 8 of the API.
+func (c *NginxPlusCollector) collectSSLFailures(ch chan<- prometheus.Metric, metrics map[string]*prometheus.Desc, ssl plusclient.SSL, labelValues []string) {
+	if c.nginxClient.Version() < 8 {
+		return
+	}
+	reasons := []struct {
+		desc   *prometheus.Desc
+		reason string
+		count  uint64
+	}{
+		{desc: metrics["ssl_handshake_failures"], reason: "no_common_protocol", count: ssl.NoCommonProtocol},
+		{desc: metrics["ssl_handshake_failures"], reason: "no_common_cipher", count: ssl.NoCommonCipher},
+		{desc: metrics["ssl_handshake_failures"], reason: "handshake_timeout", count: ssl.HandshakeTimeout},
+		{desc: metrics["ssl_handshake_failures"], reason: "peer_rejected_cert", count: ssl.PeerRejectedCert},
+		{desc: metrics["ssl_verify_failures"], reason: "no_cert", count: ssl.VerifyFailures.NoCert},
+		{desc: metrics["ssl_verify_failures"], reason: "expired_cert", count: ssl.VerifyFailures.ExpiredCert},
+		{desc: metrics["ssl_verify_failures"], reason: "revoked_cert", count: ssl.VerifyFailures.RevokedCert},
+		{desc: metrics["ssl_verify_failures"], reason: "hostname_mismatch", count: ssl.VerifyFailures.HostnameMismatch},
+		{desc: metrics["ssl_verify_failures"], reason: "other", count: ssl.VerifyFailures.Other},
+	}
+	for _, r := range reasons {
+		values := make([]string, 0, len(labelValues)+1)
+		values = append(values, labelValues...)
+		values = append(values, r.reason)
+		ch <- prometheus.MustNewConstMetric(r.desc, prometheus.CounterValue, float64(r.count), values...)
+	}
+}
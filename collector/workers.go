@@ -0,0 +1,56 @@
+package collector
+
+import (
+	"strconv"
+
+	plusclient "github.com/nginx/nginx-plus-go-client/v2/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func newWorkerRestartsMetric(namespace string, constLabels prometheus.Labels) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "worker", "restarts"),
+		"Lower bound of the number of times the worker process was replaced outside configuration reloads, detected by a change of its process ID between two fetches of the stats", []string{"id"}, constLabels)
+}
+
+// workerRestarts counts the restarts of the worker processes, which NGINX reports under the same id with a new process
+// ID. The process IDs of all the workers change on a configuration reload, so the changes that come with a new
+// configuration generation are not counted. It is updated every time the stats are fetched, on every scrape or poll,
+// and several restarts of a worker between two fetches count as one.
+type workerRestarts struct {
+	pids       map[string]uint64
+	counts     map[string]uint64
+	generation uint64
+}
+
+// update compares the process IDs of the workers with those of the previous update and returns the restarts per
+// worker id. The counts of the workers that are no longer reported, after a reload with fewer workers, are forgotten.
+// The returned map is not modified by later updates.
+func (r *workerRestarts) update(workers []*plusclient.Workers, generation uint64) map[string]uint64 {
+	reloaded := r.pids != nil && generation != r.generation
+	pids := make(map[string]uint64, len(workers))
+	counts := make(map[string]uint64, len(workers))
+	for id, worker := range workers {
+		workerID := strconv.FormatInt(int64(id), 10)
+		pids[workerID] = worker.ProcessID
+		counts[workerID] = r.counts[workerID]
+		if previous, ok := r.pids[workerID]; ok && previous != worker.ProcessID && !reloaded {
+			counts[workerID]++
+		}
+	}
+	r.pids = pids
+	r.counts = counts
+	r.generation = generation
+	return counts
+}
+
+// collectWorkerProcesses sends the number of workers, their restarts and the respawned processes to ch. The workers
+// are only reported since version 9 of the API.
+func (c *NginxPlusCollector) collectWorkerProcesses(ch chan<- prometheus.Metric, stats *plusStats) {
+	if c.nginxClient.Version() >= 9 {
+		for workerID, count := range stats.workerRestarts {
+			ch <- prometheus.MustNewConstMetric(c.workerMetrics["restarts"], prometheus.CounterValue, float64(count), workerID)
+		}
+		ch <- prometheus.MustNewConstMetric(c.workerMetrics["workers"], prometheus.GaugeValue, float64(len(stats.Workers)))
+	}
+	ch <- prometheus.MustNewConstMetric(c.workerMetrics["processes_respawned"], prometheus.CounterValue, float64(stats.Processes.Respawned))
+}
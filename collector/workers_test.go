@@ -0,0 +1,150 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	plusclient "github.com/nginx/nginx-plus-go-client/v2/client"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNginxPlusCollectorWorkerRestarts(t *testing.T) {
+	t.Parallel()
+
+	type state struct {
+		pids       []int
+		generation int
+		respawned  int
+	}
+	var current atomic.Pointer[state]
+	nginx := newTestPlusServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := current.Load()
+		workers := make([]string, 0, len(s.pids))
+		for id, pid := range s.pids {
+			workers = append(workers, fmt.Sprintf(`{"id": %v, "pid": %v}`, id, pid))
+		}
+		plusRoutes(map[string]string{
+			"/9/nginx":     fmt.Sprintf(`{"generation": %v}`, s.generation),
+			"/9/processes": fmt.Sprintf(`{"respawned": %v}`, s.respawned),
+			"/9/workers":   "[" + strings.Join(workers, ",") + "]",
+		})(w, r)
+	}))
+	c := newTestPlusCollectorOfClient(newTestPlusClient(t, nginx), nil, WithSections(SectionWorkers))
+
+	// the scrapes run in order, as every one depends on the process IDs of the previous one
+	scrapes := []struct {
+		state    state
+		name     string
+		restarts string
+		workers  int
+	}{
+		{
+			name:     "first scrape",
+			state:    state{pids: []int{100, 101}, generation: 1},
+			workers:  2,
+			restarts: "nginxplus_worker_restarts{id=\"0\"} 0\nnginxplus_worker_restarts{id=\"1\"} 0\n",
+		},
+		{
+			name:     "crashed worker",
+			state:    state{pids: []int{100, 102}, generation: 1, respawned: 1},
+			workers:  2,
+			restarts: "nginxplus_worker_restarts{id=\"0\"} 0\nnginxplus_worker_restarts{id=\"1\"} 1\n",
+		},
+		{
+			name:     "reload with more workers",
+			state:    state{pids: []int{200, 201, 202}, generation: 2, respawned: 1},
+			workers:  3,
+			restarts: "nginxplus_worker_restarts{id=\"0\"} 0\nnginxplus_worker_restarts{id=\"1\"} 1\nnginxplus_worker_restarts{id=\"2\"} 0\n",
+		},
+		{
+			name:     "reload with fewer workers",
+			state:    state{pids: []int{300}, generation: 3, respawned: 1},
+			workers:  1,
+			restarts: "nginxplus_worker_restarts{id=\"0\"} 0\n",
+		},
+	}
+	for _, s := range scrapes {
+		current.Store(&s.state)
+		want := fmt.Sprintf(`# HELP nginxplus_processes_respawned Total number of abnormally terminated and respawned child processes
+# TYPE nginxplus_processes_respawned counter
+nginxplus_processes_respawned %v
+# HELP nginxplus_worker_restarts Lower bound of the number of times the worker process was replaced outside configuration reloads, detected by a change of its process ID between two fetches of the stats
+# TYPE nginxplus_worker_restarts counter
+%v# HELP nginxplus_workers Number of worker processes
+# TYPE nginxplus_workers gauge
+nginxplus_workers %v
+`, s.state.respawned, s.restarts, s.workers)
+		if err := testutil.CollectAndCompare(c, strings.NewReader(want),
+			"nginxplus_processes_respawned", "nginxplus_worker_restarts", "nginxplus_workers"); err != nil {
+			t.Errorf("%v: %v", s.name, err)
+		}
+	}
+}
+
+func TestNginxPlusCollectorWorkersBeforeAPIVersion9(t *testing.T) {
+	t.Parallel()
+
+	nginx := newTestPlusServer(t, plusRoutes(map[string]string{
+		"/8/processes": `{"respawned": 2}`,
+	}))
+	c := newTestPlusCollectorOfClient(newTestPlusClient(t, nginx, plusclient.WithAPIVersion(8)), nil, WithSections(SectionWorkers))
+
+	// the API has no workers endpoint, so the number of workers is unknown rather than zero
+	want := `# HELP nginxplus_processes_respawned Total number of abnormally terminated and respawned child processes
+# TYPE nginxplus_processes_respawned counter
+nginxplus_processes_respawned 2
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(want),
+		"nginxplus_processes_respawned", "nginxplus_worker_restarts", "nginxplus_workers"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNginxPlusCollectorWorkerRestartsBetweenPolls(t *testing.T) {
+	t.Parallel()
+
+	var pid atomic.Int32
+	var requests atomic.Int32
+	pid.Store(100)
+	nginx := newTestPlusServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/9/workers" {
+			defer requests.Add(1)
+		}
+		plusRoutes(map[string]string{
+			"/9/nginx":     `{"generation": 1}`,
+			"/9/processes": `{"respawned": 0}`,
+			"/9/workers":   fmt.Sprintf(`[{"id": 0, "pid": %v}]`, pid.Load()),
+		})(w, r)
+	}))
+	c := newTestPlusCollectorOfClient(newTestPlusClient(t, nginx), nil, WithSections(SectionWorkers))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	c.StartPolling(ctx, 10*time.Millisecond, time.Hour)
+
+	// the worker restarts twice between two scrapes, once between every two polls
+	waitForPolls := func(n int32) {
+		deadline := time.Now().Add(5 * time.Second)
+		for requests.Load() < n && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	waitForPolls(1)
+	pid.Store(101)
+	waitForPolls(requests.Load() + 2)
+	pid.Store(102)
+	waitForPolls(requests.Load() + 2)
+
+	want := `# HELP nginxplus_worker_restarts Lower bound of the number of times the worker process was replaced outside configuration reloads, detected by a change of its process ID between two fetches of the stats
+# TYPE nginxplus_worker_restarts counter
+nginxplus_worker_restarts{id="0"} 2
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(want), "nginxplus_worker_restarts"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/nginx/nginx-prometheus-exporter/collector"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	modeOSS  = "oss"
+	modePlus = "plus"
+	modeAuto = "auto"
+)
+
+// config describes the NGINX and NGINX Plus instances scraped by the exporter.
+type config struct {
+	Targets []targetConfig `yaml:"targets"`
+}
+
+// targetConfig describes a single NGINX or NGINX Plus instance.
+type targetConfig struct {
+	ConstLabels    map[string]string       `yaml:"const_labels"`
+	TLS            tlsConfig               `yaml:"tls"`
+	Transport      transportConfig         `yaml:"transport"`
+	Collectors     []collector.Section     `yaml:"collectors"`
+	Filters        map[string]filterConfig `yaml:"filters"`
+	ResponseCodes  map[string]string       `yaml:"response_codes"`
+	KeyVals        keyValConfig            `yaml:"keyvals"`
+	Labels         *variableLabels         `yaml:"-"`
+	LabelExpiry    labelExpiry             `yaml:"-"`
+	LabelStoreDir  string                  `yaml:"-"`
+	LabelZone      string                  `yaml:"-"`
+	URI            string                  `yaml:"uri"`
+	Mode           string                  `yaml:"mode"`
+	Namespace      string                  `yaml:"namespace"`
+	Timeout        time.Duration           `yaml:"timeout"`
+	PollInterval   time.Duration           `yaml:"poll_interval"`
+	StalenessLimit time.Duration           `yaml:"staleness_limit"`
+}
+
+// tlsConfig describes how to connect to an instance over TLS.
+type tlsConfig struct {
+	Verify     *bool  `yaml:"verify"`
+	CACert     string `yaml:"ca_cert"`
+	ClientCert string `yaml:"client_cert"`
+	ClientKey  string `yaml:"client_key"`
+}
+
+// keyValConfig selects the key-value pairs of the NGINX Plus keyval zones exported as info series.
+type keyValConfig struct {
+	// ExportLimit is a pointer so that a limit of zero, counting the matching pairs without exporting any,
+	// is told apart from an unset limit.
+	ExportLimit *int   `yaml:"export_limit"`
+	ExportKeys  string `yaml:"export_keys"`
+}
+
+// exportLimit returns the maximum number of pairs exported per scrape, which is unlimited if it isn't set.
+func (k keyValConfig) exportLimit() int {
+	if k.ExportLimit == nil {
+		return math.MaxInt
+	}
+	return *k.ExportLimit
+}
+
+// filterConfig selects the objects of a family of NGINX Plus objects by name.
+type filterConfig struct {
+	Include string `yaml:"include"`
+	Exclude string `yaml:"exclude"`
+}
+
+// transportConfig describes the connections to an instance.
+type transportConfig struct {
+	DisableKeepAlives   *bool         `yaml:"disable_keep_alives"`
+	MaxIdleConns        int           `yaml:"max_idle_conns"`
+	MaxIdleConnsPerHost int           `yaml:"max_idle_conns_per_host"`
+	MaxConnsPerHost     int           `yaml:"max_conns_per_host"`
+	IdleConnTimeout     time.Duration `yaml:"idle_conn_timeout"`
+	KeepAlive           time.Duration `yaml:"keep_alive"`
+}
+
+// loadConfig reads the configuration file and fills the unset target settings from defaults.
+func loadConfig(path string, defaults targetConfig) (*config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	cfg := &config{}
+	if err := yaml.UnmarshalStrict(content, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %q: %w", path, err)
+	}
+	if len(cfg.Targets) == 0 {
+		return nil, fmt.Errorf("config file %q has no targets", path)
+	}
+
+	cfg.applyDefaults(defaults)
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %q: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// newConfigFromURIs creates a configuration with one target per scrape URI.
+func newConfigFromURIs(uris []string, defaults targetConfig) (*config, error) {
+	cfg := &config{}
+	for _, uri := range uris {
+		cfg.Targets = append(cfg.Targets, targetConfig{URI: uri})
+	}
+
+	cfg.applyDefaults(defaults)
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// equal reports whether c and other describe the same targets with the same settings. The labels are left out,
+// as the targets share the labels of the exporter.
+func (c *config) equal(other *config) bool {
+	return slices.EqualFunc(c.Targets, other.Targets, func(a, b targetConfig) bool {
+		a.Labels, b.Labels = nil, nil
+		return reflect.DeepEqual(a, b)
+	})
+}
+
+func (c *config) applyDefaults(defaults targetConfig) {
+	for i := range c.Targets {
+		t := &c.Targets[i]
+
+		if t.Mode == "" {
+			t.Mode = defaults.Mode
+		}
+		// the namespace of auto targets is set once their mode is detected
+		if t.Namespace == "" && t.Mode != modeAuto {
+			t.Namespace = defaultNamespace(t.Mode)
+		}
+		if t.Timeout == 0 {
+			t.Timeout = defaults.Timeout
+		}
+		if t.PollInterval == 0 {
+			t.PollInterval = defaults.PollInterval
+		}
+		if t.StalenessLimit == 0 {
+			t.StalenessLimit = defaults.StalenessLimit
+		}
+		if t.Collectors == nil {
+			t.Collectors = defaults.Collectors
+		}
+		if t.Labels == nil {
+			t.Labels = defaults.Labels
+		}
+		t.LabelExpiry = defaults.LabelExpiry
+		t.LabelStoreDir = defaults.LabelStoreDir
+		t.LabelZone = defaults.LabelZone
+		if t.KeyVals.ExportKeys == "" {
+			t.KeyVals.ExportKeys = defaults.KeyVals.ExportKeys
+		}
+		if t.KeyVals.ExportLimit == nil {
+			t.KeyVals.ExportLimit = defaults.KeyVals.ExportLimit
+		}
+		for family, filter := range defaults.Filters {
+			if _, ok := t.Filters[family]; ok {
+				continue
+			}
+			if t.Filters == nil {
+				t.Filters = make(map[string]filterConfig)
+			}
+			t.Filters[family] = filter
+		}
+		for family, codes := range defaults.ResponseCodes {
+			if _, ok := t.ResponseCodes[family]; ok {
+				continue
+			}
+			if t.ResponseCodes == nil {
+				t.ResponseCodes = make(map[string]string)
+			}
+			t.ResponseCodes[family] = codes
+		}
+		if t.TLS.Verify == nil {
+			t.TLS.Verify = defaults.TLS.Verify
+		}
+		if t.TLS.CACert == "" {
+			t.TLS.CACert = defaults.TLS.CACert
+		}
+		if t.TLS.ClientCert == "" && t.TLS.ClientKey == "" {
+			t.TLS.ClientCert = defaults.TLS.ClientCert
+			t.TLS.ClientKey = defaults.TLS.ClientKey
+		}
+		t.Transport.applyDefaults(defaults.Transport)
+
+		labels := maps.Clone(defaults.ConstLabels)
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		maps.Copy(labels, t.ConstLabels)
+		// add scrape URI to const labels to tell the targets apart
+		if _, ok := labels["addr"]; !ok && len(c.Targets) > 1 {
+			labels["addr"] = t.URI
+		}
+		t.ConstLabels = labels
+	}
+}
+
+func (t *transportConfig) applyDefaults(defaults transportConfig) {
+	if t.DisableKeepAlives == nil {
+		t.DisableKeepAlives = defaults.DisableKeepAlives
+	}
+	if t.MaxIdleConns == 0 {
+		t.MaxIdleConns = defaults.MaxIdleConns
+	}
+	if t.MaxIdleConnsPerHost == 0 {
+		t.MaxIdleConnsPerHost = defaults.MaxIdleConnsPerHost
+	}
+	if t.MaxConnsPerHost == 0 {
+		t.MaxConnsPerHost = defaults.MaxConnsPerHost
+	}
+	if t.IdleConnTimeout == 0 {
+		t.IdleConnTimeout = defaults.IdleConnTimeout
+	}
+	if t.KeepAlive == 0 {
+		t.KeepAlive = defaults.KeepAlive
+	}
+}
+
+func (c *config) validate() error {
+	uris := make(map[string]bool)
+	for _, t := range c.Targets {
+		if t.URI == "" {
+			return errors.New("target uri is required")
+		}
+		if uris[t.URI] {
+			return fmt.Errorf("target %q is defined more than once", t.URI)
+		}
+		uris[t.URI] = true
+
+		if t.Mode != modeOSS && t.Mode != modePlus && t.Mode != modeAuto {
+			return fmt.Errorf("target %q has unknown mode %q", t.URI, t.Mode)
+		}
+		if t.Timeout < 0 {
+			return fmt.Errorf("target %q has negative timeout %v", t.URI, t.Timeout)
+		}
+		if t.PollInterval < 0 || t.StalenessLimit < 0 {
+			return fmt.Errorf("target %q must not have a negative poll_interval or staleness_limit", t.URI)
+		}
+		for _, section := range t.Collectors {
+			if !slices.Contains(collector.AllSections(), section) {
+				return fmt.Errorf("target %q has unknown collector %q", t.URI, section)
+			}
+		}
+		if _, err := newNameFilters(t.Filters); err != nil {
+			return fmt.Errorf("target %q: %w", t.URI, err)
+		}
+		if _, err := newResponseCodes(t.ResponseCodes); err != nil {
+			return fmt.Errorf("target %q: %w", t.URI, err)
+		}
+		if _, err := collector.NewNameFilter(t.KeyVals.ExportKeys, ""); err != nil {
+			return fmt.Errorf("target %q has invalid keyvals export_keys: %w", t.URI, err)
+		}
+		if t.KeyVals.exportLimit() < 0 {
+			return fmt.Errorf("target %q has negative keyvals export_limit %v", t.URI, t.KeyVals.exportLimit())
+		}
+		if (t.TLS.ClientCert == "") != (t.TLS.ClientKey == "") {
+			return fmt.Errorf("target %q must set both client_cert and client_key", t.URI)
+		}
+		if err := t.Transport.validate(); err != nil {
+			return fmt.Errorf("target %q: %w", t.URI, err)
+		}
+	}
+	return nil
+}
+
+func (t *transportConfig) validate() error {
+	if t.MaxIdleConns < 0 || t.MaxIdleConnsPerHost < 0 || t.MaxConnsPerHost < 0 {
+		return errors.New("connection limits must not be negative")
+	}
+	if t.IdleConnTimeout < 0 || t.KeepAlive < 0 {
+		return errors.New("idle_conn_timeout and keep_alive must not be negative")
+	}
+	return nil
+}
+
+// newNameFilters creates the name filters of the NGINX Plus collector from the filters of a target.
+func newNameFilters(filters map[string]filterConfig) (map[string]collector.NameFilter, error) {
+	nameFilters := make(map[string]collector.NameFilter, len(filters))
+	for family, filter := range filters {
+		if !slices.Contains(collector.FilterFamilies(), family) {
+			return nil, fmt.Errorf("unknown filter family %q", family)
+		}
+		nameFilter, err := collector.NewNameFilter(filter.Include, filter.Exclude)
+		if err != nil {
+			return nil, fmt.Errorf("filter of family %q: %w", family, err)
+		}
+		nameFilters[family] = nameFilter
+	}
+	return nameFilters, nil
+}
+
+// newResponseCodes parses the status codes exposed by the NGINX Plus collector for every family.
+func newResponseCodes(codes map[string]string) (map[string]collector.ResponseCodes, error) {
+	responseCodes := make(map[string]collector.ResponseCodes, len(codes))
+	for family, value := range codes {
+		if !slices.Contains(collector.ResponseCodesFamilies(), family) {
+			return nil, fmt.Errorf("unknown response codes family %q", family)
+		}
+		r, err := collector.ParseResponseCodes(value)
+		if err != nil {
+			return nil, fmt.Errorf("response codes of family %q: %w", family, err)
+		}
+		responseCodes[family] = r
+	}
+	return responseCodes, nil
+}
+
+func defaultNamespace(mode string) string {
+	if mode == modePlus {
+		return "nginxplus"
+	}
+	return "nginx"
+}
+
+// newTLSConfig creates the TLS configuration used to connect to a target.
+func newTLSConfig(cfg tlsConfig) (*tls.Config, error) {
+	// #nosec G402
+	sslConfig := &tls.Config{InsecureSkipVerify: cfg.Verify == nil || !*cfg.Verify}
+	if cfg.CACert != "" {
+		caCert, err := os.ReadFile(cfg.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("loading CA cert failed: %w", err)
+		}
+		sslCaCertPool := x509.NewCertPool()
+		ok := sslCaCertPool.AppendCertsFromPEM(caCert)
+		if !ok {
+			return nil, fmt.Errorf("parsing CA cert file %q failed", cfg.CACert)
+		}
+		sslConfig.RootCAs = sslCaCertPool
+	}
+
+	if cfg.ClientCert != "" && cfg.ClientKey != "" {
+		clientCert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate failed: %w", err)
+		}
+		sslConfig.Certificates = []tls.Certificate{clientCert}
+	}
+
+	return sslConfig, nil
+}
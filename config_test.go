@@ -0,0 +1,323 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nginx/nginx-prometheus-exporter/collector"
+)
+
+func TestLoadConfig(t *testing.T) {
+	t.Parallel()
+
+	verify := true
+	exportLimit := 20
+	defaults := targetConfig{
+		Mode:        modeOSS,
+		Timeout:     5 * time.Second,
+		ConstLabels: map[string]string{"env": "prod"},
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    []targetConfig
+		wantErr bool
+	}{
+		{
+			name: "single target uses defaults",
+			content: `targets:
+  - uri: http://127.0.0.1:8080/stub_status
+`,
+			want: []targetConfig{
+				{
+					URI:         "http://127.0.0.1:8080/stub_status",
+					Mode:        modeOSS,
+					Namespace:   "nginx",
+					Timeout:     5 * time.Second,
+					ConstLabels: map[string]string{"env": "prod"},
+				},
+			},
+		},
+		{
+			name: "multiple targets with their own settings",
+			content: `targets:
+  - uri: http://127.0.0.1:8080/stub_status
+  - uri: https://plus.example.com/api
+    mode: plus
+    timeout: 10s
+    namespace: plus
+    const_labels:
+      env: staging
+      dc: eu
+    tls:
+      verify: true
+`,
+			want: []targetConfig{
+				{
+					URI:         "http://127.0.0.1:8080/stub_status",
+					Mode:        modeOSS,
+					Namespace:   "nginx",
+					Timeout:     5 * time.Second,
+					ConstLabels: map[string]string{"env": "prod", "addr": "http://127.0.0.1:8080/stub_status"},
+				},
+				{
+					URI:         "https://plus.example.com/api",
+					Mode:        modePlus,
+					Namespace:   "plus",
+					Timeout:     10 * time.Second,
+					ConstLabels: map[string]string{"env": "staging", "dc": "eu", "addr": "https://plus.example.com/api"},
+					TLS:         tlsConfig{Verify: &verify},
+				},
+			},
+		},
+		{
+			name:    "no targets",
+			content: "targets: []\n",
+			wantErr: true,
+		},
+		{
+			name: "unknown field",
+			content: `targets:
+  - url: http://127.0.0.1:8080/stub_status
+`,
+			wantErr: true,
+		},
+		{
+			name: "unknown mode",
+			content: `targets:
+  - uri: http://127.0.0.1:8080/stub_status
+    mode: foo
+`,
+			wantErr: true,
+		},
+		{
+			name: "duplicate target",
+			content: `targets:
+  - uri: http://127.0.0.1:8080/stub_status
+  - uri: http://127.0.0.1:8080/stub_status
+`,
+			wantErr: true,
+		},
+		{
+			name: "target with its own keyvals export",
+			content: `targets:
+  - uri: https://plus.example.com/api
+    mode: plus
+    collectors: [keyvals]
+    keyvals:
+      export_keys: flags/.*
+      export_limit: 20
+`,
+			want: []targetConfig{
+				{
+					URI:         "https://plus.example.com/api",
+					Mode:        modePlus,
+					Namespace:   "nginxplus",
+					Timeout:     5 * time.Second,
+					ConstLabels: map[string]string{"env": "prod"},
+					Collectors:  []collector.Section{collector.SectionKeyVals},
+					KeyVals:     keyValConfig{ExportKeys: "flags/.*", ExportLimit: &exportLimit},
+				},
+			},
+		},
+		{
+			name: "invalid keyvals export keys",
+			content: `targets:
+  - uri: https://plus.example.com/api
+    mode: plus
+    keyvals:
+      export_keys: "flags/("
+`,
+			wantErr: true,
+		},
+		{
+			name: "negative keyvals export limit",
+			content: `targets:
+  - uri: https://plus.example.com/api
+    mode: plus
+    keyvals:
+      export_limit: -1
+`,
+			wantErr: true,
+		},
+		{
+			name: "target with its own collectors",
+			content: `targets:
+  - uri: https://plus.example.com/api
+    mode: plus
+    collectors: [upstreams, server-zones]
+`,
+			want: []targetConfig{
+				{
+					URI:         "https://plus.example.com/api",
+					Mode:        modePlus,
+					Namespace:   "nginxplus",
+					Timeout:     5 * time.Second,
+					ConstLabels: map[string]string{"env": "prod"},
+					Collectors:  []collector.Section{collector.SectionUpstreams, collector.SectionServerZones},
+				},
+			},
+		},
+		{
+			name: "unknown collector",
+			content: `targets:
+  - uri: https://plus.example.com/api
+    mode: plus
+    collectors: [upstream]
+`,
+			wantErr: true,
+		},
+		{
+			name: "target with its own filters",
+			content: `targets:
+  - uri: https://plus.example.com/api
+    mode: plus
+    filters:
+      upstream:
+        exclude: tmp-.*
+`,
+			want: []targetConfig{
+				{
+					URI:         "https://plus.example.com/api",
+					Mode:        modePlus,
+					Namespace:   "nginxplus",
+					Timeout:     5 * time.Second,
+					ConstLabels: map[string]string{"env": "prod"},
+					Filters:     map[string]filterConfig{"upstream": {Exclude: "tmp-.*"}},
+				},
+			},
+		},
+		{
+			name: "unknown filter family",
+			content: `targets:
+  - uri: https://plus.example.com/api
+    filters:
+      upstreams:
+        include: app-.*
+`,
+			wantErr: true,
+		},
+		{
+			name: "invalid filter expression",
+			content: `targets:
+  - uri: https://plus.example.com/api
+    filters:
+      upstream:
+        include: app-(
+`,
+			wantErr: true,
+		},
+		{
+			name: "invalid response codes",
+			content: `targets:
+  - uri: https://plus.example.com/api
+    response_codes:
+      upstream: 2xx
+`,
+			wantErr: true,
+		},
+		{
+			name: "client cert without key",
+			content: `targets:
+  - uri: https://127.0.0.1:8443/api
+    tls:
+      client_cert: /etc/nginx/client.pem
+`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "config.yml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := loadConfig(path, defaults)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(cfg.Targets, tt.want) {
+				t.Errorf("loadConfig() = %+v, want %+v", cfg.Targets, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewConfigFromURIs(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := newConfigFromURIs([]string{"http://127.0.0.1:8080/api"}, targetConfig{Mode: modePlus})
+	if err != nil {
+		t.Fatalf("newConfigFromURIs() returned error: %v", err)
+	}
+	want := []targetConfig{
+		{
+			URI:         "http://127.0.0.1:8080/api",
+			Mode:        modePlus,
+			Namespace:   "nginxplus",
+			ConstLabels: map[string]string{},
+		},
+	}
+	if !reflect.DeepEqual(cfg.Targets, want) {
+		t.Errorf("newConfigFromURIs() = %+v, want %+v", cfg.Targets, want)
+	}
+}
+
+func TestApplyDefaultsFilters(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config{
+		Targets: []targetConfig{
+			{URI: "http://127.0.0.1:8080/api", Filters: map[string]filterConfig{"upstream": {Exclude: "tmp-.*"}}},
+			{URI: "http://127.0.0.2:8080/api"},
+		},
+	}
+	cfg.applyDefaults(targetConfig{
+		Mode: modePlus,
+		Filters: map[string]filterConfig{
+			"upstream":      {Include: "app-.*"},
+			"location-zone": {Include: "api-.*"},
+		},
+	})
+
+	want := []map[string]filterConfig{
+		{"upstream": {Exclude: "tmp-.*"}, "location-zone": {Include: "api-.*"}},
+		{"upstream": {Include: "app-.*"}, "location-zone": {Include: "api-.*"}},
+	}
+	for i, target := range cfg.Targets {
+		if !reflect.DeepEqual(target.Filters, want[i]) {
+			t.Errorf("target %v filters = %+v, want %+v", target.URI, target.Filters, want[i])
+		}
+	}
+}
+
+func TestApplyDefaultsKeyValExportLimit(t *testing.T) {
+	t.Parallel()
+
+	none, own, limit := 0, 20, 100
+	cfg := &config{
+		Targets: []targetConfig{
+			{URI: "http://127.0.0.1:8080/api", KeyVals: keyValConfig{ExportLimit: &none}},
+			{URI: "http://127.0.0.2:8080/api", KeyVals: keyValConfig{ExportLimit: &own}},
+			{URI: "http://127.0.0.3:8080/api"},
+		},
+	}
+	cfg.applyDefaults(targetConfig{Mode: modePlus, KeyVals: keyValConfig{ExportLimit: &limit}})
+
+	// a limit of zero is kept rather than replaced by the default
+	want := []int{0, 20, 100}
+	for i, target := range cfg.Targets {
+		if got := target.KeyVals.exportLimit(); got != want[i] {
+			t.Errorf("target %v export limit = %v, want %v", target.URI, got, want[i])
+		}
+	}
+}
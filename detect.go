@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nginx/nginx-prometheus-exporter/client"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxDetectBodySize limits how much of the response is read when detecting the mode of a target.
+const maxDetectBodySize = 1 << 20
+
+var errUnknownEndpoint = errors.New("endpoint is neither an NGINX Plus API nor a stub_status page")
+
+// detectMode fetches addr and returns the mode of the endpoint. For an NGINX Plus API root, which
+// lists the API versions, the versions are returned as well, newest first.
+func detectMode(ctx context.Context, httpClient *http.Client, addr string) (string, []int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, addr, nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get %v: %w", addr, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("expected %v response, got %v", http.StatusOK, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDetectBodySize))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read the response body: %w", err)
+	}
+
+	var versions []int
+	if err := json.Unmarshal(body, &versions); err == nil && len(versions) > 0 {
+		slices.Sort(versions)
+		slices.Reverse(versions)
+		return modePlus, versions, nil
+	}
+
+	if _, err := client.ParseStubStats(bytes.NewReader(body)); err == nil {
+		return modeOSS, nil, nil
+	}
+
+	return "", nil, errUnknownEndpoint
+}
+
+// autoCollector detects on the first scrape whether the target is an NGINX Plus API or a
+// stub_status page and then delegates to the matching collector. Detection is retried on
+// the next scrape if it fails. The collector is unchecked, as its metrics aren't known
+// until the target has been detected.
+type autoCollector struct {
+	logger     *slog.Logger
+	httpClient *http.Client
+	collector  prometheus.Collector
+	upDesc     *prometheus.Desc
+	poll       func(pollingCollector)
+	addr       string
+	target     targetConfig
+	mutex      sync.Mutex
+	// claimed is set once the collector must write the label file of the target, and released once it no
+	// longer must.
+	claimed  bool
+	released bool
+}
+
+func newAutoCollector(logger *slog.Logger, httpClient *http.Client, addr string, target targetConfig) *autoCollector {
+	// until the target is detected, it is reported as down under the namespace of stub_status
+	namespace := target.Namespace
+	if namespace == "" {
+		namespace = defaultNamespace(modeOSS)
+	}
+	return &autoCollector{
+		logger:     logger,
+		httpClient: httpClient,
+		addr:       addr,
+		target:     target,
+		upDesc:     prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "up"), "Status of the last metric scrape", nil, target.ConstLabels),
+	}
+}
+
+// StartPolling makes the collector poll the target in the background once it has been detected.
+func (c *autoCollector) StartPolling(ctx context.Context, interval, staleness time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.poll = func(p pollingCollector) {
+		p.StartPolling(ctx, interval, staleness)
+	}
+}
+
+// ClaimLabelFile makes the detected collector the writer of the label file of the target, now or once the target
+// is detected.
+func (c *autoCollector) ClaimLabelFile() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.claimed = true
+	if inner, ok := c.collector.(labelFileCollector); ok {
+		inner.ClaimLabelFile()
+	}
+}
+
+// ReleaseLabelFile stops the detected collector from writing the label file of the target, now or once the target
+// is detected.
+func (c *autoCollector) ReleaseLabelFile() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.released = true
+	if inner, ok := c.collector.(labelFileCollector); ok {
+		inner.ReleaseLabelFile()
+	}
+}
+
+// UpDesc returns the descriptor of the up metric of the detected collector, or of the collector itself until the
+// target is detected.
+func (c *autoCollector) UpDesc() *prometheus.Desc {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if inner, ok := c.collector.(upCollector); ok {
+		return inner.UpDesc()
+	}
+	return c.upDesc
+}
+
+// Describe implements prometheus.Collector. It sends no descriptors, making the collector unchecked.
+func (c *autoCollector) Describe(_ chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (c *autoCollector) Collect(ch chan<- prometheus.Metric) {
+	_ = c.Scrape(context.Background(), ch)
+}
+
+// Scrape detects the target if needed, then collects its metrics and returns the error
+// that prevented getting them, if any.
+func (c *autoCollector) Scrape(ctx context.Context, ch chan<- prometheus.Metric) error {
+	inner, err := c.detect(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			c.logger.Debug("scrape cancelled", "target", c.target.URI, "error", err.Error())
+		} else {
+			c.logger.Error("detecting the type of the target failed", "target", c.target.URI, "error", err.Error())
+		}
+		ch <- prometheus.MustNewConstMetric(c.upDesc, prometheus.GaugeValue, 0)
+		return err
+	}
+	return scrape(ctx, inner, ch)
+}
+
+func (c *autoCollector) detect(ctx context.Context) (prometheus.Collector, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.collector != nil {
+		return c.collector, nil
+	}
+
+	mode, versions, err := detectMode(ctx, c.httpClient, c.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	target := c.target
+	target.Mode = mode
+	if target.Namespace == "" {
+		target.Namespace = defaultNamespace(mode)
+	}
+
+	if mode == modeOSS {
+		inner, err := newModeCollector(c.logger, c.httpClient, c.addr, target, 0)
+		if err != nil {
+			return nil, err
+		}
+		c.logger.Info("detected NGINX stub_status", "target", c.target.URI)
+		return c.use(inner), nil
+	}
+
+	// use the newest API version that the NGINX Plus client supports
+	for _, version := range versions {
+		inner, err := newModeCollector(c.logger, c.httpClient, c.addr, target, version)
+		if err != nil {
+			continue
+		}
+		c.logger.Info("detected NGINX Plus API", "target", c.target.URI, "api_version", version)
+		return c.use(inner), nil
+	}
+	return nil, fmt.Errorf("none of the NGINX Plus API versions %v is supported", versions)
+}
+
+// use makes inner the collector of the detected target, polling it if requested.
+func (c *autoCollector) use(inner prometheus.Collector) prometheus.Collector {
+	if p, ok := inner.(pollingCollector); ok && c.poll != nil {
+		c.poll(p)
+	}
+	if l, ok := inner.(labelFileCollector); ok {
+		switch {
+		case c.released:
+			l.ReleaseLabelFile()
+		case c.claimed:
+			l.ClaimLabelFile()
+		}
+	}
+	c.collector = inner
+	return inner
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nginx/nginx-prometheus-exporter/collector"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestDetectMode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		body         string
+		wantMode     string
+		wantVersions []int
+		status       int
+		wantErr      bool
+	}{
+		{
+			name:         "plus api root",
+			body:         "[1,2,3,4,5,6,7,8,9]",
+			status:       http.StatusOK,
+			wantMode:     modePlus,
+			wantVersions: []int{9, 8, 7, 6, 5, 4, 3, 2, 1},
+		},
+		{
+			name:     "stub status",
+			body:     validStubStatus,
+			status:   http.StatusOK,
+			wantMode: modeOSS,
+		},
+		{
+			name:    "empty version list",
+			body:    "[]",
+			status:  http.StatusOK,
+			wantErr: true,
+		},
+		{
+			name:    "unknown content",
+			body:    "<html></html>",
+			status:  http.StatusOK,
+			wantErr: true,
+		},
+		{
+			name:    "error status",
+			body:    validStubStatus,
+			status:  http.StatusNotFound,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = io.WriteString(w, tt.body)
+			}))
+			t.Cleanup(server.Close)
+
+			mode, versions, err := detectMode(context.Background(), server.Client(), server.URL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("detectMode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if mode != tt.wantMode {
+				t.Errorf("detectMode() mode = %v, want %v", mode, tt.wantMode)
+			}
+			if !reflect.DeepEqual(versions, tt.wantVersions) {
+				t.Errorf("detectMode() versions = %v, want %v", versions, tt.wantVersions)
+			}
+		})
+	}
+}
+
+func TestAutoCollector(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		body        string
+		wantMode    string
+		wantVersion int
+		wantErr     bool
+	}{
+		{
+			name:     "stub status",
+			body:     validStubStatus,
+			wantMode: modeOSS,
+		},
+		{
+			name:        "newest version supported by the client",
+			body:        "[1,2,3,4,5,6,7,8,9,10,11]",
+			wantMode:    modePlus,
+			wantVersion: 9,
+		},
+		{
+			name:        "newest version of an older server",
+			body:        "[1,2,3,4,5,6]",
+			wantMode:    modePlus,
+			wantVersion: 6,
+		},
+		{
+			name:    "no supported version",
+			body:    "[1,2,3]",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var (
+				mutex sync.Mutex
+				paths []string
+			)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/" {
+					mutex.Lock()
+					paths = append(paths, r.URL.Path)
+					mutex.Unlock()
+					http.NotFound(w, r)
+					return
+				}
+				_, _ = io.WriteString(w, tt.body)
+			}))
+			t.Cleanup(server.Close)
+
+			c := newAutoCollector(slog.New(slog.NewTextHandler(io.Discard, nil)), server.Client(), server.URL, targetConfig{URI: server.URL, Mode: modeAuto})
+			inner, err := c.detect(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("detect() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			switch tt.wantMode {
+			case modeOSS:
+				if _, ok := inner.(*collector.NginxCollector); !ok {
+					t.Errorf("detect() returned %T, want *collector.NginxCollector", inner)
+				}
+			case modePlus:
+				if _, ok := inner.(*collector.NginxPlusCollector); !ok {
+					t.Fatalf("detect() returned %T, want *collector.NginxPlusCollector", inner)
+				}
+				// the API version shows in the paths requested by the collector
+				ch := make(chan prometheus.Metric)
+				go func() {
+					inner.Collect(ch)
+					close(ch)
+				}()
+				for range ch {
+				}
+				mutex.Lock()
+				defer mutex.Unlock()
+				prefix := fmt.Sprintf("/%d/", tt.wantVersion)
+				if len(paths) == 0 || !strings.HasPrefix(paths[0], prefix) {
+					t.Errorf("collector requested %v, want paths starting with %v", paths, prefix)
+				}
+			}
+			if again, _ := c.detect(context.Background()); again != inner {
+				t.Error("detect() did not reuse the detected collector")
+			}
+		})
+	}
+}
+
+func TestAutoCollectorCollectsStubStatus(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, validStubStatus)
+	}))
+	t.Cleanup(server.Close)
+
+	c := newAutoCollector(slog.New(slog.NewTextHandler(io.Discard, nil)), server.Client(), server.URL, targetConfig{URI: server.URL, Mode: modeAuto})
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+
+	if got := countMetrics(t, registry, "nginx_connections_active"); got != 1 {
+		t.Errorf("got %v nginx_connections_active metrics, want 1", got)
+	}
+}
+
+func TestAutoCollectorUnreachableTarget(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, validStubStatus)
+	}))
+	// the target refuses the connections
+	server.Close()
+
+	tests := []struct {
+		name      string
+		namespace string
+		wantName  string
+	}{
+		{
+			name:     "default namespace",
+			wantName: "nginx_up",
+		},
+		{
+			name:      "namespace of the target",
+			namespace: "edge",
+			wantName:  "edge_up",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			target := targetConfig{URI: server.URL, Mode: modeAuto, Namespace: tt.namespace, ConstLabels: map[string]string{"addr": server.URL}}
+			c := newAutoCollector(slog.New(slog.NewTextHandler(io.Discard, nil)), server.Client(), server.URL, target)
+			want := fmt.Sprintf(`# HELP %[1]v Status of the last metric scrape
+# TYPE %[1]v gauge
+%[1]v{addr=%[2]q} 0
+`, tt.wantName, server.URL)
+			if err := testutil.CollectAndCompare(c, strings.NewReader(want)); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -2,16 +2,14 @@ package main
 
 import (
 	"context"
-	"crypto/tls"
-	"crypto/x509"
 	"errors"
 	"fmt"
 	"log/slog"
-	"maps"
-	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -56,10 +54,77 @@ func parsePositiveDuration(s string) (positiveDuration, error) {
 	return positiveDuration{dur}, nil
 }
 
-func createPositiveDurationFlag(s kingpin.Settings) (target *time.Duration) {
-	target = new(time.Duration)
-	s.SetValue(&positiveDuration{Duration: *target})
-	return
+func createPositiveDurationFlag(s kingpin.Settings) *time.Duration {
+	pd := &positiveDuration{}
+	s.SetValue(pd)
+	return &pd.Duration
+}
+
+// createSectionFlags registers the --collector.<section> flag of every NGINX Plus section.
+func createSectionFlags() map[collector.Section]*bool {
+	flags := make(map[collector.Section]*bool)
+	for _, section := range collector.AllSections() {
+		name := string(section)
+		enabled := strconv.FormatBool(slices.Contains(collector.DefaultSections(), section))
+		flags[section] = kingpin.Flag("collector."+name, fmt.Sprintf("Collect the %s metrics of NGINX Plus.", strings.ReplaceAll(name, "-", " "))).Default(enabled).Envar(convertFlagToEnvar("collector." + name)).Bool()
+	}
+	return flags
+}
+
+// enabledSections returns the NGINX Plus sections enabled by the --collector.<section> flags.
+func enabledSections() []collector.Section {
+	sections := []collector.Section{}
+	for _, section := range collector.AllSections() {
+		if *sectionFlags[section] {
+			sections = append(sections, section)
+		}
+	}
+	return sections
+}
+
+// createFilterFlags registers the --filter.<family>.include and --filter.<family>.exclude flags of every
+// family of NGINX Plus objects.
+func createFilterFlags() map[string]*filterConfig {
+	flags := make(map[string]*filterConfig)
+	for _, family := range collector.FilterFamilies() {
+		name := strings.ReplaceAll(family, "-", " ")
+		f := &filterConfig{}
+		kingpin.Flag("filter."+family+".include", fmt.Sprintf("Regular expression matching the full names of the %s objects of NGINX Plus to collect.", name)).Envar(convertFlagToEnvar("filter." + family + ".include")).StringVar(&f.Include)
+		kingpin.Flag("filter."+family+".exclude", fmt.Sprintf("Regular expression matching the full names of the %s objects of NGINX Plus to leave out. Takes precedence over the include expression.", name)).Envar(convertFlagToEnvar("filter." + family + ".exclude")).StringVar(&f.Exclude)
+		flags[family] = f
+	}
+	return flags
+}
+
+// flagFilters returns the name filters set by the --filter flags.
+func flagFilters() map[string]filterConfig {
+	filters := make(map[string]filterConfig)
+	for family, f := range filterFlags {
+		if f.Include != "" || f.Exclude != "" {
+			filters[family] = *f
+		}
+	}
+	return filters
+}
+
+// createResponseCodesFlags registers the --response-codes.<family> flag of every family of NGINX Plus objects
+// that report their responses per status code.
+func createResponseCodesFlags() map[string]*string {
+	flags := make(map[string]*string)
+	for _, family := range collector.ResponseCodesFamilies() {
+		help := fmt.Sprintf("Status codes exposed in the responses_codes metrics of the %s objects of NGINX Plus: all, classes for none of them, or a comma-separated list of codes.", strings.ReplaceAll(family, "-", " "))
+		flags[family] = kingpin.Flag("response-codes."+family, help).Default(collector.ResponseCodesAll).Envar(convertFlagToEnvar("response-codes." + family)).String()
+	}
+	return flags
+}
+
+// flagResponseCodes returns the status codes set by the --response-codes flags.
+func flagResponseCodes() map[string]string {
+	codes := make(map[string]string)
+	for family, value := range codesFlags {
+		codes[family] = *value
+	}
+	return codes
 }
 
 func parseUnixSocketAddress(address string) (string, string, error) {
@@ -82,17 +147,49 @@ var (
 	constLabels = map[string]string{}
 
 	// Command-line flags.
-	webConfig     = kingpinflag.AddFlags(kingpin.CommandLine, ":9113")
-	metricsPath   = kingpin.Flag("web.telemetry-path", "Path under which to expose metrics.").Default("/metrics").Envar("TELEMETRY_PATH").String()
-	nginxPlus     = kingpin.Flag("nginx.plus", "Start the exporter for NGINX Plus. By default, the exporter is started for NGINX.").Default("false").Envar("NGINX_PLUS").Bool()
-	scrapeURIs    = kingpin.Flag("nginx.scrape-uri", "A URI or unix domain socket path for scraping NGINX or NGINX Plus metrics. For NGINX, the stub_status page must be available through the URI. For NGINX Plus -- the API. Repeatable for multiple URIs.").Default("http://127.0.0.1:8080/stub_status").Envar("SCRAPE_URI").HintOptions("http://127.0.0.1:8080/stub_status", "http://127.0.0.1:8080/api").Strings()
-	sslVerify     = kingpin.Flag("nginx.ssl-verify", "Perform SSL certificate verification.").Default("false").Envar("SSL_VERIFY").Bool()
-	sslCaCert     = kingpin.Flag("nginx.ssl-ca-cert", "Path to the PEM encoded CA certificate file used to validate the servers SSL certificate.").Default("").Envar("SSL_CA_CERT").String()
-	sslClientCert = kingpin.Flag("nginx.ssl-client-cert", "Path to the PEM encoded client certificate file to use when connecting to the server.").Default("").Envar("SSL_CLIENT_CERT").String()
-	sslClientKey  = kingpin.Flag("nginx.ssl-client-key", "Path to the PEM encoded client certificate key file to use when connecting to the server.").Default("").Envar("SSL_CLIENT_KEY").String()
+	webConfig           = kingpinflag.AddFlags(kingpin.CommandLine, ":9113")
+	metricsPath         = kingpin.Flag("web.telemetry-path", "Path under which to expose metrics.").Default("/metrics").Envar("TELEMETRY_PATH").String()
+	nginxPlus           = kingpin.Flag("nginx.plus", "Start the exporter for NGINX Plus. By default, the exporter is started for NGINX.").Default("false").Envar("NGINX_PLUS").Bool()
+	nginxMode           = kingpin.Flag("nginx.mode", "Type of the scraped instances: oss, plus or auto. With auto, the type and the newest supported NGINX Plus API version are detected for every URI. Overrides --nginx.plus.").Default("").Envar("NGINX_MODE").HintOptions(modeOSS, modePlus, modeAuto).String()
+	scrapeURIs          = kingpin.Flag("nginx.scrape-uri", "A URI or unix domain socket path for scraping NGINX or NGINX Plus metrics. For NGINX, the stub_status page must be available through the URI. For NGINX Plus -- the API. Repeatable for multiple URIs.").Default("http://127.0.0.1:8080/stub_status").Envar("SCRAPE_URI").HintOptions("http://127.0.0.1:8080/stub_status", "http://127.0.0.1:8080/api").Strings()
+	sslVerify           = kingpin.Flag("nginx.ssl-verify", "Perform SSL certificate verification.").Default("false").Envar("SSL_VERIFY").Bool()
+	sslCaCert           = kingpin.Flag("nginx.ssl-ca-cert", "Path to the PEM encoded CA certificate file used to validate the servers SSL certificate.").Default("").Envar("SSL_CA_CERT").String()
+	sslClientCert       = kingpin.Flag("nginx.ssl-client-cert", "Path to the PEM encoded client certificate file to use when connecting to the server.").Default("").Envar("SSL_CLIENT_CERT").String()
+	sslClientKey        = kingpin.Flag("nginx.ssl-client-key", "Path to the PEM encoded client certificate key file to use when connecting to the server.").Default("").Envar("SSL_CLIENT_KEY").String()
+	configFile          = kingpin.Flag("config.file", "Path to a YAML file describing the targets to scrape. When set, --nginx.scrape-uri is ignored and the other --nginx flags are the defaults of the targets.").Default("").Envar("TARGETS_CONFIG_FILE").String()
+	configCheck         = kingpin.Flag("config.check", "Check the configuration file and exit, without scraping or polling the targets.").Default("false").Bool()
+	labelsFile          = kingpin.Flag("labels.file", "Path to a YAML file declaring variable label names of NGINX Plus objects per kind, their values per object name and rules deriving them from the object names. The file is reloaded like the configuration file.").Default("").Envar("LABELS_FILE").String()
+	labelsExpireScrapes = kingpin.Flag("labels.expire-after-scrapes", "Number of successful scrapes of a target after which the variable label values of an NGINX Plus object that is no longer reported are removed. Zero disables the expiry.").Default("0").Envar("LABELS_EXPIRE_AFTER_SCRAPES").Int()
+	labelsStoreDir      = kingpin.Flag("labels.store-dir", "Directory in which the variable label values of NGINX Plus objects are saved, in a file per target, so that they survive a restart of the exporter. An empty value disables the saving.").Default("").Envar("LABELS_STORE_DIR").String()
+	labelsKeyValZone    = kingpin.Flag("labels.keyval-zone", "Name of an HTTP keyval zone of NGINX Plus from which the variable label values of NGINX Plus objects are read on every fetch of the stats. The keys are kind:name, and the values are labels in URL query encoding, for example team=payments&tier=gold. An empty value disables the zone.").Default("").Envar("LABELS_KEYVAL_ZONE").String()
+	keyValExportKeys    = kingpin.Flag("keyval.export-keys", "Regular expression matching the full names, zone/key, of the key-value pairs of NGINX Plus keyval zones to export as info series with their values. Requires the keyvals collector. An empty value exports no pairs.").Default("").Envar("KEYVAL_EXPORT_KEYS").String()
+	keyValExportLimit   = kingpin.Flag("keyval.export-limit", "Maximum number of key-value pairs exported as info series per scrape. The other matching pairs are counted in the nginx_exporter_keyval_keys_dropped gauge of the scrape.").Default("100").Envar("KEYVAL_EXPORT_LIMIT").Int()
+	adminAddress        = kingpin.Flag("admin.listen-address", "Address on which to expose the admin API for changing the variable labels of NGINX Plus objects. Requires --labels.file. An empty value disables the API.").Default("").Envar("ADMIN_LISTEN_ADDRESS").String()
+	adminTokenFile      = kingpin.Flag("admin.token-file", "Path to a file with the bearer token required by the admin API.").Default("").Envar("ADMIN_TOKEN_FILE").String()
+	adminWebConfigFile  = kingpin.Flag("admin.web-config-file", "Path to a web configuration file enabling TLS on the admin API. With client_auth_type RequireAndVerifyClientCert, the clients are authenticated by their certificates.").Default("").Envar("ADMIN_WEB_CONFIG_FILE").String()
+	maxIdleConns        = kingpin.Flag("nginx.max-idle-conns", "Maximum number of idle connections kept open to a target. Zero means no limit.").Default("100").Envar("MAX_IDLE_CONNS").Int()
+	maxIdleConnsPerHost = kingpin.Flag("nginx.max-idle-conns-per-host", "Maximum number of idle connections kept open to each host of a target.").Default("4").Envar("MAX_IDLE_CONNS_PER_HOST").Int()
+	maxConnsPerHost     = kingpin.Flag("nginx.max-conns-per-host", "Maximum number of connections to each host of a target. Zero means no limit.").Default("0").Envar("MAX_CONNS_PER_HOST").Int()
+	disableKeepAlives   = kingpin.Flag("nginx.disable-keep-alives", "Open a new connection for every request to a target.").Default("false").Envar("DISABLE_KEEP_ALIVES").Bool()
+	scrapeConcurrency   = kingpin.Flag("scrape.concurrency", "Maximum number of targets scraped at the same time.").Default("10").Envar("SCRAPE_CONCURRENCY").Int()
+	probePath           = kingpin.Flag("web.probe-path", "Path under which to expose the probe endpoint for scraping the target given by the target query parameter. An empty value disables the endpoint.").Default("/probe").String()
+	probeCIDRs          = kingpin.Flag("probe.allowed-target-cidr", "Network in CIDR notation that probe targets are allowed to connect to. Repeatable for multiple networks.").Envar("PROBE_ALLOWED_TARGET_CIDR").Strings()
+	probeRegexps        = kingpin.Flag("probe.allowed-target-regex", "Regular expression matching the full probe targets that are allowed. Repeatable for multiple expressions.").Envar("PROBE_ALLOWED_TARGET_REGEX").Strings()
 
 	// Custom command-line flags.
-	timeout = createPositiveDurationFlag(kingpin.Flag("nginx.timeout", "A timeout for scraping metrics from NGINX or NGINX Plus.").Default("5s").Envar("TIMEOUT").HintOptions("5s", "10s", "30s", "1m", "5m"))
+	timeout             = createPositiveDurationFlag(kingpin.Flag("nginx.timeout", "A timeout for scraping metrics from NGINX or NGINX Plus.").Default("5s").Envar("TIMEOUT").HintOptions("5s", "10s", "30s", "1m", "5m"))
+	reloadInterval      = createPositiveDurationFlag(kingpin.Flag("config.reload-interval", "How often to check the configuration file for changes. Zero disables the check, the configuration is still reloaded on SIGHUP.").Default("0s").Envar("CONFIG_RELOAD_INTERVAL"))
+	idleConnTimeout     = createPositiveDurationFlag(kingpin.Flag("nginx.idle-conn-timeout", "How long an idle connection to a target is kept open. Zero means no limit.").Default("90s").Envar("IDLE_CONN_TIMEOUT"))
+	scrapeTimeoutOffset = createPositiveDurationFlag(kingpin.Flag("scrape.timeout-offset", "Offset to subtract from the scrape timeout sent by Prometheus in the X-Prometheus-Scrape-Timeout-Seconds header, so that the exporter answers before Prometheus gives up.").Default("500ms").Envar("SCRAPE_TIMEOUT_OFFSET"))
+	labelsExpireAfter   = createPositiveDurationFlag(kingpin.Flag("labels.expire-after", "Time after which the variable label values of an NGINX Plus object that is no longer reported are removed. Zero disables the expiry.").Default("0s").Envar("LABELS_EXPIRE_AFTER"))
+	pollInterval        = createPositiveDurationFlag(kingpin.Flag("nginx.poll-interval", "How often to poll NGINX or NGINX Plus for stats in the background. Scrapes are then served from the latest stats instead of querying NGINX. Zero disables polling.").Default("0s").Envar("POLL_INTERVAL"))
+	stalenessLimit      = createPositiveDurationFlag(kingpin.Flag("nginx.staleness-limit", "Age after which polled stats are considered stale and the target is reported as down. Zero means three poll intervals.").Default("0s").Envar("STALENESS_LIMIT"))
+	keepAlive           = createPositiveDurationFlag(kingpin.Flag("nginx.keep-alive", "Interval between TCP keep-alive probes on connections to a target.").Default("30s").Envar("KEEP_ALIVE"))
+
+	// NGINX Plus sections
+	sectionFlags = createSectionFlags()
+	filterFlags  = createFilterFlags()
+	codesFlags   = createResponseCodesFlags()
 )
 
 const exporterName = "nginx_exporter"
@@ -109,71 +206,87 @@ func main() {
 		}
 	}
 
-	config := &promslog.Config{}
+	logConfig := &promslog.Config{}
 
-	flag.AddFlags(kingpin.CommandLine, config)
+	flag.AddFlags(kingpin.CommandLine, logConfig)
 	kingpin.Version(common_version.Print(exporterName))
 	kingpin.HelpFlag.Short('h')
 
 	addMissingEnvironmentFlags(kingpin.CommandLine)
 
 	kingpin.Parse()
-	logger := promslog.New(config)
+	logger := promslog.New(logConfig)
 
 	logger.Info("nginx-prometheus-exporter", "version", common_version.Info())
 	logger.Info("build context", "build_context", common_version.BuildContext())
 
 	prometheus.MustRegister(version.NewCollector(exporterName))
 
-	if len(*scrapeURIs) == 0 {
-		logger.Error("no scrape addresses provided")
-		os.Exit(1)
-	}
-
-	// #nosec G402
-	sslConfig := &tls.Config{InsecureSkipVerify: !*sslVerify}
-	if *sslCaCert != "" {
-		caCert, err := os.ReadFile(*sslCaCert)
+	defaults := flagTargetDefaults()
+	if *labelsFile != "" {
+		labels, err := loadLabels(*labelsFile)
 		if err != nil {
-			logger.Error("loading CA cert failed", "err", err.Error())
+			logger.Error("loading labels failed", "error", err.Error())
 			os.Exit(1)
 		}
-		sslCaCertPool := x509.NewCertPool()
-		ok := sslCaCertPool.AppendCertsFromPEM(caCert)
-		if !ok {
-			logger.Error("parsing CA cert file failed.")
-			os.Exit(1)
+		defaults.Labels.set(labels)
+	}
+	loadTargets := func() (*config, error) {
+		if *configFile != "" {
+			return loadConfig(*configFile, defaults)
+		}
+		if len(*scrapeURIs) == 0 {
+			return nil, errors.New("no scrape addresses provided")
 		}
-		sslConfig.RootCAs = sslCaCertPool
+		return newConfigFromURIs(*scrapeURIs, defaults)
 	}
 
-	if *sslClientCert != "" && *sslClientKey != "" {
-		clientCert, err := tls.LoadX509KeyPair(*sslClientCert, *sslClientKey)
-		if err != nil {
-			logger.Error("loading client certificate failed", "error", err.Error())
+	cfg, err := loadTargets()
+	if err != nil {
+		logger.Error("loading configuration failed", "error", err.Error())
+		os.Exit(1)
+	}
+
+	if *scrapeConcurrency < 1 {
+		logger.Error("--scrape.concurrency must be at least 1", "value", *scrapeConcurrency)
+		os.Exit(1)
+	}
+
+	// the admin API only changes the values of the label names declared in the labels file
+	if (*adminAddress != "" || *adminTokenFile != "" || *adminWebConfigFile != "") && *labelsFile == "" {
+		logger.Error("the --admin flags require --labels.file, which declares the label names")
+		os.Exit(1)
+	}
+
+	targets := newTargetManager(logger, *scrapeConcurrency)
+	if *configCheck {
+		if err := targets.check(cfg); err != nil {
+			logger.Error("checking configuration failed", "error", err.Error())
 			os.Exit(1)
 		}
-		sslConfig.Certificates = []tls.Certificate{clientCert}
+		logger.Info("configuration is valid", "targets", len(cfg.Targets))
+		os.Exit(0)
 	}
 
-	transport := &http.Transport{
-		TLSClientConfig: sslConfig,
+	if err := targets.apply(cfg); err != nil {
+		logger.Error("configuring targets failed", "error", err.Error())
+		os.Exit(1)
 	}
 
-	if len(*scrapeURIs) == 1 {
-		registerCollector(logger, transport, (*scrapeURIs)[0], constLabels)
-	} else {
-		for _, addr := range *scrapeURIs {
-			// add scrape URI to const labels
-			labels := maps.Clone(constLabels)
-			labels["addr"] = addr
+	http.Handle(*metricsPath, promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		newMetricsHandler(targets, *scrapeTimeoutOffset),
+	))
 
-			registerCollector(logger, transport, addr, labels)
+	if *probePath != "" {
+		allowlist, err := newTargetAllowlist(*probeCIDRs, *probeRegexps)
+		if err != nil {
+			logger.Error("parsing probe target allowlist failed", "error", err.Error())
+			os.Exit(1)
 		}
+		http.Handle(*probePath, newProbeHandler(logger, allowlist, defaults, *scrapeTimeoutOffset))
 	}
 
-	http.Handle(*metricsPath, promhttp.Handler())
-
 	if *metricsPath != "/" && *metricsPath != "" {
 		landingConfig := web.LandingConfig{
 			Name:        "NGINX Prometheus Exporter",
@@ -198,6 +311,48 @@ func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill, syscall.SIGTERM)
 	defer cancel()
 
+	reloadTargets := func() error {
+		cfg, err := loadTargets()
+		if err != nil {
+			return err
+		}
+		return targets.apply(cfg)
+	}
+	// on SIGHUP, the labels are reloaded before the targets, which are then built from the new labels
+	var watched []watchedFile
+	if *labelsFile != "" {
+		watched = append(watched, watchedFile{path: *labelsFile, reload: func() error {
+			return reloadLabels(*labelsFile, defaults.Labels, targets)
+		}})
+	}
+	watched = append(watched, watchedFile{path: *configFile, reload: reloadTargets})
+	go watchConfig(ctx, logger, *reloadInterval, watched...)
+
+	var adminSrv *http.Server
+	if *adminAddress != "" {
+		token, err := loadAdminToken(*adminTokenFile, *adminWebConfigFile)
+		if err != nil {
+			logger.Error("configuring admin API failed", "error", err.Error())
+			os.Exit(1)
+		}
+		adminSrv = &http.Server{
+			Handler:           newAdminHandler(logger, token, defaults.Labels, targets),
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+		systemdSocket := false
+		adminConfig := &web.FlagConfig{
+			WebListenAddresses: &[]string{*adminAddress},
+			WebSystemdSocket:   &systemdSocket,
+			WebConfigFile:      adminWebConfigFile,
+		}
+		go func() {
+			if err := web.ListenAndServe(adminSrv, adminConfig, logger); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error("admin HTTP server failed", "error", err.Error())
+				os.Exit(1)
+			}
+		}()
+	}
+
 	srv := &http.Server{
 		ReadHeaderTimeout: 5 * time.Second,
 	}
@@ -217,47 +372,127 @@ func main() {
 	logger.Info("shutting down")
 	srvCtx, srvCancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer srvCancel()
+	if adminSrv != nil {
+		_ = adminSrv.Shutdown(srvCtx)
+	}
 	_ = srv.Shutdown(srvCtx)
 }
 
-func registerCollector(logger *slog.Logger, transport *http.Transport,
-	addr string, labels map[string]string,
-) {
-	if strings.HasPrefix(addr, "unix:") {
-		socketPath, requestPath, err := parseUnixSocketAddress(addr)
-		if err != nil {
-			logger.Error("parsing unix domain socket scrape address failed", "uri", addr, "error", err.Error())
-			os.Exit(1)
-		}
-
-		transport.DialContext = func(_ context.Context, _, _ string) (net.Conn, error) {
-			return net.Dial("unix", socketPath)
+// flagTargetDefaults returns the target settings given by the command-line flags.
+func flagTargetDefaults() targetConfig {
+	mode := *nginxMode
+	if mode == "" {
+		mode = modeOSS
+		if *nginxPlus {
+			mode = modePlus
 		}
-		addr = "http://unix" + requestPath
 	}
+	return targetConfig{
+		Mode:           mode,
+		Timeout:        *timeout,
+		PollInterval:   *pollInterval,
+		StalenessLimit: *stalenessLimit,
+		Collectors:     enabledSections(),
+		Filters:        flagFilters(),
+		ResponseCodes:  flagResponseCodes(),
+		Labels:         &variableLabels{},
+		LabelExpiry:    labelExpiry{Scrapes: *labelsExpireScrapes, After: *labelsExpireAfter},
+		LabelStoreDir:  *labelsStoreDir,
+		LabelZone:      *labelsKeyValZone,
+		KeyVals:        keyValConfig{ExportKeys: *keyValExportKeys, ExportLimit: keyValExportLimit},
+		ConstLabels:    constLabels,
+		TLS: tlsConfig{
+			Verify:     sslVerify,
+			CACert:     *sslCaCert,
+			ClientCert: *sslClientCert,
+			ClientKey:  *sslClientKey,
+		},
+		Transport: transportConfig{
+			DisableKeepAlives:   disableKeepAlives,
+			MaxIdleConns:        *maxIdleConns,
+			MaxIdleConnsPerHost: *maxIdleConnsPerHost,
+			MaxConnsPerHost:     *maxConnsPerHost,
+			IdleConnTimeout:     *idleConnTimeout,
+			KeepAlive:           *keepAlive,
+		},
+	}
+}
 
+// newCollector creates an NGINX or NGINX Plus collector for the target, which is scraped at addr
+// through transport.
+func newCollector(logger *slog.Logger, transport *http.Transport, addr string, target targetConfig) (prometheus.Collector, error) {
 	userAgent := fmt.Sprintf("NGINX-Prometheus-Exporter/v%v", common_version.Version)
 
 	httpClient := &http.Client{
-		Timeout: *timeout,
+		Timeout: target.Timeout,
 		Transport: &userAgentRoundTripper{
 			agent: userAgent,
 			rt:    transport,
 		},
 	}
 
-	if *nginxPlus {
-		plusClient, err := plusclient.NewNginxClient(addr, plusclient.WithHTTPClient(httpClient))
+	if target.Mode == modeAuto {
+		return newAutoCollector(logger, httpClient, addr, target), nil
+	}
+	return newModeCollector(logger, httpClient, addr, target, 0)
+}
+
+// newModeCollector creates the collector for the mode of the target. The NGINX Plus
+// collector uses the given API version, or the default version of the client if it is zero.
+func newModeCollector(logger *slog.Logger, httpClient *http.Client, addr string, target targetConfig, apiVersion int) (prometheus.Collector, error) {
+	if target.Mode == modePlus {
+		opts := []plusclient.Option{plusclient.WithHTTPClient(httpClient)}
+		if apiVersion != 0 {
+			opts = append(opts, plusclient.WithAPIVersion(apiVersion))
+		}
+		plusClient, err := plusclient.NewNginxClient(addr, opts...)
 		if err != nil {
-			logger.Error("could not create Nginx Plus Client", "error", err.Error())
-			os.Exit(1)
+			return nil, fmt.Errorf("could not create Nginx Plus Client: %w", err)
+		}
+		labels := target.Labels.current()
+		responseCodes, err := newResponseCodes(target.ResponseCodes)
+		if err != nil {
+			return nil, err
+		}
+		collectorOpts := []collector.NginxPlusCollectorOption{
+			collector.WithAPIEndpoint(httpClient, addr),
+			collector.WithResponseCodes(responseCodes),
+		}
+		if target.Collectors != nil {
+			collectorOpts = append(collectorOpts, collector.WithSections(target.Collectors...))
+		}
+		filters, err := newNameFilters(target.Filters)
+		if err != nil {
+			return nil, err
+		}
+		if len(filters) > 0 {
+			collectorOpts = append(collectorOpts, collector.WithNameFilters(filters))
+		}
+		if rules := labels.labelRules(); len(rules) > 0 {
+			collectorOpts = append(collectorOpts, collector.WithLabelRules(rules))
+		}
+		if target.LabelExpiry.Scrapes > 0 || target.LabelExpiry.After > 0 {
+			collectorOpts = append(collectorOpts, collector.WithLabelExpiry(target.LabelExpiry.Scrapes, target.LabelExpiry.After))
+		}
+		if target.LabelZone != "" {
+			collectorOpts = append(collectorOpts, collector.WithLabelKeyValZone(target.LabelZone))
+		}
+		if target.KeyVals.ExportKeys != "" {
+			keys, err := collector.NewNameFilter(target.KeyVals.ExportKeys, "")
+			if err != nil {
+				return nil, fmt.Errorf("invalid keyvals export_keys: %w", err)
+			}
+			collectorOpts = append(collectorOpts, collector.WithKeyValKeys(keys, target.KeyVals.exportLimit()))
+		}
+		if target.LabelStoreDir != "" {
+			collectorOpts = append(collectorOpts, collector.WithLabelFile(labelStoreFile(target.LabelStoreDir, target.URI)))
 		}
-		variableLabelNames := collector.NewVariableLabelNames(nil, nil, nil, nil, nil, nil, nil)
-		prometheus.MustRegister(collector.NewNginxPlusCollector(plusClient, "nginxplus", variableLabelNames, labels, logger))
-	} else {
-		ossClient := client.NewNginxClient(httpClient, addr)
-		prometheus.MustRegister(collector.NewNginxCollector(ossClient, "nginx", labels, logger))
+		plusCollector := collector.NewNginxPlusCollector(plusClient, target.Namespace, labels.variableLabelNames(), target.ConstLabels, logger, collectorOpts...)
+		updateLabels(plusCollector.LabelStore(), nil, labels)
+		return plusCollector, nil
 	}
+	ossClient := client.NewNginxClient(httpClient, addr)
+	return collector.NewNginxCollector(ossClient, target.Namespace, target.ConstLabels, logger), nil
 }
 
 type userAgentRoundTripper struct {
@@ -168,3 +168,17 @@ func TestConvertFlagToEnvar(t *testing.T) {
 		}
 	}
 }
+
+func TestCreatePositiveDurationFlag(t *testing.T) {
+	t.Parallel()
+
+	app := kingpin.New("test", "")
+	d := createPositiveDurationFlag(app.Flag("duration", "").Default("5s"))
+
+	if _, err := app.Parse([]string{"--duration=10s"}); err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+	if *d != 10*time.Second {
+		t.Errorf("createPositiveDurationFlag() = %v, want %v", *d, 10*time.Second)
+	}
+}
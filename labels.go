@@ -0,0 +1,379 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nginx/nginx-prometheus-exporter/collector"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v2"
+)
+
+// Kinds of NGINX Plus objects that can have variable labels. Peers are named upstream/server.
+const (
+	labelKindUpstream              = collector.LabelKindUpstream
+	labelKindServerZone            = collector.LabelKindServerZone
+	labelKindUpstreamPeer          = collector.LabelKindUpstreamPeer
+	labelKindStreamUpstream        = collector.LabelKindStreamUpstream
+	labelKindStreamServerZone      = collector.LabelKindStreamServerZone
+	labelKindStreamUpstreamPeer    = collector.LabelKindStreamUpstreamPeer
+	labelKindCacheZone             = collector.LabelKindCacheZone
+	labelKindLocationZone          = collector.LabelKindLocationZone
+	labelKindResolver              = collector.LabelKindResolver
+	labelKindLimitRequest          = collector.LabelKindLimitRequest
+	labelKindLimitConnection       = collector.LabelKindLimitConnection
+	labelKindStreamLimitConnection = collector.LabelKindStreamLimitConnection
+	labelKindStreamZoneSyncZone    = collector.LabelKindStreamZoneSyncZone
+)
+
+// fixedLabels are the label names the metrics of every kind already have.
+var fixedLabels = map[string][]string{
+	labelKindUpstream:              {"upstream", "server", "code", "reason"},
+	labelKindServerZone:            {"server_zone", "code", "reason"},
+	labelKindUpstreamPeer:          {"upstream", "server", "code", "reason"},
+	labelKindStreamUpstream:        {"upstream", "server"},
+	labelKindStreamServerZone:      {"server_zone", "reason"},
+	labelKindStreamUpstreamPeer:    {"upstream", "server"},
+	labelKindCacheZone:             {"zone"},
+	labelKindLocationZone:          {"location_zone", "code"},
+	labelKindResolver:              {"resolver"},
+	labelKindLimitRequest:          {"zone"},
+	labelKindLimitConnection:       {"zone"},
+	labelKindStreamLimitConnection: {"zone"},
+	labelKindStreamZoneSyncZone:    {"zone"},
+}
+
+// labelsConfig describes the variable labels of NGINX Plus objects: the label names of every kind,
+// the label values of the objects by name and the rules deriving the values of the other objects from
+// their names.
+type labelsConfig struct {
+	LabelNames map[string][]string                     `yaml:"label_names"`
+	Values     map[string]map[string]map[string]string `yaml:"values"`
+	Rules      map[string][]string                     `yaml:"rules"`
+	// rules are the compiled Rules, set by validate.
+	rules map[string][]collector.LabelRule
+}
+
+// loadLabels reads the labels file.
+func loadLabels(path string) (*labelsConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read labels file: %w", err)
+	}
+
+	cfg := &labelsConfig{}
+	if err := yaml.UnmarshalStrict(content, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse labels file %q: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid labels file %q: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func (c *labelsConfig) validate() error {
+	for kind, names := range c.LabelNames {
+		fixed, ok := fixedLabels[kind]
+		if !ok {
+			return fmt.Errorf("unknown kind %q", kind)
+		}
+		// the peer labels are added to the upstream labels
+		switch kind {
+		case labelKindUpstreamPeer:
+			fixed = append(slices.Clone(fixed), c.LabelNames[labelKindUpstream]...)
+		case labelKindStreamUpstreamPeer:
+			fixed = append(slices.Clone(fixed), c.LabelNames[labelKindStreamUpstream]...)
+		}
+		for i, name := range names {
+			if !model.LabelName(name).IsValidLegacy() {
+				return fmt.Errorf("invalid label name %q of kind %q", name, kind)
+			}
+			if slices.Contains(fixed, name) || slices.Contains(names[:i], name) {
+				return fmt.Errorf("duplicate label name %q of kind %q", name, kind)
+			}
+		}
+	}
+	for kind, objects := range c.Values {
+		names, ok := c.LabelNames[kind]
+		if !ok {
+			return fmt.Errorf("values of kind %q have no label_names", kind)
+		}
+		for object, labels := range objects {
+			for name := range labels {
+				if !slices.Contains(names, name) {
+					return fmt.Errorf("object %q of kind %q has undeclared label %q", object, kind, name)
+				}
+			}
+		}
+	}
+	c.rules = make(map[string][]collector.LabelRule, len(c.Rules))
+	for kind, exprs := range c.Rules {
+		names, ok := c.LabelNames[kind]
+		if !ok {
+			return fmt.Errorf("rules of kind %q have no label_names", kind)
+		}
+		for _, expr := range exprs {
+			rule, err := collector.NewLabelRule(expr)
+			if err != nil {
+				return fmt.Errorf("invalid rule of kind %q: %w", kind, err)
+			}
+			for _, name := range rule.LabelNames() {
+				if !slices.Contains(names, name) {
+					return fmt.Errorf("rule %q of kind %q has undeclared label %q", expr, kind, name)
+				}
+			}
+			c.rules[kind] = append(c.rules[kind], rule)
+		}
+	}
+	return nil
+}
+
+// labelRules returns the label rules of every kind for the NGINX Plus collector.
+func (c *labelsConfig) labelRules() map[string][]collector.LabelRule {
+	if c == nil {
+		return nil
+	}
+	return c.rules
+}
+
+// variableLabelNames returns the label names of every kind for the NGINX Plus collector.
+func (c *labelsConfig) variableLabelNames() collector.VariableLabelNames {
+	if c == nil {
+		return collector.NewVariableLabelNamesByKind(nil)
+	}
+	return collector.NewVariableLabelNamesByKind(c.LabelNames)
+}
+
+// labelValues returns the label values of the objects of kind, in the order of the label names.
+// Labels without a value are empty.
+func (c *labelsConfig) labelValues(kind string) map[string][]string {
+	if c == nil {
+		return nil
+	}
+	values := make(map[string][]string, len(c.Values[kind]))
+	for object, labels := range c.Values[kind] {
+		for _, name := range c.LabelNames[kind] {
+			values[object] = append(values[object], labels[name])
+		}
+	}
+	return values
+}
+
+// clone returns a copy of c whose values can be changed without changing c.
+func (c *labelsConfig) clone() *labelsConfig {
+	clone := &labelsConfig{
+		LabelNames: make(map[string][]string),
+		Values:     make(map[string]map[string]map[string]string),
+	}
+	if c == nil {
+		return clone
+	}
+	maps.Copy(clone.LabelNames, c.LabelNames)
+	clone.Rules = c.Rules
+	clone.rules = c.rules
+	for kind, objects := range c.Values {
+		clone.Values[kind] = maps.Clone(objects)
+	}
+	return clone
+}
+
+// namedValues returns the label values of an object of kind by label name, given in the order of the label names.
+func (c *labelsConfig) namedValues(kind string, object string, values []string) (map[string]string, error) {
+	var names []string
+	if c != nil {
+		names = c.LabelNames[kind]
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("kind %q has no label_names", kind)
+	}
+	if len(values) != len(names) {
+		return nil, fmt.Errorf("object %q of kind %q has %v label values, expected %v for the label names %v", object, kind, len(values), len(names), names)
+	}
+	labels := make(map[string]string, len(names))
+	for i, name := range names {
+		labels[name] = values[i]
+	}
+	return labels, nil
+}
+
+// sameLabelNames reports whether a and b declare the same label names and rules, so that the collectors
+// created for one can take the values of the other.
+func sameLabelNames(a, b *labelsConfig) bool {
+	var namesA, namesB, rulesA, rulesB map[string][]string
+	if a != nil {
+		namesA, rulesA = a.LabelNames, a.Rules
+	}
+	if b != nil {
+		namesB, rulesB = b.LabelNames, b.Rules
+	}
+	return maps.EqualFunc(namesA, namesB, slices.Equal) && maps.EqualFunc(rulesA, rulesB, slices.Equal)
+}
+
+// variableLabels holds the current labels configuration, which the NGINX Plus collectors take
+// their variable labels from. It is the configuration of the labels file with the changes made through
+// the admin API applied over it.
+type variableLabels struct {
+	cfg   *labelsConfig
+	file  *labelsConfig
+	admin adminLabels
+	mutex sync.RWMutex
+}
+
+// current returns the current labels configuration, which is nil if there is none.
+func (v *variableLabels) current() *labelsConfig {
+	if v == nil {
+		return nil
+	}
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	return v.cfg
+}
+
+// set replaces the configuration of the labels file, keeping the changes made through the admin API over it,
+// and returns the previous labels configuration.
+func (v *variableLabels) set(file *labelsConfig) *labelsConfig {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	previous := v.cfg
+	v.file = file
+	v.cfg = v.admin.apply(file)
+	return previous
+}
+
+// update applies change to a copy of the changes made through the admin API, which replace the current ones
+// unless change fails, and sets the new label values on the collectors of targets. The label names are those
+// of the labels file.
+func (v *variableLabels) update(targets *targetManager, change func(file *labelsConfig, admin adminLabels) error) error {
+	targets.reloads.Lock()
+	defer targets.reloads.Unlock()
+
+	v.mutex.RLock()
+	file := v.file
+	admin := v.admin.clone()
+	v.mutex.RUnlock()
+	if err := change(file, admin); err != nil {
+		return err
+	}
+
+	v.mutex.Lock()
+	previous := v.cfg
+	v.admin = admin
+	v.cfg = admin.apply(file)
+	cfg := v.cfg
+	v.mutex.Unlock()
+	targets.updateLabels(previous, cfg)
+	return nil
+}
+
+// labelExpiry describes when the label values of the NGINX Plus objects that are no longer reported are removed
+// from the collectors.
+type labelExpiry struct {
+	Scrapes int
+	After   time.Duration
+}
+
+// labelStoreFile returns the path of the file in dir in which the label values of the target with the given URI
+// are saved.
+func labelStoreFile(dir, uri string) string {
+	return filepath.Join(dir, url.QueryEscape(uri)+".json")
+}
+
+// updateLabels applies the changes of the label values from previous to cfg to the store, in a single
+// transaction. The values are pinned, so that the label expiry never evicts them: an object declared in
+// the configuration keeps its labels when it comes back after being removed from NGINX Plus.
+func updateLabels(store *collector.LabelStore, previous, cfg *labelsConfig) {
+	store.Update(func(tx *collector.LabelTx) {
+		for kind := range fixedLabels {
+			before := previous.labelValues(kind)
+			after := cfg.labelValues(kind)
+			var deleted []string
+			for object := range before {
+				if _, ok := after[object]; !ok {
+					deleted = append(deleted, object)
+				}
+			}
+			changed := make(map[string][]string)
+			for object, values := range after {
+				if v, ok := before[object]; !ok || !slices.Equal(v, values) {
+					changed[object] = values
+				}
+			}
+			tx.Delete(kind, deleted)
+			tx.SetPinned(kind, changed)
+		}
+	})
+}
+
+// labelStore returns the label store of the collector of a target, if any.
+func labelStore(c prometheus.Collector) (*collector.LabelStore, bool) {
+	if auto, ok := c.(*autoCollector); ok {
+		auto.mutex.Lock()
+		c = auto.collector
+		auto.mutex.Unlock()
+	}
+	if plus, ok := c.(*collector.NginxPlusCollector); ok {
+		return plus.LabelStore(), true
+	}
+	return nil, false
+}
+
+// labelStores returns the label stores of the collectors of the targets that have one, by target URI. The
+// targets whose mode is not detected yet have none.
+func (m *targetManager) labelStores() map[string]*collector.LabelStore {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	stores := make(map[string]*collector.LabelStore, len(m.collectors))
+	for _, tc := range m.collectors {
+		if store, ok := labelStore(tc.collector); ok {
+			stores[tc.target] = store
+		}
+	}
+	return stores
+}
+
+// updateLabels applies the changes of the label values from previous to cfg to the collectors of all
+// targets. The collectors must have been created for the same label names.
+func (m *targetManager) updateLabels(previous, cfg *labelsConfig) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	for _, tc := range m.collectors {
+		if store, ok := labelStore(tc.collector); ok {
+			updateLabels(store, previous, cfg)
+		}
+	}
+}
+
+// reloadLabels reads the labels file at path and applies it. The values are updated in place when
+// the label names and rules are unchanged; otherwise the collectors of the current targets are recreated.
+func reloadLabels(path string, labels *variableLabels, targets *targetManager) error {
+	cfg, err := loadLabels(path)
+	if err != nil {
+		return err
+	}
+	targets.reloads.Lock()
+	defer targets.reloads.Unlock()
+
+	previousFile := labels.file
+	previous := labels.set(cfg)
+	if sameLabelNames(previous, cfg) {
+		targets.updateLabels(previous, labels.current())
+		return nil
+	}
+	if targets.config == nil {
+		return nil
+	}
+	if err := targets.rebuild(targets.config); err != nil {
+		labels.set(previousFile)
+		return fmt.Errorf("failed to apply the label names: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nginx/nginx-prometheus-exporter/collector"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestLoadLabels(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		content    string
+		wantValues map[string][]string
+		wantErr    bool
+	}{
+		{
+			name: "values in the order of the label names",
+			content: `label_names:
+  upstream: [team, service]
+values:
+  upstream:
+    backend: {service: api, team: payments}
+    legacy: {team: core}
+`,
+			wantValues: map[string][]string{
+				"backend": {"payments", "api"},
+				"legacy":  {"core", ""},
+			},
+		},
+		{
+			name: "unknown kind",
+			content: `label_names:
+  upstreams: [team]
+`,
+			wantErr: true,
+		},
+		{
+			name: "invalid label name",
+			content: `label_names:
+  upstream: [team-name]
+`,
+			wantErr: true,
+		},
+		{
+			name: "label name of the metrics",
+			content: `label_names:
+  server_zone: [server_zone]
+`,
+			wantErr: true,
+		},
+		{
+			name: "label name of the limit zone metrics",
+			content: `label_names:
+  limit_request: [zone]
+`,
+			wantErr: true,
+		},
+		{
+			name: "label name of the SSL failure metrics",
+			content: `label_names:
+  upstream_peer: [reason]
+`,
+			wantErr: true,
+		},
+		{
+			name: "peer label name of the upstream",
+			content: `label_names:
+  upstream: [team]
+  upstream_peer: [team]
+`,
+			wantErr: true,
+		},
+		{
+			name: "undeclared label",
+			content: `label_names:
+  upstream: [team]
+values:
+  upstream:
+    backend: {tier: gold}
+`,
+			wantErr: true,
+		},
+		{
+			name: "rules",
+			content: `label_names:
+  upstream: [team, service]
+values:
+  upstream:
+    backend: {team: payments, service: api}
+rules:
+  upstream:
+    - '(?P<team>[^-]+)-(?P<service>.+)'
+`,
+			wantValues: map[string][]string{
+				"backend": {"payments", "api"},
+			},
+		},
+		{
+			name: "rule with undeclared label",
+			content: `label_names:
+  upstream: [team]
+rules:
+  upstream:
+    - '(?P<team>[^-]+)-(?P<tier>.+)'
+`,
+			wantErr: true,
+		},
+		{
+			name: "invalid rule",
+			content: `label_names:
+  upstream: [team]
+rules:
+  upstream:
+    - '(?P<team>[^-]+'
+`,
+			wantErr: true,
+		},
+		{
+			name: "rules without label names",
+			content: `rules:
+  cache_zone:
+    - '(?P<team>[^-]+)-.+'
+`,
+			wantErr: true,
+		},
+		{
+			name: "values without label names",
+			content: `values:
+  cache_zone:
+    images: {team: media}
+`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "labels.yml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := loadLabels(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadLabels() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := cfg.labelValues(labelKindUpstream); !reflect.DeepEqual(got, tt.wantValues) {
+				t.Errorf("labelValues() = %v, want %v", got, tt.wantValues)
+			}
+		})
+	}
+}
+
+// upstreamLabels returns the labels of the nginxplus_upstream_server_requests metrics.
+func upstreamLabels(t *testing.T, gatherer prometheus.Gatherer) []map[string]string {
+	t.Helper()
+	families, err := gatherer.Gather()
+	if err != nil {
+		t.Fatalf("Gather() returned error: %v", err)
+	}
+	var labels []map[string]string
+	for _, family := range families {
+		if family.GetName() != "nginxplus_upstream_server_requests" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			l := make(map[string]string)
+			for _, pair := range m.GetLabel() {
+				l[pair.GetName()] = pair.GetValue()
+			}
+			labels = append(labels, l)
+		}
+	}
+	return labels
+}
+
+func TestReloadLabels(t *testing.T) {
+	t.Parallel()
+
+	nginx := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/9/http/upstreams":
+			_, _ = io.WriteString(w, `{"backend": {"peers": [{"server": "10.0.0.1:80"}]}}`)
+		case "/9/workers":
+			_, _ = io.WriteString(w, "[]")
+		default:
+			_, _ = io.WriteString(w, "{}")
+		}
+	}))
+	t.Cleanup(nginx.Close)
+
+	path := filepath.Join(t.TempDir(), "labels.yml")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`label_names:
+  upstream: [team]
+values:
+  upstream:
+    backend: {team: payments}
+`)
+
+	labels := &variableLabels{}
+	initial, err := loadLabels(path)
+	if err != nil {
+		t.Fatalf("loadLabels() returned error: %v", err)
+	}
+	labels.set(initial)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	manager := newTargetManager(logger, 10)
+	defaults := targetConfig{Mode: modePlus, Timeout: time.Second, Labels: labels}
+	cfg, err := newConfigFromURIs([]string{nginx.URL}, defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.apply(cfg); err != nil {
+		t.Fatalf("apply() returned error: %v", err)
+	}
+	current := func() *targetCollector {
+		manager.mutex.RLock()
+		defer manager.mutex.RUnlock()
+		return manager.collectors[0]
+	}
+	initialCollector := current()
+
+	got := upstreamLabels(t, manager)
+	if len(got) != 1 || got[0]["team"] != "payments" {
+		t.Fatalf("labels = %v, want team=payments", got)
+	}
+
+	// new values for the same label names are updated in place
+	write(`label_names:
+  upstream: [team]
+values:
+  upstream:
+    backend: {team: checkout}
+`)
+	if err := reloadLabels(path, labels, manager); err != nil {
+		t.Fatalf("reloadLabels() returned error: %v", err)
+	}
+	if current() != initialCollector {
+		t.Error("the collectors were rebuilt for the same label names")
+	}
+	got = upstreamLabels(t, manager)
+	if len(got) != 1 || got[0]["team"] != "checkout" {
+		t.Errorf("labels = %v, want team=checkout", got)
+	}
+
+	// new label names recreate the collectors
+	write(`label_names:
+  upstream: [team, tier]
+values:
+  upstream:
+    backend: {team: checkout, tier: gold}
+`)
+	if err := reloadLabels(path, labels, manager); err != nil {
+		t.Fatalf("reloadLabels() returned error: %v", err)
+	}
+	if current() == initialCollector {
+		t.Error("the collectors were not rebuilt for new label names")
+	}
+	got = upstreamLabels(t, manager)
+	if len(got) != 1 || got[0]["tier"] != "gold" {
+		t.Errorf("labels = %v, want tier=gold", got)
+	}
+
+	// an invalid file keeps the current labels
+	write("label_names: [team]\n")
+	if err := reloadLabels(path, labels, manager); err == nil {
+		t.Error("reloadLabels() did not return error for an invalid file")
+	}
+	if !sameLabelNames(labels.current(), &labelsConfig{LabelNames: map[string][]string{labelKindUpstream: {"team", "tier"}}}) {
+		t.Errorf("labels = %+v, want the previous labels", labels.current())
+	}
+}
+
+func TestUpdateLabels(t *testing.T) {
+	t.Parallel()
+
+	previous := &labelsConfig{
+		LabelNames: map[string][]string{labelKindUpstream: {"team"}},
+		Values: map[string]map[string]map[string]string{labelKindUpstream: {
+			"backend": {"team": "payments"},
+			"expired": {"team": "core"},
+			"removed": {"team": "core"},
+		}},
+	}
+	cfg := previous.clone()
+	delete(cfg.Values[labelKindUpstream], "removed")
+	cfg.Values[labelKindUpstream]["checkout"] = map[string]string{"team": "payments"}
+
+	store := collector.NewLabelStore()
+	updateLabels(store, nil, previous)
+	// an object deleted from the store is not set again while its values are not changed by cfg
+	store.Update(func(tx *collector.LabelTx) {
+		tx.Delete(labelKindUpstream, []string{"expired"})
+	})
+	updateLabels(store, previous, cfg)
+
+	want := map[string][]string{"backend": {"payments"}, "checkout": {"payments"}}
+	if got := store.Values(labelKindUpstream); !reflect.DeepEqual(got, want) {
+		t.Errorf("Values() = %v, want %v", got, want)
+	}
+}
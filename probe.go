@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var errTargetNotAllowed = errors.New("target is not allowed")
+
+// targetAllowlist decides which targets may be scraped through the probe endpoint.
+// A target is allowed if it matches one of the regular expressions, or if all the
+// addresses it resolves to belong to one of the networks.
+type targetAllowlist struct {
+	networks []*net.IPNet
+	regexps  []*regexp.Regexp
+}
+
+func newTargetAllowlist(cidrs []string, regexps []string) (*targetAllowlist, error) {
+	a := &targetAllowlist{}
+
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CIDR %q: %w", cidr, err)
+		}
+		a.networks = append(a.networks, network)
+	}
+
+	for _, expr := range regexps {
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse regex %q: %w", expr, err)
+		}
+		a.regexps = append(a.regexps, re)
+	}
+
+	return a, nil
+}
+
+func (a *targetAllowlist) matchesRegexp(target string) bool {
+	for _, re := range a.regexps {
+		if re.MatchString(target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *targetAllowlist) containsIP(ip net.IP) bool {
+	for _, network := range a.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// dialControl checks that target may be scraped. If target is only allowed by the networks
+// of the allowlist, the returned control refuses connections to any address outside of them,
+// so a DNS answer that changes after the check cannot be used to bypass it. A nil control
+// means that connections are not restricted.
+func (a *targetAllowlist) dialControl(ctx context.Context, target string) (dialControl, error) {
+	if a.matchesRegexp(target) {
+		return nil, nil
+	}
+
+	if strings.HasPrefix(target, "unix:") || len(a.networks) == 0 {
+		return nil, errTargetNotAllowed
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse target %q: %w", target, err)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("target %q has no host: %w", target, errTargetNotAllowed)
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve target %q: %w", target, err)
+	}
+	for _, addr := range addrs {
+		if !a.containsIP(addr.IP) {
+			return nil, fmt.Errorf("target %q resolves to %v: %w", target, addr.IP, errTargetNotAllowed)
+		}
+	}
+
+	return func(_, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return fmt.Errorf("failed to parse address %q: %w", address, err)
+		}
+		if ip := net.ParseIP(host); ip == nil || !a.containsIP(ip) {
+			return fmt.Errorf("address %q: %w", address, errTargetNotAllowed)
+		}
+		return nil
+	}, nil
+}
+
+// probeHandler scrapes the NGINX or NGINX Plus instance given by the target query parameter
+// and serves its metrics. The module query parameter selects the collector: oss, plus or auto.
+type probeHandler struct {
+	logger        *slog.Logger
+	allowlist     *targetAllowlist
+	defaults      targetConfig
+	timeoutOffset time.Duration
+}
+
+func newProbeHandler(logger *slog.Logger, allowlist *targetAllowlist, defaults targetConfig, timeoutOffset time.Duration) *probeHandler {
+	return &probeHandler{
+		logger:        logger,
+		allowlist:     allowlist,
+		defaults:      defaults,
+		timeoutOffset: timeoutOffset,
+	}
+}
+
+func (h *probeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	target := params.Get("target")
+	if target == "" {
+		http.Error(w, "target parameter is missing", http.StatusBadRequest)
+		return
+	}
+
+	cfg := &config{Targets: []targetConfig{{URI: target, Mode: params.Get("module")}}}
+	cfg.applyDefaults(h.defaults)
+	// the collector of a probe only lives for the request, so it doesn't save its labels
+	cfg.Targets[0].LabelStoreDir = ""
+	if err := cfg.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := scrapeContext(r, h.timeoutOffset)
+	defer cancel()
+
+	control, err := h.allowlist.dialControl(ctx, target)
+	if err != nil {
+		h.logger.Warn("probe target rejected", "target", target, "error", err.Error())
+		http.Error(w, fmt.Sprintf("target %q is not allowed", target), http.StatusForbidden)
+		return
+	}
+
+	transport, addr, err := newTargetTransport(cfg.Targets[0], control)
+	if err != nil {
+		h.logger.Error("could not create transport", "target", target, "error", err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer transport.CloseIdleConnections()
+
+	c, err := newCollector(h.logger, transport, addr, cfg.Targets[0])
+	if err != nil {
+		h.logger.Error("could not create collector", "target", target, "error", err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(withContext(ctx, c)); err != nil {
+		h.logger.Error("could not register collector", "target", target, "error", err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+const validStubStatus = "Active connections: 1 \nserver accepts handled requests\n 2 2 3 \nReading: 0 Writing: 1 Waiting: 0 \n"
+
+func TestTargetAllowlist(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		target  string
+		cidrs   []string
+		regexps []string
+		wantErr bool
+	}{
+		{
+			name:    "empty allowlist rejects everything",
+			target:  "http://127.0.0.1:8080/stub_status",
+			wantErr: true,
+		},
+		{
+			name:   "ip in allowed network",
+			target: "http://127.0.0.1:8080/stub_status",
+			cidrs:  []string{"127.0.0.0/8"},
+		},
+		{
+			name:    "ip outside allowed network",
+			target:  "http://127.0.0.1:8080/stub_status",
+			cidrs:   []string{"10.0.0.0/8"},
+			wantErr: true,
+		},
+		{
+			name:    "regex matches full target",
+			target:  "http://nginx-1.example.com:8080/api",
+			regexps: []string{`http://nginx-\d+\.example\.com:8080/api`},
+		},
+		{
+			name:    "regex is anchored",
+			target:  "http://nginx-1.example.com.evil.com:8080/api",
+			regexps: []string{`http://nginx-\d+\.example\.com`},
+			wantErr: true,
+		},
+		{
+			name:    "unix socket allowed by regex",
+			target:  "unix:/var/run/nginx.sock:/stub_status",
+			regexps: []string{`unix:/var/run/.*`},
+		},
+		{
+			name:    "unix socket is never allowed by network",
+			target:  "unix:/var/run/nginx.sock:/stub_status",
+			cidrs:   []string{"0.0.0.0/0"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			allowlist, err := newTargetAllowlist(tt.cidrs, tt.regexps)
+			if err != nil {
+				t.Fatalf("newTargetAllowlist() returned error: %v", err)
+			}
+			_, err = allowlist.dialControl(context.Background(), tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("dialControl() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewTargetAllowlistInvalid(t *testing.T) {
+	t.Parallel()
+
+	if _, err := newTargetAllowlist([]string{"10.0.0.0"}, nil); err == nil {
+		t.Error("newTargetAllowlist() did not return error for invalid CIDR")
+	}
+	if _, err := newTargetAllowlist(nil, []string{"("}); err == nil {
+		t.Error("newTargetAllowlist() did not return error for invalid regex")
+	}
+}
+
+func TestTargetAllowlistDialerRejectsOtherAddresses(t *testing.T) {
+	t.Parallel()
+
+	allowlist, err := newTargetAllowlist([]string{"127.0.0.0/8"}, nil)
+	if err != nil {
+		t.Fatalf("newTargetAllowlist() returned error: %v", err)
+	}
+	control, err := allowlist.dialControl(context.Background(), "http://127.0.0.1:8080/stub_status")
+	if err != nil {
+		t.Fatalf("dialControl() returned error: %v", err)
+	}
+	transport, _, err := newTargetTransport(targetConfig{URI: "http://127.0.0.1:8080/stub_status"}, control)
+	if err != nil {
+		t.Fatalf("newTargetTransport() returned error: %v", err)
+	}
+
+	_, err = transport.DialContext(context.Background(), "tcp", "192.0.2.1:80")
+	if !errors.Is(err, errTargetNotAllowed) {
+		t.Errorf("DialContext() error = %v, want %v", err, errTargetNotAllowed)
+	}
+}
+
+func TestProbeHandler(t *testing.T) {
+	t.Parallel()
+
+	nginx := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, validStubStatus)
+	}))
+	t.Cleanup(nginx.Close)
+
+	allowlist, err := newTargetAllowlist([]string{"127.0.0.0/8", "::1/128"}, nil)
+	if err != nil {
+		t.Fatalf("newTargetAllowlist() returned error: %v", err)
+	}
+	handler := newProbeHandler(slog.New(slog.NewTextHandler(io.Discard, nil)), allowlist, targetConfig{Mode: modeOSS}, 0)
+
+	tests := []struct {
+		name       string
+		query      url.Values
+		wantBody   string
+		wantStatus int
+	}{
+		{
+			name:       "scrapes allowed target",
+			query:      url.Values{"target": {nginx.URL}},
+			wantStatus: http.StatusOK,
+			wantBody:   "nginx_connections_active 1",
+		},
+		{
+			name:       "detects module",
+			query:      url.Values{"target": {nginx.URL}, "module": {modeAuto}},
+			wantStatus: http.StatusOK,
+			wantBody:   "nginx_connections_active 1",
+		},
+		{
+			name:       "missing target",
+			query:      url.Values{},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown module",
+			query:      url.Values{"target": {nginx.URL}, "module": {"foo"}},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "target not allowed",
+			query:      url.Values{"target": {"http://192.0.2.1/stub_status"}},
+			wantStatus: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest(http.MethodGet, "/probe?"+tt.query.Encode(), nil)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("ServeHTTP() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("ServeHTTP() body %q does not contain %q", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestProbeHandlerWithoutLabelFile(t *testing.T) {
+	t.Parallel()
+
+	nginx := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(nginx.Close)
+
+	allowlist, err := newTargetAllowlist([]string{"127.0.0.0/8", "::1/128"}, nil)
+	if err != nil {
+		t.Fatalf("newTargetAllowlist() returned error: %v", err)
+	}
+	dir := t.TempDir()
+	defaults := targetConfig{Mode: modePlus, Labels: &variableLabels{}, LabelStoreDir: dir}
+	handler := newProbeHandler(slog.New(slog.NewTextHandler(io.Discard, nil)), allowlist, defaults, 0)
+
+	req := httptest.NewRequest(http.MethodGet, "/probe?"+url.Values{"target": {nginx.URL}}.Encode(), nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	// the collectors of the probes don't save their labels
+	time.Sleep(50 * time.Millisecond)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("the label store directory has %v entries, want none", len(entries))
+	}
+}
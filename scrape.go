@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// scrapeTimeoutHeader is the header in which Prometheus sends the timeout of its scrape.
+const scrapeTimeoutHeader = "X-Prometheus-Scrape-Timeout-Seconds"
+
+// scraper is implemented by collectors that report whether getting the metrics of their target succeeded
+// and that stop getting them when ctx is done.
+type scraper interface {
+	Scrape(ctx context.Context, ch chan<- prometheus.Metric) error
+}
+
+// upCollector is implemented by collectors that report whether their target is up in a metric.
+type upCollector interface {
+	UpDesc() *prometheus.Desc
+}
+
+// scrape collects the metrics of c and returns the error reported by c, if it is a scraper.
+// Other collectors are not aware of ctx.
+func scrape(ctx context.Context, c prometheus.Collector, ch chan<- prometheus.Metric) error {
+	if s, ok := c.(scraper); ok {
+		return s.Scrape(ctx, ch)
+	}
+	c.Collect(ch)
+	return nil
+}
+
+// boundCollector collects the metrics of a collector within the context of a single request,
+// which a registry can't pass to its collectors.
+type boundCollector struct {
+	prometheus.Collector
+	collect func(ch chan<- prometheus.Metric)
+}
+
+func (c boundCollector) Collect(ch chan<- prometheus.Metric) {
+	c.collect(ch)
+}
+
+// withContext returns a collector that scrapes c within ctx.
+func withContext(ctx context.Context, c prometheus.Collector) prometheus.Collector {
+	return boundCollector{
+		Collector: c,
+		collect: func(ch chan<- prometheus.Metric) {
+			_ = scrape(ctx, c, ch)
+		},
+	}
+}
+
+// scrapeContext returns the context for serving the scrape request r. If Prometheus sent the timeout
+// of its scrape, the context ends offset before it, so that the exporter still answers in time.
+func scrapeContext(r *http.Request, offset time.Duration) (context.Context, context.CancelFunc) {
+	header := r.Header.Get(scrapeTimeoutHeader)
+	if header == "" {
+		return context.WithCancel(r.Context())
+	}
+	seconds, err := strconv.ParseFloat(header, 64)
+	if err != nil || seconds <= 0 {
+		return context.WithCancel(r.Context())
+	}
+	timeout := time.Duration(seconds*float64(time.Second)) - offset
+	if timeout <= 0 {
+		// the offset would leave no time for the scrape, use the timeout of Prometheus as is
+		timeout = time.Duration(seconds * float64(time.Second))
+	}
+	return context.WithTimeout(r.Context(), timeout)
+}
+
+// newMetricsHandler serves the metrics of the exporter and of the targets. The targets are scraped within
+// the context of the request.
+func newMetricsHandler(targets *targetManager, offset time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := scrapeContext(r, offset)
+		defer cancel()
+
+		gatherer := prometheus.Gatherers{
+			prometheus.DefaultGatherer,
+			prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+				return targets.GatherContext(ctx)
+			}),
+		}
+		promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	})
+}
+
+// scrapePool limits the number of targets that are scraped at the same time.
+type scrapePool chan struct{}
+
+func newScrapePool(size int) scrapePool {
+	return make(scrapePool, size)
+}
+
+// targetCollector scrapes a single target through the pool and reports the duration and the
+// outcome of the scrape. If the target doesn't answer within the deadline, its metrics are
+// dropped, its up metric is set to 0 and the scrape is reported as failed, so a slow target
+// can't stall the others. Scrapes abandoned by the client are counted separately from failures
+// of the target.
+type targetCollector struct {
+	collector prometheus.Collector
+	cancelled prometheus.Counter
+	// transport is the transport of the requests to the target, if known.
+	transport     *http.Transport
+	logger        *slog.Logger
+	pool          scrapePool
+	durationDesc  *prometheus.Desc
+	successDesc   *prometheus.Desc
+	timestampDesc *prometheus.Desc
+	lastSuccess   time.Time
+	descs         []*prometheus.Desc
+	target        string
+	deadline      time.Duration
+	mutex         sync.Mutex
+}
+
+func newTargetCollector(logger *slog.Logger, c prometheus.Collector, pool scrapePool, target targetConfig, cancelled prometheus.Counter) *targetCollector {
+	descs := make(chan *prometheus.Desc)
+	go func() {
+		c.Describe(descs)
+		close(descs)
+	}()
+	var described []*prometheus.Desc
+	for d := range descs {
+		described = append(described, d)
+	}
+
+	labels := prometheus.Labels{"target": target.URI}
+	return &targetCollector{
+		collector: c,
+		cancelled: cancelled,
+		descs:     described,
+		logger:    logger,
+		pool:      pool,
+		target:    target.URI,
+		deadline:  target.Timeout,
+		durationDesc: prometheus.NewDesc(prometheus.BuildFQName(exporterName, "", "scrape_duration_seconds"),
+			"Duration of the last scrape of the target", nil, labels),
+		successDesc: prometheus.NewDesc(prometheus.BuildFQName(exporterName, "", "scrape_success"),
+			"Whether the last scrape of the target succeeded", nil, labels),
+		timestampDesc: prometheus.NewDesc(prometheus.BuildFQName(exporterName, "", "last_scrape_timestamp_seconds"),
+			"Unix time of the last successful scrape of the target", nil, labels),
+	}
+}
+
+// Describe implements prometheus.Collector. If the wrapped collector is unchecked, so is the targetCollector,
+// as a registry only accepts described metrics from checked collectors.
+func (c *targetCollector) Describe(ch chan<- *prometheus.Desc) {
+	if len(c.descs) == 0 {
+		return
+	}
+	ch <- c.durationDesc
+	ch <- c.successDesc
+	ch <- c.timestampDesc
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *targetCollector) Collect(ch chan<- prometheus.Metric) {
+	_ = c.Scrape(context.Background(), ch)
+}
+
+// Scrape scrapes the target within ctx and the deadline of the target.
+func (c *targetCollector) Scrape(ctx context.Context, ch chan<- prometheus.Metric) error {
+	start := time.Now()
+
+	var collected []prometheus.Metric
+	err := c.acquire(ctx)
+	if err == nil {
+		collected, err = c.scrape(ctx)
+	}
+	duration := time.Since(start)
+
+	for _, m := range collected {
+		ch <- m
+	}
+	// the metrics of an abandoned scrape are dropped, the target is reported as down
+	if u, ok := c.collector.(upCollector); ok && err != nil && collected == nil {
+		ch <- prometheus.MustNewConstMetric(u.UpDesc(), prometheus.GaugeValue, 0)
+	}
+
+	success := 1.0
+	switch {
+	case err == nil:
+	case ctx.Err() != nil:
+		success = 0
+		c.cancelled.Inc()
+		c.logger.Debug("scrape cancelled", "target", c.target, "error", err.Error())
+	default:
+		success = 0
+		c.logger.Debug("scrape failed", "target", c.target, "error", err.Error())
+	}
+
+	c.mutex.Lock()
+	if err == nil {
+		c.lastSuccess = start
+	}
+	lastSuccess := c.lastSuccess
+	c.mutex.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(c.durationDesc, prometheus.GaugeValue, duration.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.successDesc, prometheus.GaugeValue, success)
+	if !lastSuccess.IsZero() {
+		ch <- prometheus.MustNewConstMetric(c.timestampDesc, prometheus.GaugeValue, float64(lastSuccess.UnixNano())/1e9)
+	}
+	return err
+}
+
+// acquire waits for a free slot in the pool.
+func (c *targetCollector) acquire(ctx context.Context) error {
+	select {
+	case c.pool <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for a free scrape slot: %w", ctx.Err())
+	}
+}
+
+// scrape collects the metrics of the target and releases the slot in the pool once done.
+func (c *targetCollector) scrape(ctx context.Context) ([]prometheus.Metric, error) {
+	var scrapeCtx context.Context
+	var cancel context.CancelFunc
+	if c.deadline > 0 {
+		scrapeCtx, cancel = context.WithTimeout(ctx, c.deadline)
+	} else {
+		scrapeCtx, cancel = context.WithCancel(ctx)
+	}
+	// an abandoned scrape stops the requests to the target
+	defer cancel()
+
+	metrics := make(chan prometheus.Metric)
+	result := make(chan error, 1)
+	go func() {
+		defer func() { <-c.pool }()
+		result <- scrape(scrapeCtx, c.collector, metrics)
+		close(metrics)
+	}()
+
+	var collected []prometheus.Metric
+	for {
+		select {
+		case m, ok := <-metrics:
+			if !ok {
+				return collected, <-result
+			}
+			collected = append(collected, m)
+		case <-scrapeCtx.Done():
+			// let the scrape finish in the background, it keeps its slot in the pool until then
+			go func() {
+				for range metrics {
+				}
+			}()
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("scrape abandoned: %w", ctx.Err())
+			}
+			err := fmt.Errorf("scrape did not finish within %v: %w", c.deadline, scrapeCtx.Err())
+			c.logger.Warn("scrape timed out", "target", c.target, "error", err.Error())
+			return nil, err
+		}
+	}
+}
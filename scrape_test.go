@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestTargetCollector(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		handler     http.HandlerFunc
+		wantUp      float64
+		wantSuccess float64
+	}{
+		{
+			name: "successful scrape",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = io.WriteString(w, validStubStatus)
+			},
+			wantSuccess: 1,
+			wantUp:      1,
+		},
+		{
+			name: "failed scrape",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			wantSuccess: 0,
+			wantUp:      0,
+		},
+		{
+			name: "scrape exceeding the deadline",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(time.Second):
+				}
+				_, _ = io.WriteString(w, validStubStatus)
+			},
+			wantSuccess: 0,
+			wantUp:      0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			nginx := httptest.NewServer(tt.handler)
+			t.Cleanup(nginx.Close)
+
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			target := targetConfig{URI: nginx.URL, Mode: modeOSS, Namespace: "nginx"}
+			c, err := newCollector(logger, &http.Transport{}, nginx.URL, target)
+			if err != nil {
+				t.Fatalf("newCollector() returned error: %v", err)
+			}
+			target.Timeout = 100 * time.Millisecond
+			tc := newTargetCollector(logger, c, newScrapePool(1), target, prometheus.NewCounter(prometheus.CounterOpts{Name: "cancelled"}))
+
+			registry := prometheus.NewRegistry()
+			registry.MustRegister(tc)
+
+			start := time.Now()
+			families, err := registry.Gather()
+			if err != nil {
+				t.Fatalf("Gather() returned error: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("scrape took %v, want it to stop at the deadline", elapsed)
+			}
+
+			var up, success float64 = -1, -1
+			for _, family := range families {
+				switch family.GetName() {
+				case "nginx_up":
+					up = family.GetMetric()[0].GetGauge().GetValue()
+				case "nginx_exporter_scrape_success":
+					success = family.GetMetric()[0].GetGauge().GetValue()
+				}
+			}
+			// the target is reported as down even when its scrape is abandoned
+			if up != tt.wantUp {
+				t.Errorf("nginx_up = %v, want %v", up, tt.wantUp)
+			}
+			if success != tt.wantSuccess {
+				t.Errorf("scrape_success = %v, want %v", success, tt.wantSuccess)
+			}
+			if got := countMetrics(t, registry, "nginx_exporter_scrape_duration_seconds"); got != 1 {
+				t.Errorf("got %v nginx_exporter_scrape_duration_seconds metrics, want 1", got)
+			}
+			wantTimestamps := 0
+			if tt.wantSuccess == 1 {
+				wantTimestamps = 1
+			}
+			if got := countMetrics(t, registry, "nginx_exporter_last_scrape_timestamp_seconds"); got != wantTimestamps {
+				t.Errorf("got %v nginx_exporter_last_scrape_timestamp_seconds metrics, want %v", got, wantTimestamps)
+			}
+		})
+	}
+}
+
+// slowCollector records how many of its instances are collecting at the same time.
+type slowCollector struct {
+	current *atomic.Int32
+	max     *atomic.Int32
+	desc    *prometheus.Desc
+}
+
+func (c *slowCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *slowCollector) Collect(ch chan<- prometheus.Metric) {
+	n := c.current.Add(1)
+	defer c.current.Add(-1)
+	for {
+		m := c.max.Load()
+		if n <= m || c.max.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, 1)
+}
+
+func TestTargetCollectorPoolLimitsConcurrency(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	pool := newScrapePool(2)
+	var current, maxConcurrent atomic.Int32
+
+	registry := prometheus.NewRegistry()
+	for i := range 6 {
+		uri := "http://nginx-" + strconv.Itoa(i)
+		c := &slowCollector{
+			current: &current,
+			max:     &maxConcurrent,
+			desc:    prometheus.NewDesc("slow", "Slow metric", nil, prometheus.Labels{"addr": uri}),
+		}
+		registry.MustRegister(newTargetCollector(logger, c, pool, targetConfig{URI: uri}, prometheus.NewCounter(prometheus.CounterOpts{Name: "cancelled"})))
+	}
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got := countMetrics(t, registry, "slow"); got != 6 {
+				t.Errorf("got %v slow metrics, want 6", got)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := maxConcurrent.Load(); got > 2 {
+		t.Errorf("%v targets were scraped at the same time, want at most 2", got)
+	}
+}
+
+func TestTargetCollectorCancelledScrape(t *testing.T) {
+	t.Parallel()
+
+	aborted := make(chan struct{})
+	nginx := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			close(aborted)
+		case <-time.After(5 * time.Second):
+		}
+		_, _ = io.WriteString(w, validStubStatus)
+	}))
+	t.Cleanup(nginx.Close)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	target := targetConfig{URI: nginx.URL, Mode: modeOSS, Namespace: "nginx", Timeout: 10 * time.Second}
+	c, err := newCollector(logger, &http.Transport{}, nginx.URL, target)
+	if err != nil {
+		t.Fatalf("newCollector() returned error: %v", err)
+	}
+	cancelled := prometheus.NewCounter(prometheus.CounterOpts{Name: "cancelled"})
+	tc := newTargetCollector(logger, c, newScrapePool(1), target, cancelled)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	ch := make(chan prometheus.Metric, 10)
+	if err := tc.Scrape(ctx, ch); err == nil {
+		t.Error("Scrape() did not return error for cancelled scrape")
+	}
+
+	select {
+	case <-aborted:
+	case <-time.After(time.Second):
+		t.Error("the request to NGINX was not aborted")
+	}
+	if got := testutil.ToFloat64(cancelled); got != 1 {
+		t.Errorf("cancelled scrapes = %v, want 1", got)
+	}
+}
+
+func TestScrapeContext(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		header       string
+		offset       time.Duration
+		wantTimeout  time.Duration
+		wantDeadline bool
+	}{
+		{
+			name: "no header",
+		},
+		{
+			name:   "invalid header",
+			header: "soon",
+		},
+		{
+			name:         "timeout minus offset",
+			header:       "10",
+			offset:       500 * time.Millisecond,
+			wantDeadline: true,
+			wantTimeout:  9500 * time.Millisecond,
+		},
+		{
+			name:         "offset larger than the timeout",
+			header:       "0.5",
+			offset:       time.Second,
+			wantDeadline: true,
+			wantTimeout:  500 * time.Millisecond,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.header != "" {
+				req.Header.Set(scrapeTimeoutHeader, tt.header)
+			}
+
+			ctx, cancel := scrapeContext(req, tt.offset)
+			defer cancel()
+
+			deadline, ok := ctx.Deadline()
+			if ok != tt.wantDeadline {
+				t.Fatalf("scrapeContext() has deadline = %v, want %v", ok, tt.wantDeadline)
+			}
+			if !ok {
+				return
+			}
+			if got := time.Until(deadline); got > tt.wantTimeout || got < tt.wantTimeout-100*time.Millisecond {
+				t.Errorf("scrapeContext() timeout = %v, want %v", got, tt.wantTimeout)
+			}
+		})
+	}
+}
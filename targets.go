@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// targetManager keeps the collectors of the configured targets, which are replaced as a whole when
+// the targets change. Every gathering uses a new registry, so that the collectors can be bound to
+// the context of the request. A registry remembers the label names of every metric it has ever seen,
+// so reusing one would also prevent the const labels from changing on reload.
+type targetManager struct {
+	logger     *slog.Logger
+	cancelled  *prometheus.CounterVec
+	cancel     context.CancelFunc
+	pool       scrapePool
+	collectors []*targetCollector
+	targets    []string
+	mutex      sync.RWMutex
+	// config is the configuration the collectors were built for, guarded by reloads.
+	config *config
+	// reloads serializes the changes of the targets and of the labels along with their application to the
+	// collectors, so that the collectors are always built from the current labels.
+	reloads sync.Mutex
+}
+
+// pollingCollector is implemented by collectors that can serve scrapes from stats polled in the background.
+type pollingCollector interface {
+	StartPolling(ctx context.Context, interval, staleness time.Duration)
+}
+
+// labelFileCollector is implemented by collectors that save their label values to a label file, which must be
+// claimed by the collector replacing the one that wrote it so far and released by the collectors no longer used.
+type labelFileCollector interface {
+	ClaimLabelFile()
+	ReleaseLabelFile()
+}
+
+// newTargetManager creates a targetManager that scrapes at most concurrency targets at the same time.
+func newTargetManager(logger *slog.Logger, concurrency int) *targetManager {
+	return &targetManager{
+		logger: logger,
+		cancelled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: exporterName,
+			Name:      "scrapes_cancelled_total",
+			Help:      "Total number of scrapes of the target abandoned before they finished",
+		}, []string{"target"}),
+		cancel: func() {},
+		pool:   newScrapePool(concurrency),
+	}
+}
+
+// Gather implements prometheus.Gatherer.
+func (m *targetManager) Gather() ([]*dto.MetricFamily, error) {
+	return m.GatherContext(context.Background())
+}
+
+// GatherContext gathers the metrics of all targets, aborting the scrapes when ctx is done.
+func (m *targetManager) GatherContext(ctx context.Context) ([]*dto.MetricFamily, error) {
+	m.mutex.RLock()
+	collectors := m.collectors
+	m.mutex.RUnlock()
+
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(m.cancelled); err != nil {
+		return nil, fmt.Errorf("failed to register scrape metrics: %w", err)
+	}
+	for _, c := range collectors {
+		if err := registry.Register(withContext(ctx, c)); err != nil {
+			return nil, fmt.Errorf("failed to register collector for target %q: %w", c.target, err)
+		}
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		return families, fmt.Errorf("failed to gather target metrics: %w", err)
+	}
+	return families, nil
+}
+
+// buildCollectors creates the collectors for all targets of cfg without registering them, along with the
+// transports of their requests.
+func buildCollectors(logger *slog.Logger, cfg *config) ([]prometheus.Collector, []*http.Transport, error) {
+	collectors := make([]prometheus.Collector, 0, len(cfg.Targets))
+	transports := make([]*http.Transport, 0, len(cfg.Targets))
+	for _, t := range cfg.Targets {
+		transport, addr, err := newTargetTransport(t, nil)
+		if err != nil {
+			releaseLabelFiles(collectors...)
+			return nil, nil, fmt.Errorf("target %q: %w", t.URI, err)
+		}
+
+		c, err := newCollector(logger, transport, addr, t)
+		if err != nil {
+			releaseLabelFiles(collectors...)
+			return nil, nil, fmt.Errorf("target %q: %w", t.URI, err)
+		}
+		collectors = append(collectors, c)
+		transports = append(transports, transport)
+	}
+	return collectors, transports, nil
+}
+
+// releaseLabelFiles releases the label files of collectors that are discarded or replaced.
+func releaseLabelFiles(collectors ...prometheus.Collector) {
+	for _, c := range collectors {
+		if l, ok := c.(labelFileCollector); ok {
+			l.ReleaseLabelFile()
+		}
+	}
+}
+
+// build creates the collectors for the targets of cfg and checks that they can be gathered together.
+// If they can't, the collectors are discarded and the cancelled scrapes counters of the targets that are not
+// current are removed.
+func (m *targetManager) build(cfg *config) ([]*targetCollector, []string, error) {
+	collectors, transports, err := buildCollectors(m.logger, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// registering the collectors once checks that they can be gathered together
+	registry := prometheus.NewRegistry()
+	targetCollectors := make([]*targetCollector, 0, len(collectors))
+	targets := make([]string, 0, len(collectors))
+	for i, c := range collectors {
+		uri := cfg.Targets[i].URI
+		tc := newTargetCollector(m.logger, c, m.pool, cfg.Targets[i], m.cancelled.WithLabelValues(uri))
+		tc.transport = transports[i]
+		if err := registry.Register(tc); err != nil {
+			releaseLabelFiles(collectors...)
+			m.mutex.RLock()
+			m.deleteCancelled(append(targets, uri), m.targets)
+			m.mutex.RUnlock()
+			return nil, nil, fmt.Errorf("failed to register collector for target %q: %w", uri, err)
+		}
+		targetCollectors = append(targetCollectors, tc)
+		targets = append(targets, uri)
+	}
+	return targetCollectors, targets, nil
+}
+
+// check checks that the collectors for the targets of cfg can be created and gathered together. The current
+// collectors are kept, and the targets are neither scraped nor polled. The label files are left untouched.
+func (m *targetManager) check(cfg *config) error {
+	checked := &config{Targets: slices.Clone(cfg.Targets)}
+	for i := range checked.Targets {
+		checked.Targets[i].LabelStoreDir = ""
+	}
+	_, targets, err := m.build(checked)
+	if err != nil {
+		return err
+	}
+	m.mutex.RLock()
+	m.deleteCancelled(targets, m.targets)
+	m.mutex.RUnlock()
+	return nil
+}
+
+// apply replaces the collectors with the collectors for the targets of cfg, unless cfg is the configuration of
+// the current collectors, which are then kept along with their state. Targets with a poll interval are polled in
+// the background until the collectors are replaced again.
+// If any of the new collectors can't be created or registered, the previous ones are kept.
+func (m *targetManager) apply(cfg *config) error {
+	m.reloads.Lock()
+	defer m.reloads.Unlock()
+
+	if m.config != nil && m.config.equal(cfg) {
+		m.logger.Info("targets unchanged", "targets", len(cfg.Targets))
+		return nil
+	}
+	return m.rebuild(cfg)
+}
+
+// rebuild replaces the collectors with new collectors for the targets of cfg, built from the current labels.
+// The caller must hold the reloads mutex.
+func (m *targetManager) rebuild(cfg *config) error {
+	targetCollectors, targets, err := m.build(cfg)
+	if err != nil {
+		return err
+	}
+
+	// the label files are written by the new collectors from now on
+	for _, tc := range targetCollectors {
+		if c, ok := tc.collector.(labelFileCollector); ok {
+			c.ClaimLabelFile()
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	for i, tc := range targetCollectors {
+		if p, ok := tc.collector.(pollingCollector); ok && cfg.Targets[i].PollInterval > 0 {
+			p.StartPolling(ctx, cfg.Targets[i].PollInterval, cfg.Targets[i].StalenessLimit)
+		}
+	}
+
+	m.mutex.Lock()
+	previous := m.collectors
+	m.deleteCancelled(m.targets, targets)
+	m.collectors = targetCollectors
+	m.targets = targets
+	// stop polling the previous targets
+	m.cancel()
+	m.cancel = cancel
+	m.mutex.Unlock()
+	m.config = cfg
+
+	// the connections of the previous targets that are not in use by scrapes still running are closed, and their
+	// label files are released
+	for _, tc := range previous {
+		if tc.transport != nil {
+			tc.transport.CloseIdleConnections()
+		}
+		releaseLabelFiles(tc.collector)
+	}
+
+	m.logger.Info("targets configured", "targets", len(targetCollectors))
+	return nil
+}
+
+// deleteCancelled removes the cancelled scrapes counters of the targets that are not kept.
+func (m *targetManager) deleteCancelled(targets []string, kept []string) {
+	for _, uri := range targets {
+		if !slices.Contains(kept, uri) {
+			m.cancelled.DeleteLabelValues(uri)
+		}
+	}
+}
+
+// watchedFile is a configuration file applied by reload. A file without a path is only reloaded on SIGHUP.
+type watchedFile struct {
+	reload func() error
+	path   string
+}
+
+// watchConfig reloads all the files, in order, when the process receives SIGHUP and, if interval is positive,
+// every file whose content changes. It returns when ctx is done.
+func watchConfig(ctx context.Context, logger *slog.Logger, interval time.Duration, files ...watchedFile) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if interval > 0 && slices.ContainsFunc(files, func(f watchedFile) bool { return f.path != "" }) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	checksums := make([][sha256.Size]byte, len(files))
+	for i, f := range files {
+		checksums[i], _ = fileChecksum(f.path)
+	}
+
+	doReload := func(f watchedFile, reason string) {
+		logger.Info("reloading configuration", "file", f.path, "reason", reason)
+		if err := f.reload(); err != nil {
+			logger.Error("reloading configuration failed, keeping the current configuration", "file", f.path, "error", err.Error())
+			return
+		}
+		logger.Info("configuration reloaded", "file", f.path)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			for i, f := range files {
+				checksums[i], _ = fileChecksum(f.path)
+				doReload(f, "SIGHUP")
+			}
+		case <-tick:
+			for i, f := range files {
+				if f.path == "" {
+					continue
+				}
+				current, err := fileChecksum(f.path)
+				if err != nil {
+					logger.Warn("checking configuration file failed", "file", f.path, "error", err.Error())
+					continue
+				}
+				if current == checksums[i] {
+					continue
+				}
+				checksums[i] = current
+				doReload(f, "file changed")
+			}
+		}
+	}
+}
+
+func fileChecksum(path string) ([sha256.Size]byte, error) {
+	if path == "" {
+		return [sha256.Size]byte{}, errors.New("no file to check")
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return [sha256.Size]byte{}, fmt.Errorf("failed to read %q: %w", path, err)
+	}
+	return sha256.Sum256(content), nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func countMetrics(t *testing.T, gatherer prometheus.Gatherer, name string) int {
+	t.Helper()
+	families, err := gatherer.Gather()
+	if err != nil {
+		t.Fatalf("Gather() returned error: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() == name {
+			return len(family.GetMetric())
+		}
+	}
+	return 0
+}
+
+func TestTargetManagerApply(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	manager := newTargetManager(logger, 10)
+	defaults := targetConfig{Mode: modeOSS, Timeout: time.Second}
+
+	one, err := newConfigFromURIs([]string{"http://127.0.0.1:1/stub_status"}, defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.apply(one); err != nil {
+		t.Fatalf("apply() returned error: %v", err)
+	}
+	if got := countMetrics(t, manager, "nginx_up"); got != 1 {
+		t.Errorf("got %v nginx_up metrics, want 1", got)
+	}
+
+	two, err := newConfigFromURIs([]string{"http://127.0.0.1:1/stub_status", "http://127.0.0.1:2/stub_status"}, defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.apply(two); err != nil {
+		t.Fatalf("apply() returned error: %v", err)
+	}
+	if got := countMetrics(t, manager, "nginx_up"); got != 2 {
+		t.Errorf("got %v nginx_up metrics, want 2", got)
+	}
+
+	// the collectors of an unchanged configuration are kept
+	manager.mutex.RLock()
+	collectors := manager.collectors
+	manager.mutex.RUnlock()
+	same, err := newConfigFromURIs([]string{"http://127.0.0.1:1/stub_status", "http://127.0.0.1:2/stub_status"}, defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.apply(same); err != nil {
+		t.Fatalf("apply() returned error: %v", err)
+	}
+	manager.mutex.RLock()
+	if !slices.Equal(manager.collectors, collectors) {
+		t.Error("the collectors were replaced for an unchanged configuration")
+	}
+	manager.mutex.RUnlock()
+
+	// both targets have the same const labels, so the second one can't be registered
+	conflicting := &config{Targets: []targetConfig{
+		{URI: "http://127.0.0.1:3/stub_status", ConstLabels: map[string]string{"addr": "same"}},
+		{URI: "http://127.0.0.1:4/stub_status", ConstLabels: map[string]string{"addr": "same"}},
+	}}
+	conflicting.applyDefaults(defaults)
+	if err := manager.apply(conflicting); err == nil {
+		t.Error("apply() did not return error for conflicting targets")
+	}
+	if got := countMetrics(t, manager, "nginx_up"); got != 2 {
+		t.Errorf("got %v nginx_up metrics after failed apply, want 2", got)
+	}
+}
+
+func TestTargetManagerApplyClosesIdleConnections(t *testing.T) {
+	t.Parallel()
+
+	closed := make(chan struct{}, 10)
+	nginx := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, validStubStatus)
+	}))
+	nginx.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			closed <- struct{}{}
+		}
+	}
+	nginx.Start()
+	t.Cleanup(nginx.Close)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	manager := newTargetManager(logger, 10)
+	cfg, err := newConfigFromURIs([]string{nginx.URL}, targetConfig{Mode: modeOSS, Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.apply(cfg); err != nil {
+		t.Fatalf("apply() returned error: %v", err)
+	}
+	// the scrape leaves an idle keep-alive connection
+	if got := countMetrics(t, manager, "nginx_up"); got != 1 {
+		t.Fatalf("got %v nginx_up metrics, want 1", got)
+	}
+
+	changed, err := newConfigFromURIs([]string{nginx.URL}, targetConfig{Mode: modeOSS, Timeout: 2 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.apply(changed); err != nil {
+		t.Fatalf("apply() returned error: %v", err)
+	}
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Error("the idle connection of the replaced target was not closed")
+	}
+}
+
+func TestTargetManagerCheck(t *testing.T) {
+	t.Parallel()
+
+	var requests atomic.Int32
+	nginx := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		_, _ = io.WriteString(w, validStubStatus)
+	}))
+	t.Cleanup(nginx.Close)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	manager := newTargetManager(logger, 10)
+	cfg, err := newConfigFromURIs([]string{nginx.URL}, targetConfig{Mode: modeOSS, Timeout: time.Second, PollInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.check(cfg); err != nil {
+		t.Fatalf("check() returned error: %v", err)
+	}
+	// the checked targets are neither configured nor polled
+	time.Sleep(50 * time.Millisecond)
+	if got := requests.Load(); got != 0 {
+		t.Errorf("the target was requested %v times, want 0", got)
+	}
+	if got := countMetrics(t, manager, "nginx_up"); got != 0 {
+		t.Errorf("got %v nginx_up metrics, want 0", got)
+	}
+
+	conflicting := &config{Targets: []targetConfig{
+		{URI: "http://127.0.0.1:3/stub_status", ConstLabels: map[string]string{"addr": "same"}},
+		{URI: "http://127.0.0.1:4/stub_status", ConstLabels: map[string]string{"addr": "same"}},
+	}}
+	conflicting.applyDefaults(targetConfig{Mode: modeOSS})
+	if err := manager.check(conflicting); err == nil {
+		t.Error("check() did not return error for conflicting targets")
+	}
+}
+
+func TestWatchConfigReloadsOnFileChange(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte("a"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reloaded := make(chan struct{}, 1)
+	go watchConfig(ctx, logger, 10*time.Millisecond, watchedFile{path: path, reload: func() error {
+		reloaded <- struct{}{}
+		return nil
+	}})
+
+	// give the watcher time to record the initial checksum
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(path, []byte("b"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-reloaded:
+	case <-time.After(5 * time.Second):
+		t.Error("configuration was not reloaded after the file changed")
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+)
+
+// dialControl is called by the dialer of a target after creating a connection and before connecting it.
+type dialControl func(network, address string, c syscall.RawConn) error
+
+// newTargetTransport creates the transport used to scrape the target and the address to request through it.
+// Every target gets its own dialer, TLS configuration and connection pool, so targets never share connections
+// or settings. If control is not nil, the dialer calls it before connecting.
+func newTargetTransport(target targetConfig, control dialControl) (*http.Transport, string, error) {
+	tlsCfg, err := newTLSConfig(target.TLS)
+	if err != nil {
+		return nil, "", err
+	}
+
+	dialer := &net.Dialer{
+		KeepAlive: target.Transport.KeepAlive,
+		Control:   control,
+	}
+
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSClientConfig:     tlsCfg,
+		MaxIdleConns:        target.Transport.MaxIdleConns,
+		MaxIdleConnsPerHost: target.Transport.MaxIdleConnsPerHost,
+		MaxConnsPerHost:     target.Transport.MaxConnsPerHost,
+		IdleConnTimeout:     target.Transport.IdleConnTimeout,
+		DisableKeepAlives:   target.Transport.DisableKeepAlives != nil && *target.Transport.DisableKeepAlives,
+	}
+
+	addr := target.URI
+	if strings.HasPrefix(addr, "unix:") {
+		socketPath, requestPath, err := parseUnixSocketAddress(addr)
+		if err != nil {
+			return nil, "", fmt.Errorf("parsing unix domain socket scrape address %q failed: %w", addr, err)
+		}
+
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socketPath)
+		}
+		addr = "http://unix" + requestPath
+	}
+
+	return transport, addr, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// serveUnixSocket serves a stub_status page with the given number of active connections on a unix socket.
+func serveUnixSocket(t *testing.T, active int) string {
+	t.Helper()
+
+	// unix socket paths are limited to ~100 bytes, which t.TempDir() can exceed
+	dir, err := os.MkdirTemp("", "nginx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "nginx.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{
+		ReadHeaderTimeout: time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			fmt.Fprintf(w, "Active connections: %d \nserver accepts handled requests\n 2 2 3 \nReading: 0 Writing: 1 Waiting: 0 \n", active)
+		}),
+	}
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { server.Close() })
+
+	return path
+}
+
+func TestTargetTransportsAreIsolated(t *testing.T) {
+	t.Parallel()
+
+	first := serveUnixSocket(t, 1)
+	second := serveUnixSocket(t, 2)
+
+	cfg, err := newConfigFromURIs([]string{"unix:" + first + ":/stub_status", "unix:" + second + ":/stub_status"}, targetConfig{Mode: modeOSS, Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := newTargetManager(slog.New(slog.NewTextHandler(io.Discard, nil)), 10)
+	if err := manager.apply(cfg); err != nil {
+		t.Fatalf("apply() returned error: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			families, err := manager.Gather()
+			if err != nil {
+				t.Errorf("Gather() returned error: %v", err)
+				return
+			}
+			for _, family := range families {
+				if family.GetName() != "nginx_connections_active" {
+					continue
+				}
+				if len(family.GetMetric()) != 2 {
+					t.Errorf("got %v nginx_connections_active metrics, want 2", len(family.GetMetric()))
+				}
+				for _, m := range family.GetMetric() {
+					want := map[string]float64{cfg.Targets[0].URI: 1, cfg.Targets[1].URI: 2}
+					for _, label := range m.GetLabel() {
+						if label.GetName() == "addr" && m.GetGauge().GetValue() != want[label.GetValue()] {
+							t.Errorf("target %v has %v active connections, want %v", label.GetValue(), m.GetGauge().GetValue(), want[label.GetValue()])
+						}
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestNewTargetTransport(t *testing.T) {
+	t.Parallel()
+
+	disable := true
+	target := targetConfig{
+		URI: "unix:/var/run/nginx.sock:/stub_status",
+		Transport: transportConfig{
+			DisableKeepAlives:   &disable,
+			MaxIdleConns:        10,
+			MaxIdleConnsPerHost: 5,
+			MaxConnsPerHost:     3,
+			IdleConnTimeout:     time.Minute,
+		},
+	}
+
+	transport, addr, err := newTargetTransport(target, nil)
+	if err != nil {
+		t.Fatalf("newTargetTransport() returned error: %v", err)
+	}
+	if addr != "http://unix/stub_status" {
+		t.Errorf("newTargetTransport() addr = %v, want http://unix/stub_status", addr)
+	}
+	if transport.MaxIdleConns != 10 || transport.MaxIdleConnsPerHost != 5 || transport.MaxConnsPerHost != 3 {
+		t.Errorf("newTargetTransport() connection limits = %v, %v, %v, want 10, 5, 3", transport.MaxIdleConns, transport.MaxIdleConnsPerHost, transport.MaxConnsPerHost)
+	}
+	if transport.IdleConnTimeout != time.Minute {
+		t.Errorf("newTargetTransport() IdleConnTimeout = %v, want %v", transport.IdleConnTimeout, time.Minute)
+	}
+	if !transport.DisableKeepAlives {
+		t.Error("newTargetTransport() did not disable keep-alives")
+	}
+
+	other, _, err := newTargetTransport(targetConfig{URI: "http://127.0.0.1:8080/stub_status"}, nil)
+	if err != nil {
+		t.Fatalf("newTargetTransport() returned error: %v", err)
+	}
+	if other == transport || other.TLSClientConfig == transport.TLSClientConfig {
+		t.Error("newTargetTransport() shares state between targets")
+	}
+}